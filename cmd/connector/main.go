@@ -17,10 +17,15 @@ package main
 import (
 	sdk "github.com/conduitio/conduit-connector-sdk"
 	connector "github.com/neha-Gupta1/conduit-connector-bigquery"
+	"github.com/neha-Gupta1/conduit-connector-bigquery/googledestination"
 	"github.com/neha-Gupta1/conduit-connector-bigquery/googlesource"
 )
 
 func main() {
-	connector := sdk.Connector{NewSpecification: connector.Specification, NewSource: googlesource.NewSource}
+	connector := sdk.Connector{
+		NewSpecification: connector.Specification,
+		NewSource:        googlesource.NewSource,
+		NewDestination:   googledestination.NewDestination,
+	}
 	sdk.Serve(connector)
 }
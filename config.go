@@ -15,10 +15,19 @@
 package googlebigquery
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
 )
 
 const (
@@ -28,42 +37,391 @@ const (
 	// ConfigDatasetID is the dataset ID
 	ConfigDatasetID = "datasetID"
 
-	// ConfigTableID is the tableID
+	// ConfigTableID is the comma separated list of table IDs. All tables of the dataset are synced when blank
 	ConfigTableID = "tableID"
 
-	// ConfigServiceAccount path to service account key
+	// ConfigSkipTableValidation skips checking that the configured tables exist when the source is opened
+	ConfigSkipTableValidation = "skipTableValidation"
+
+	// ConfigTableIncludeRegex only tables matching it are synced when tables are discovered from the dataset
+	ConfigTableIncludeRegex = "tableIncludeRegex"
+
+	// ConfigTableExcludeRegex tables matching it are skipped when tables are discovered from the dataset
+	ConfigTableExcludeRegex = "tableExcludeRegex"
+
+	// ConfigServiceAccount service account key as JSON, or path to the key file. When blank, Application Default Credentials are used
 	ConfigServiceAccount = "serviceAccount"
 
+	// ConfigServiceAccountJSON service account key provided inline as JSON. Takes precedence over ConfigServiceAccount
+	ConfigServiceAccountJSON = "serviceAccountJSON"
+
+	// ConfigServiceAccountBase64 service account key JSON encoded as base64. Takes precedence over ConfigServiceAccount
+	ConfigServiceAccountBase64 = "serviceAccountBase64"
+
+	// ConfigImpersonateServiceAccount service account to impersonate using the resolved credentials
+	ConfigImpersonateServiceAccount = "impersonateServiceAccount"
+
+	// ConfigImpersonateDelegates comma separated delegation chain used for impersonation
+	ConfigImpersonateDelegates = "impersonateDelegates"
+
+	// ConfigScopes comma separated OAuth scopes requested for the BigQuery client
+	ConfigScopes = "scopes"
+
+	// ConfigEndpoint BigQuery API endpoint used instead of the default one, eg. for emulators and private deployments
+	ConfigEndpoint = "endpoint"
+
+	// ConfigInsecure sends requests to the custom endpoint without credentials, eg. to the BigQuery emulator
+	ConfigInsecure = "insecure"
+
+	// ConfigMaxConcurrentReads is the maximum number of tables read at the same time
+	ConfigMaxConcurrentReads = "maxConcurrentReads"
+
+	// ConfigBytesEncoding encoding used for BYTES columns. Either base64 or hex
+	ConfigBytesEncoding = "bytesEncoding"
+
+	// ConfigJSONAsString keeps JSON columns as raw strings instead of parsing them
+	ConfigJSONAsString = "jsonAsString"
+
+	// ConfigTimestampFormat layout used for TIMESTAMP columns. Also accepts rfc3339 and unix (epoch milliseconds)
+	ConfigTimestampFormat = "timestampFormat"
+
+	// ConfigTimestampLocation IANA time zone TIMESTAMP columns are formatted in
+	ConfigTimestampLocation = "timestampLocation"
+
+	// ConfigFilter SQL condition added to the WHERE clause of every query
+	ConfigFilter = "filter"
+
+	// ConfigSamplePercent percentage of the table blocks read, for cheap syncs of a sample of large tables
+	ConfigSamplePercent = "samplePercent"
+
+	// ConfigQuery custom SQL query synced instead of the tables of the dataset
+	ConfigQuery = "query"
+
+	// ConfigPartitions comma separated IDs of the time partitions read, eg. 20240101. All partitions are read when blank
+	ConfigPartitions = "partitions"
+
+	// ConfigPartitionField column the tables are partitioned by. Defaults to _PARTITIONTIME of ingestion time partitioned tables
+	ConfigPartitionField = "partitionField"
+
+	// ConfigPartitionLookback time window of partitions read from tables requiring a partition filter
+	ConfigPartitionLookback = "partitionLookback"
+
+	// ConfigIncludePseudoColumns adds the partition pseudo columns of ingestion time partitioned tables to the payload
+	ConfigIncludePseudoColumns = "includePseudoColumns"
+
+	// ConfigColumns comma separated list of columns to select. All columns are selected when blank
+	ConfigColumns = "columns"
+
+	// ConfigExcludeColumns comma separated list of columns dropped from the records. Nested fields are given as path, eg. user.email
+	ConfigExcludeColumns = "excludeColumns"
+
+	// ConfigBatchSize is the number of rows fetched by each query
+	ConfigBatchSize = "batchSize"
+
+	// ConfigMaxRows is the total number of records emitted before reading stops. No limit when empty
+	ConfigMaxRows = "maxRows"
+
+	// ConfigBufferSize is the number of records buffered before the tables are read any further
+	ConfigBufferSize = "bufferSize"
+
+	// ConfigReadMode decides how snapshots are read. Either query or storage
+	ConfigReadMode = "readMode"
+
+	// ConfigReadStreams number of parallel streams a snapshot is split across. Requires the storage read mode
+	ConfigReadStreams = "readStreams"
+
+	// ConfigMaterializeSnapshot writes the rows of a snapshot to a temporary table with a single query and reads them from it
+	ConfigMaterializeSnapshot = "materializeSnapshot"
+
+	// ConfigKeyCacheSize number of record keys remembered to emit rows read again as updates. 0 disables it
+	ConfigKeyCacheSize = "keyCacheSize"
+
+	// ConfigStartPosition value of the incrementing column the tables without position are read after
+	ConfigStartPosition = "startPosition"
+
+	// ConfigEndPosition value of the incrementing column the tables are read up to. Tables are no longer polled once reached
+	ConfigEndPosition = "endPosition"
+
+	// ConfigMode decides if tables are snapshot before reading their changes. Either snapshot or cdc
+	ConfigMode = "mode"
+
+	// ConfigCDCMode decides how changes after the snapshot are read. Either polling or changeHistory
+	ConfigCDCMode = "cdcMode"
+
+	// ConfigDetectDeletes periodically compares the primary keys of the tables to emit deleted rows
+	ConfigDetectDeletes = "detectDeletes"
+
+	// ConfigDetectDeletesInterval time between two scans of the primary keys of a table
+	ConfigDetectDeletesInterval = "detectDeletesInterval"
+
+	// ConfigWriteMode decides how the destination writes records. Either insert or upsert
+	ConfigWriteMode = "writeMode"
+
+	// ConfigAutoCreate creates the destination table from the first record when it doesn't exist
+	ConfigAutoCreate = "autoCreate"
+
+	// ConfigMaxRetries is the number of times a query failing with a transient error is retried
+	ConfigMaxRetries = "maxRetries"
+
+	// ConfigRetryDelay is the delay before the first retry of a query, it doubles with every retry
+	ConfigRetryDelay = "retryDelay"
+
+	// ConfigJobTimeout is how long a query job may run before it is canceled. No timeout when empty
+	ConfigJobTimeout = "jobTimeout"
+
+	// ConfigQueryLabels comma separated key=value labels set on every query job, eg. for cost attribution
+	ConfigQueryLabels = "queryLabels"
+
+	// ConfigMaxBytesBilled limits the bytes billed for every query job. Queries above it fail
+	ConfigMaxBytesBilled = "maxBytesBilled"
+
+	// ConfigDryRun only estimates the bytes processed by the queries of the tables without reading any rows
+	ConfigDryRun = "dryRun"
+
+	// ConfigUseQueryCache decides if query jobs may return cached results
+	ConfigUseQueryCache = "useQueryCache"
+
+	// ConfigQueryPriority is the priority query jobs run with. Either interactive or batch
+	ConfigQueryPriority = "queryPriority"
+
+	// ConfigLogLevel is the minimum level of the messages logged by the connector
+	ConfigLogLevel = "logLevel"
+
 	// ConfigLocation location of the dataset
 	ConfigLocation = "datasetLocation"
 
 	// ConfigPollingTime time after which polling should be done
 	ConfigPollingTime = "pollingTime"
 
-	// ConfigOrderBy lets user decide
+	// ConfigAllowFastPolling allows polling times below MinPollingTime
+	ConfigAllowFastPolling = "allowFastPolling"
+
+	// ConfigMaxPollingTime cap the polling period grows to while polls return no rows
+	ConfigMaxPollingTime = "maxPollingTime"
+
+	// ConfigHeartbeatInterval is how long polls may return no rows before a heartbeat record is emitted. No heartbeats when empty
+	ConfigHeartbeatInterval = "heartbeatInterval"
+
+	// ConfigIncrementalColName lets user decide the column used as offset. Either a single column name used
+	// for all tables or per table in the format table1:column1,table2:column2. An entry without table
+	// name is used for the tables which are not listed.
 	ConfigIncrementalColName = "incrementingColumnName"
 
-	// ConfigPrimaryKeyColName provide primary key
+	// ConfigIncrementOrder decides if the rows are read by ascending or descending incrementing column. Either asc or desc
+	ConfigIncrementOrder = "incrementOrder"
+
+	// ConfigOrderBy comma separated list of columns the rows are ordered by instead of the incrementing columns,
+	// which are still used as offset
+	ConfigOrderBy = "orderBy"
+
+	// ConfigPrimaryKeyColName provide primary key. Composite keys are given as comma separated list of columns
 	ConfigPrimaryKeyColName = "primaryKeyColName"
+
+	// ConfigKeyColumns comma separated list of columns the record key is built from instead of the primary key
+	ConfigKeyColumns = "keyColumns"
+
+	// ConfigKeyFormat encoding of the record key. Either json or gob
+	ConfigKeyFormat = "keyFormat"
+
+	// ConfigNullHandling how NULL column values are written to the payload. Either null or omit
+	ConfigNullHandling = "nullHandling"
+
+	// ConfigOnConversionError what happens to rows which can't be converted to a record. Either fail, skip or dlq
+	ConfigOnConversionError = "onConversionError"
 )
 
+const (
+	// BytesEncodingBase64 encodes BYTES columns as standard base64 strings
+	BytesEncodingBase64 = "base64"
+
+	// BytesEncodingHex encodes BYTES columns as lowercase hex strings
+	BytesEncodingHex = "hex"
+
+	// TimestampFormatRFC3339 formats TIMESTAMP columns as RFC 3339 strings
+	TimestampFormatRFC3339 = "rfc3339"
+
+	// TimestampFormatUnix formats TIMESTAMP columns as milliseconds since the Unix epoch
+	TimestampFormatUnix = "unix"
+
+	// KeyFormatJSON encodes record keys as JSON, a composite key as object of its columns
+	KeyFormatJSON = "json"
+
+	// KeyFormatGob encodes record keys with encoding/gob like earlier versions of the connector
+	KeyFormatGob = "gob"
+
+	// NullHandlingNull keeps the columns holding NULL in the payload with a null value
+	NullHandlingNull = "null"
+
+	// NullHandlingOmit leaves the columns holding NULL out of the payload
+	NullHandlingOmit = "omit"
+
+	// OnConversionErrorFail stops the sync with the error of a row which can't be converted
+	OnConversionErrorFail = "fail"
+
+	// OnConversionErrorSkip logs and skips rows which can't be converted
+	OnConversionErrorSkip = "skip"
+
+	// OnConversionErrorDLQ emits rows which can't be converted as dead-letter records carrying the error in their metadata
+	OnConversionErrorDLQ = "dlq"
+
+	// ReadModeQuery reads snapshots using paginated query jobs
+	ReadModeQuery = "query"
+
+	// ReadModeStorage reads snapshots with a single query job whose result is downloaded using the
+	// BigQuery Storage Read API. The query is still billed, only the download is faster.
+	ReadModeStorage = "storage"
+
+	// ModeSnapshot reads a snapshot of the tables before their changes
+	ModeSnapshot = "snapshot"
+
+	// ModeCDC only reads the changes made after the connector started, eg. for tables backfilled elsewhere
+	ModeCDC = "cdc"
+
+	// IncrementOrderAsc reads the rows from the smallest incrementing column value on
+	IncrementOrderAsc = "asc"
+
+	// IncrementOrderDesc reads the rows from the greatest incrementing column value on, eg. to backfill the newest rows first
+	IncrementOrderDesc = "desc"
+
+	// QueryPriorityInteractive runs query jobs as soon as possible
+	QueryPriorityInteractive = "interactive"
+
+	// QueryPriorityBatch queues query jobs till idle slots are available, eg. for large backfills
+	QueryPriorityBatch = "batch"
+
+	// CDCModePolling polls the tables for rows with a bigger incrementing column
+	CDCModePolling = "polling"
+
+	// CDCModeChangeHistory reads the changes of the tables with the CHANGES and APPENDS functions
+	CDCModeChangeHistory = "changeHistory"
+
+	// WriteModeInsert appends the records to the destination table with streaming inserts
+	WriteModeInsert = "insert"
+
+	// WriteModeUpsert merges the records into the destination table by primary key
+	WriteModeUpsert = "upsert"
+
+	// QueryTableID is the table name used for position and metadata of records read with a custom query
+	QueryTableID = "query"
+
+	// DefaultTimestampLayout is the layout used for TIMESTAMP columns when no format is provided
+	DefaultTimestampLayout = "2006-01-02 15:04:05.999999 MST"
+
+	// DefaultPartitionField is the pseudo column of the partition of ingestion time partitioned tables
+	DefaultPartitionField = "_PARTITIONTIME"
+
+	// MetadataHeartbeat is the Record.Metadata key set to true on the heartbeat records of the source,
+	// which only carry the position. They aren't written by the destination.
+	MetadataHeartbeat = "bigquery.heartbeat"
+)
+
+// Partition is the time range of the rows of a time partition, eg. [2024-01-01, 2024-01-02) for
+// the daily partition 20240101. Start and End are formatted as BigQuery literals.
+type Partition struct {
+	ID    string
+	Start string
+	End   string
+}
+
 // Config represents configuration needed for S3
 type Config struct {
-	ProjectID         string
-	DatasetID         string
-	TableID           string
-	ServiceAccount    string
-	Location          string
-	PollingTime       string
-	IncrementColName  string // IncrementColName is incrementing column name. This is used as offset
-	PrimaryKeyColName string // PrimaryKeyColName is primary key column. This is used as primary key
+	ProjectID                 string
+	DatasetID                 string
+	TableIDs                  []string
+	TableIncludeRegex         *regexp.Regexp
+	TableExcludeRegex         *regexp.Regexp
+	ServiceAccount            string
+	ServiceAccountJSON        string
+	ServiceAccountBase64      string
+	ImpersonateServiceAccount string   // ImpersonateServiceAccount is the service account impersonated with the resolved credentials
+	ImpersonateDelegates      []string // ImpersonateDelegates is the optional delegation chain used for impersonation
+	Scopes                    []string // Scopes are the OAuth scopes requested. The full BigQuery scope is used when empty, query jobs can't be run with the read-only one
+	Endpoint                  string   // Endpoint is the BigQuery API endpoint used instead of the default one
+	Insecure                  bool     // Insecure sends requests to the endpoint without credentials
+	Location                  string
+	LogLevel                  string // LogLevel is the minimum level logged by the connector. Conduit's level applies when empty
+	PollingTime               string
+	MaxPollingTime            time.Duration       // MaxPollingTime caps the polling period growing while polls return no rows. No backoff when 0
+	HeartbeatInterval         time.Duration       // HeartbeatInterval is the time without records after which a heartbeat record is emitted. No heartbeats when 0
+	IncrementColNames         []string            // IncrementColNames are the default incrementing columns. These are used as offset
+	TableIncrementColNames    map[string][]string // TableIncrementColNames are incrementing columns per table. Takes precedence over IncrementColNames
+	IncrementOrder            string              // IncrementOrder decides if the rows are read by ascending or descending incrementing column
+	OrderBy                   []string            // OrderBy are the columns the rows are ordered by. The incrementing columns are used when empty
+	StartPosition             string              // StartPosition is the incrementing column value the tables without position are read after
+	EndPosition               string              // EndPosition is the incrementing column value the tables are read up to
+	PrimaryKeyColNames        []string            // PrimaryKeyColNames are the primary key columns. These are used as record key
+	KeyColumns                []string            // KeyColumns are the columns the record key is built from. The primary key is used when empty
+	KeyFormat                 string              // KeyFormat is the encoding of the record key, json or gob
+	NullHandling              string              // NullHandling is how NULL values are written to the payload, null or omit
+	OnConversionError         string              // OnConversionError is what happens to rows which can't be converted, fail, skip or dlq
+	MaxConcurrentReads        int                 // MaxConcurrentReads limits how many tables are queried at the same time
+	BytesEncoding             string              // BytesEncoding is the encoding used for BYTES columns
+	JSONAsString              bool                // JSONAsString keeps JSON columns as raw strings
+	TimestampFormat           string              // TimestampFormat is the layout, rfc3339 or unix used for TIMESTAMP columns
+	TimestampLocation         *time.Location      // TimestampLocation is the time zone TIMESTAMP columns are formatted in
+	Filter                    string              // Filter is the SQL condition rows need to match to be synced
+	SamplePercent             float64             // SamplePercent is the percentage of the table blocks read. All rows are read when 0
+	Query                     string              // Query is the custom SQL query synced instead of the tables
+	Columns                   []string            // Columns are the columns selected. All columns are selected when empty
+	Partitions                []Partition         // Partitions are the time partitions read. All partitions are read when empty
+	PartitionField            string              // PartitionField is the column the tables are partitioned by
+	PartitionLookback         time.Duration       // PartitionLookback is the window of partitions read from tables requiring a partition filter
+	IncludePseudoColumns      bool                // IncludePseudoColumns adds the partition pseudo columns to the payload
+	ExcludeColumns            []string            // ExcludeColumns are the columns dropped from the records
+	BatchSize                 int                 // BatchSize is the number of rows fetched by each query
+	MaxRows                   int                 // MaxRows is the total number of records emitted before reading stops. No limit when 0
+	BufferSize                int                 // BufferSize is the number of records buffered before the tables are read any further
+	ReadMode                  string              // ReadMode decides if snapshots are read with paginated queries or the storage API
+	ReadStreams               int                 // ReadStreams is the number of parallel streams a snapshot is split across
+	MaterializeSnapshot       bool                // MaterializeSnapshot reads snapshots from a temporary table written by a single query
+	KeyCacheSize              int                 // KeyCacheSize is the number of record keys remembered to detect updated rows
+	Mode                      string              // Mode decides if tables are snapshot before reading their changes
+	CDCMode                   string              // CDCMode decides if changes are polled or read from the change history
+	DetectDeletes             bool                // DetectDeletes compares the primary keys of the tables to find deleted rows
+	SkipTableValidation       bool                // SkipTableValidation skips checking that the configured tables exist on open
+	DetectDeletesInterval     time.Duration       // DetectDeletesInterval is the time between two scans of the primary keys of a table
+	MaxRetries                int                 // MaxRetries is the number of retries of queries failing with transient errors
+	RetryDelay                time.Duration       // RetryDelay is the delay before the first retry of a query
+	JobTimeout                time.Duration       // JobTimeout is how long a query job may run before it is canceled. No timeout when 0
+	QueryLabels               map[string]string   // QueryLabels are the labels set on every query job
+	MaxBytesBilled            int64               // MaxBytesBilled limits the bytes billed for every query job. No limit when 0
+	DryRun                    bool                // DryRun only estimates the bytes processed by the queries without reading rows
+	UseQueryCache             bool                // UseQueryCache lets query jobs return cached results
+	QueryPriority             string              // QueryPriority is the priority query jobs run with
 }
 
 var (
 	// CounterLimit sets limit of how many rows will be fetched in each job
 	CounterLimit = 500
 	PollingTime  = time.Minute * 5
-	TimeoutTime  = time.Second * 120
+	// MinPollingTime is the smallest polling time allowed without ConfigAllowFastPolling
+	MinPollingTime = time.Second
+	// MaxConcurrentReads is the default number of tables queried at the same time
+	MaxConcurrentReads = 4
+	// BufferSize is the default number of records buffered before the tables are read any further
+	BufferSize = 100
+	// KeyCacheSize is the default number of record keys remembered to detect updated rows
+	KeyCacheSize = 10000
+	// DetectDeletesInterval is the default time between two scans of the primary keys of a table
+	DetectDeletesInterval = time.Hour
+	// MaxRetries is the default number of retries of queries failing with transient errors
+	MaxRetries = 3
+	// RetryDelay is the default delay before the first retry of a query
+	RetryDelay  = time.Second
+	TimeoutTime = time.Second * 120
+)
+
+var (
+	// ErrEmptyConfig is returned when no parameter is configured
+	ErrEmptyConfig = errors.New("empty config found")
+	// ErrMissingParameter is returned when a required parameter is blank
+	ErrMissingParameter = errors.New("missing required parameter")
+	// ErrNoTableID is returned when the destination has no table to write to
+	ErrNoTableID = errors.New("table ID blank")
+	// ErrInvalidPollingTime is returned when the polling time or max polling time can't be used
+	ErrInvalidPollingTime = errors.New("invalid polling time")
+	// ErrUnknownKeys is returned when keys which aren't parameters of the connector are configured
+	ErrUnknownKeys = errors.New("unknown configuration keys")
 )
 
 // SourceConfig is config for source
@@ -77,49 +435,834 @@ func ParseSourceConfig(cfg map[string]string) (SourceConfig, error) {
 		log.Println("Empty config found:", err)
 		return SourceConfig{}, err
 	}
+	if err := checkUnknownKeys(cfg, SourceParameters()); err != nil {
+		return SourceConfig{}, err
+	}
+
+	if err := validateConnection(cfg); err != nil {
+		return SourceConfig{}, err
+	}
+
+	primaryKeyColNames := splitList(cfg[ConfigPrimaryKeyColName])
+	if len(primaryKeyColNames) == 0 {
+		return SourceConfig{}, fmt.Errorf("%w: %s", ErrMissingParameter, ConfigPrimaryKeyColName)
+	}
+
+	var tableIncludeRegex, tableExcludeRegex *regexp.Regexp
+	if len(cfg[ConfigTableIncludeRegex]) > 0 {
+		tableIncludeRegex, err = regexp.Compile(cfg[ConfigTableIncludeRegex])
+		if err != nil {
+			return SourceConfig{}, fmt.Errorf("invalid table include regex: %w", err)
+		}
+	}
+
+	if len(cfg[ConfigTableExcludeRegex]) > 0 {
+		tableExcludeRegex, err = regexp.Compile(cfg[ConfigTableExcludeRegex])
+		if err != nil {
+			return SourceConfig{}, fmt.Errorf("invalid table exclude regex: %w", err)
+		}
+	}
+
+	incrementColNames, tableIncrementColNames, err := parseTableColumns(cfg[ConfigIncrementalColName])
+	if err != nil {
+		return SourceConfig{}, fmt.Errorf("invalid incrementing column name: %w", err)
+	}
+
+	maxConcurrentReads := MaxConcurrentReads
+	if len(cfg[ConfigMaxConcurrentReads]) > 0 {
+		maxConcurrentReads, err = strconv.Atoi(cfg[ConfigMaxConcurrentReads])
+		if err != nil || maxConcurrentReads <= 0 {
+			return SourceConfig{}, fmt.Errorf("max concurrent reads should be a positive integer, got %q", cfg[ConfigMaxConcurrentReads])
+		}
+	}
+
+	batchSize := CounterLimit
+	if len(cfg[ConfigBatchSize]) > 0 {
+		batchSize, err = strconv.Atoi(cfg[ConfigBatchSize])
+		if err != nil || batchSize <= 0 {
+			return SourceConfig{}, fmt.Errorf("batch size should be a positive integer, got %q", cfg[ConfigBatchSize])
+		}
+	}
+
+	var maxRows int
+	if len(cfg[ConfigMaxRows]) > 0 {
+		maxRows, err = strconv.Atoi(cfg[ConfigMaxRows])
+		if err != nil || maxRows <= 0 {
+			return SourceConfig{}, fmt.Errorf("max rows should be a positive integer, got %q", cfg[ConfigMaxRows])
+		}
+	}
+
+	bufferSize := BufferSize
+	if len(cfg[ConfigBufferSize]) > 0 {
+		bufferSize, err = strconv.Atoi(cfg[ConfigBufferSize])
+		if err != nil || bufferSize <= 0 {
+			return SourceConfig{}, fmt.Errorf("buffer size should be a positive integer, got %q", cfg[ConfigBufferSize])
+		}
+	}
+
+	readMode := ReadModeQuery
+	if len(cfg[ConfigReadMode]) > 0 {
+		readMode = cfg[ConfigReadMode]
+		if readMode != ReadModeQuery && readMode != ReadModeStorage {
+			return SourceConfig{}, fmt.Errorf("read mode should be %q or %q, got %q", ReadModeQuery, ReadModeStorage, readMode)
+		}
+	}
+
+	readStreams := 1
+	if len(cfg[ConfigReadStreams]) > 0 {
+		readStreams, err = strconv.Atoi(cfg[ConfigReadStreams])
+		if err != nil || readStreams <= 0 {
+			return SourceConfig{}, fmt.Errorf("read streams should be a positive integer, got %q", cfg[ConfigReadStreams])
+		}
+		if readStreams > 1 && readMode != ReadModeStorage {
+			return SourceConfig{}, fmt.Errorf("read streams can only be used with read mode %q", ReadModeStorage)
+		}
+	}
+
+	var materializeSnapshot bool
+	if len(cfg[ConfigMaterializeSnapshot]) > 0 {
+		materializeSnapshot, err = strconv.ParseBool(cfg[ConfigMaterializeSnapshot])
+		if err != nil {
+			return SourceConfig{}, fmt.Errorf("materialize snapshot should be a boolean, got %q", cfg[ConfigMaterializeSnapshot])
+		}
+		if materializeSnapshot && readStreams > 1 {
+			return SourceConfig{}, errors.New("materialize snapshot can't be used with read streams")
+		}
+	}
+	if readMode == ReadModeStorage && len(cfg[ConfigEndpoint]) > 0 {
+		// the Storage Read API is served over gRPC by a different endpoint
+		return SourceConfig{}, fmt.Errorf("read mode %q can't be used with a custom endpoint", ReadModeStorage)
+	}
+
+	keyCacheSize := KeyCacheSize
+	if len(cfg[ConfigKeyCacheSize]) > 0 {
+		keyCacheSize, err = strconv.Atoi(cfg[ConfigKeyCacheSize])
+		if err != nil || keyCacheSize < 0 {
+			return SourceConfig{}, fmt.Errorf("key cache size should be a non negative integer, got %q", cfg[ConfigKeyCacheSize])
+		}
+	}
+
+	bytesEncoding := BytesEncodingBase64
+	if len(cfg[ConfigBytesEncoding]) > 0 {
+		bytesEncoding = cfg[ConfigBytesEncoding]
+		if bytesEncoding != BytesEncodingBase64 && bytesEncoding != BytesEncodingHex {
+			return SourceConfig{}, fmt.Errorf("bytes encoding should be %q or %q, got %q", BytesEncodingBase64, BytesEncodingHex, bytesEncoding)
+		}
+	}
+
+	keyFormat := KeyFormatJSON
+	if len(cfg[ConfigKeyFormat]) > 0 {
+		keyFormat = cfg[ConfigKeyFormat]
+		if keyFormat != KeyFormatJSON && keyFormat != KeyFormatGob {
+			return SourceConfig{}, fmt.Errorf("key format should be %q or %q, got %q", KeyFormatJSON, KeyFormatGob, keyFormat)
+		}
+	}
+
+	nullHandling := NullHandlingNull
+	if len(cfg[ConfigNullHandling]) > 0 {
+		nullHandling = cfg[ConfigNullHandling]
+		if nullHandling != NullHandlingNull && nullHandling != NullHandlingOmit {
+			return SourceConfig{}, fmt.Errorf("null handling should be %q or %q, got %q", NullHandlingNull, NullHandlingOmit, nullHandling)
+		}
+	}
+
+	onConversionError := OnConversionErrorFail
+	if len(cfg[ConfigOnConversionError]) > 0 {
+		onConversionError = cfg[ConfigOnConversionError]
+		if onConversionError != OnConversionErrorFail && onConversionError != OnConversionErrorSkip && onConversionError != OnConversionErrorDLQ {
+			return SourceConfig{}, fmt.Errorf("%s should be %q, %q or %q, got %q", ConfigOnConversionError,
+				OnConversionErrorFail, OnConversionErrorSkip, OnConversionErrorDLQ, onConversionError)
+		}
+	}
+
+	jsonAsString := false
+	if len(cfg[ConfigJSONAsString]) > 0 {
+		jsonAsString, err = strconv.ParseBool(cfg[ConfigJSONAsString])
+		if err != nil {
+			return SourceConfig{}, fmt.Errorf("json as string should be a boolean, got %q", cfg[ConfigJSONAsString])
+		}
+	}
 
-	if _, ok := cfg[ConfigServiceAccount]; !ok {
-		return SourceConfig{}, errors.New("service account can't be blank")
+	pollingTime := PollingTime
+	if len(cfg[ConfigPollingTime]) > 0 {
+		pollingTime, err = time.ParseDuration(cfg[ConfigPollingTime])
+		if err != nil || pollingTime <= 0 {
+			return SourceConfig{}, fmt.Errorf("%w: should be a positive duration, got %q", ErrInvalidPollingTime, cfg[ConfigPollingTime])
+		}
 	}
 
-	if _, ok := cfg[ConfigProjectID]; !ok {
-		return SourceConfig{}, errors.New("project ID can't be blank")
+	allowFastPolling := false
+	if len(cfg[ConfigAllowFastPolling]) > 0 {
+		allowFastPolling, err = strconv.ParseBool(cfg[ConfigAllowFastPolling])
+		if err != nil {
+			return SourceConfig{}, fmt.Errorf("allow fast polling should be a boolean, got %q", cfg[ConfigAllowFastPolling])
+		}
+	}
+	if pollingTime < MinPollingTime && !allowFastPolling {
+		return SourceConfig{}, fmt.Errorf("%w: %v is below %v and would query BigQuery very often, set %s to allow it",
+			ErrInvalidPollingTime, pollingTime, MinPollingTime, ConfigAllowFastPolling)
+	}
+
+	var maxPollingTime time.Duration
+	if len(cfg[ConfigMaxPollingTime]) > 0 {
+		maxPollingTime, err = time.ParseDuration(cfg[ConfigMaxPollingTime])
+		if err != nil || maxPollingTime <= 0 {
+			return SourceConfig{}, fmt.Errorf("%w: max polling time should be a positive duration, got %q", ErrInvalidPollingTime, cfg[ConfigMaxPollingTime])
+		}
+		if maxPollingTime < pollingTime {
+			return SourceConfig{}, fmt.Errorf("%w: max polling time %v can't be smaller than the polling time %v", ErrInvalidPollingTime, maxPollingTime, pollingTime)
+		}
+	}
+
+	detectDeletes := false
+	if len(cfg[ConfigDetectDeletes]) > 0 {
+		detectDeletes, err = strconv.ParseBool(cfg[ConfigDetectDeletes])
+		if err != nil {
+			return SourceConfig{}, fmt.Errorf("detect deletes should be a boolean, got %q", cfg[ConfigDetectDeletes])
+		}
+	}
+	keyColumns := splitList(cfg[ConfigKeyColumns])
+	if len(keyColumns) > 0 && detectDeletes {
+		// deleted rows are only known by their primary key
+		return SourceConfig{}, errors.New("key columns can't be used with detect deletes")
+	}
+
+	detectDeletesInterval := DetectDeletesInterval
+	if len(cfg[ConfigDetectDeletesInterval]) > 0 {
+		detectDeletesInterval, err = time.ParseDuration(cfg[ConfigDetectDeletesInterval])
+		if err != nil || detectDeletesInterval <= 0 {
+			return SourceConfig{}, fmt.Errorf("detect deletes interval should be a positive duration, got %q", cfg[ConfigDetectDeletesInterval])
+		}
+	}
+
+	skipTableValidation := false
+	if len(cfg[ConfigSkipTableValidation]) > 0 {
+		skipTableValidation, err = strconv.ParseBool(cfg[ConfigSkipTableValidation])
+		if err != nil {
+			return SourceConfig{}, fmt.Errorf("skip table validation should be a boolean, got %q", cfg[ConfigSkipTableValidation])
+		}
+	}
+
+	maxRetries := MaxRetries
+	if len(cfg[ConfigMaxRetries]) > 0 {
+		maxRetries, err = strconv.Atoi(cfg[ConfigMaxRetries])
+		if err != nil || maxRetries < 0 {
+			return SourceConfig{}, fmt.Errorf("max retries should be a non negative integer, got %q", cfg[ConfigMaxRetries])
+		}
+	}
+
+	retryDelay := RetryDelay
+	if len(cfg[ConfigRetryDelay]) > 0 {
+		retryDelay, err = time.ParseDuration(cfg[ConfigRetryDelay])
+		if err != nil || retryDelay <= 0 {
+			return SourceConfig{}, fmt.Errorf("retry delay should be a positive duration, got %q", cfg[ConfigRetryDelay])
+		}
+	}
+
+	var heartbeatInterval time.Duration
+	if len(cfg[ConfigHeartbeatInterval]) > 0 {
+		heartbeatInterval, err = time.ParseDuration(cfg[ConfigHeartbeatInterval])
+		if err != nil || heartbeatInterval <= 0 {
+			return SourceConfig{}, fmt.Errorf("heartbeat interval should be a positive duration, got %q", cfg[ConfigHeartbeatInterval])
+		}
+	}
+
+	var jobTimeout time.Duration
+	if len(cfg[ConfigJobTimeout]) > 0 {
+		jobTimeout, err = time.ParseDuration(cfg[ConfigJobTimeout])
+		if err != nil || jobTimeout <= 0 {
+			return SourceConfig{}, fmt.Errorf("job timeout should be a positive duration, got %q", cfg[ConfigJobTimeout])
+		}
+	}
+
+	timestampFormat := DefaultTimestampLayout
+	if len(cfg[ConfigTimestampFormat]) > 0 {
+		timestampFormat = cfg[ConfigTimestampFormat]
+		if err := validateTimestampFormat(timestampFormat); err != nil {
+			return SourceConfig{}, err
+		}
+	}
+
+	timestampLocation := time.UTC
+	if len(cfg[ConfigTimestampLocation]) > 0 {
+		timestampLocation, err = time.LoadLocation(cfg[ConfigTimestampLocation])
+		if err != nil {
+			return SourceConfig{}, fmt.Errorf("invalid timestamp location: %w", err)
+		}
+	}
+
+	query := strings.TrimSuffix(strings.TrimSpace(cfg[ConfigQuery]), ";")
+	if len(query) > 0 && len(strings.TrimSpace(cfg[ConfigFilter])) > 0 {
+		return SourceConfig{}, errors.New("filter can't be used together with a custom query, add the condition to the query instead")
+	}
+
+	mode := ModeSnapshot
+	if len(cfg[ConfigMode]) > 0 {
+		mode = cfg[ConfigMode]
+		if mode != ModeSnapshot && mode != ModeCDC {
+			return SourceConfig{}, fmt.Errorf("mode should be %q or %q, got %q", ModeSnapshot, ModeCDC, mode)
+		}
+	}
+
+	cdcMode := CDCModePolling
+	if len(cfg[ConfigCDCMode]) > 0 {
+		cdcMode = cfg[ConfigCDCMode]
+		if cdcMode != CDCModePolling && cdcMode != CDCModeChangeHistory {
+			return SourceConfig{}, fmt.Errorf("cdc mode should be %q or %q, got %q", CDCModePolling, CDCModeChangeHistory, cdcMode)
+		}
+		if cdcMode == CDCModeChangeHistory && len(query) > 0 {
+			return SourceConfig{}, errors.New("change history can't be read for a custom query")
+		}
+	}
+
+	incrementOrder := IncrementOrderAsc
+	if len(cfg[ConfigIncrementOrder]) > 0 {
+		incrementOrder = cfg[ConfigIncrementOrder]
+		if incrementOrder != IncrementOrderAsc && incrementOrder != IncrementOrderDesc {
+			return SourceConfig{}, fmt.Errorf("increment order should be %q or %q, got %q", IncrementOrderAsc, IncrementOrderDesc, incrementOrder)
+		}
+	}
+	if incrementOrder == IncrementOrderDesc {
+		// these start from or merge into the greatest incrementing column value
+		switch {
+		case mode == ModeCDC:
+			return SourceConfig{}, fmt.Errorf("increment order %q can't be used with mode %q", IncrementOrderDesc, ModeCDC)
+		case cdcMode == CDCModeChangeHistory:
+			return SourceConfig{}, fmt.Errorf("increment order %q can't be used with cdc mode %q", IncrementOrderDesc, CDCModeChangeHistory)
+		case readStreams > 1:
+			return SourceConfig{}, fmt.Errorf("increment order %q can't be used with read streams", IncrementOrderDesc)
+		}
+	}
+
+	queryLabels, err := parseLabels(cfg[ConfigQueryLabels])
+	if err != nil {
+		return SourceConfig{}, fmt.Errorf("invalid query labels: %w", err)
 	}
 
-	if _, ok := cfg[ConfigDatasetID]; !ok {
-		return SourceConfig{}, errors.New("dataset ID can't be blank")
+	var maxBytesBilled int64
+	if len(cfg[ConfigMaxBytesBilled]) > 0 {
+		maxBytesBilled, err = strconv.ParseInt(cfg[ConfigMaxBytesBilled], 10, 64)
+		if err != nil || maxBytesBilled <= 0 {
+			return SourceConfig{}, fmt.Errorf("max bytes billed should be a positive integer, got %q", cfg[ConfigMaxBytesBilled])
+		}
 	}
 
-	if _, ok := cfg[ConfigLocation]; !ok {
-		return SourceConfig{}, errors.New("location can't be blank")
+	dryRun := false
+	if len(cfg[ConfigDryRun]) > 0 {
+		dryRun, err = strconv.ParseBool(cfg[ConfigDryRun])
+		if err != nil {
+			return SourceConfig{}, fmt.Errorf("dry run should be a boolean, got %q", cfg[ConfigDryRun])
+		}
 	}
 
-	if _, ok := cfg[ConfigTableID]; !ok {
-		return SourceConfig{}, errors.New("tableID can't be blank")
+	useQueryCache := true
+	if len(cfg[ConfigUseQueryCache]) > 0 {
+		useQueryCache, err = strconv.ParseBool(cfg[ConfigUseQueryCache])
+		if err != nil {
+			return SourceConfig{}, fmt.Errorf("use query cache should be a boolean, got %q", cfg[ConfigUseQueryCache])
+		}
 	}
 
-	if _, ok := cfg[ConfigPrimaryKeyColName]; !ok {
-		return SourceConfig{}, errors.New("primary key can't be blank")
+	queryPriority := QueryPriorityInteractive
+	if len(cfg[ConfigQueryPriority]) > 0 {
+		queryPriority = cfg[ConfigQueryPriority]
+		if queryPriority != QueryPriorityInteractive && queryPriority != QueryPriorityBatch {
+			return SourceConfig{}, fmt.Errorf("query priority should be %q or %q, got %q", QueryPriorityInteractive, QueryPriorityBatch, queryPriority)
+		}
+	}
+
+	partitions, err := parsePartitions(cfg[ConfigPartitions])
+	if err != nil {
+		return SourceConfig{}, err
+	}
+	if len(partitions) > 0 && len(query) > 0 {
+		return SourceConfig{}, errors.New("partitions can't be used together with a custom query, add the condition to the query instead")
+	}
+	if len(partitions) > 0 && cdcMode == CDCModeChangeHistory {
+		return SourceConfig{}, errors.New("partitions can't be used with change history")
+	}
+
+	includePseudoColumns := false
+	if len(cfg[ConfigIncludePseudoColumns]) > 0 {
+		includePseudoColumns, err = strconv.ParseBool(cfg[ConfigIncludePseudoColumns])
+		if err != nil {
+			return SourceConfig{}, fmt.Errorf("include pseudo columns should be a boolean, got %q", cfg[ConfigIncludePseudoColumns])
+		}
+		switch {
+		case !includePseudoColumns:
+		case len(query) > 0:
+			return SourceConfig{}, errors.New("pseudo columns can't be included with a custom query, select them in the query instead")
+		case cdcMode == CDCModeChangeHistory:
+			return SourceConfig{}, errors.New("pseudo columns can't be included with change history")
+		}
+	}
+
+	var samplePercent float64
+	if len(cfg[ConfigSamplePercent]) > 0 {
+		samplePercent, err = strconv.ParseFloat(cfg[ConfigSamplePercent], 64)
+		if err != nil || samplePercent <= 0 || samplePercent > 100 {
+			return SourceConfig{}, fmt.Errorf("sample percent should be a number above 0 and up to 100, got %q", cfg[ConfigSamplePercent])
+		}
+		switch {
+		case len(query) > 0:
+			return SourceConfig{}, errors.New("sample percent can't be used together with a custom query, add TABLESAMPLE to the query instead")
+		case cdcMode == CDCModeChangeHistory:
+			return SourceConfig{}, errors.New("sample percent can't be used with change history")
+		}
+	}
+	var partitionLookback time.Duration
+	if len(cfg[ConfigPartitionLookback]) > 0 {
+		partitionLookback, err = time.ParseDuration(cfg[ConfigPartitionLookback])
+		if err != nil || partitionLookback <= 0 {
+			return SourceConfig{}, fmt.Errorf("partition lookback should be a positive duration, got %q", cfg[ConfigPartitionLookback])
+		}
+	}
+	partitionField := DefaultPartitionField
+	if len(cfg[ConfigPartitionField]) > 0 {
+		partitionField = cfg[ConfigPartitionField]
+	}
+
+	startPosition := strings.TrimSpace(cfg[ConfigStartPosition])
+	endPosition := strings.TrimSpace(cfg[ConfigEndPosition])
+	for _, position := range []struct{ name, value string }{{"start position", startPosition}, {"end position", endPosition}} {
+		name := position.name
+		if len(position.value) == 0 {
+			continue
+		}
+		switch {
+		case len(query) > 0:
+			return SourceConfig{}, fmt.Errorf("%s can't be used with a custom query, add the condition to the query instead", name)
+		case cdcMode == CDCModeChangeHistory:
+			return SourceConfig{}, fmt.Errorf("%s can't be used with change history", name)
+		case compositeColumns(incrementColNames, tableIncrementColNames, primaryKeyColNames):
+			return SourceConfig{}, fmt.Errorf("%s can only be used with a single incrementing column", name)
+		}
+	}
+
+	excludeColumns := splitList(cfg[ConfigExcludeColumns])
+	requiredColumns := append(append([]string{}, incrementColNames...), primaryKeyColNames...)
+	for _, columns := range tableIncrementColNames {
+		requiredColumns = append(requiredColumns, columns...)
+	}
+	for _, column := range excludeColumns {
+		for _, required := range requiredColumns {
+			if column == required {
+				return SourceConfig{}, fmt.Errorf("column %q can't be excluded as it is used as primary key or incrementing column", column)
+			}
+		}
 	}
 
 	config := Config{
-		ServiceAccount:    cfg[ConfigServiceAccount],
-		ProjectID:         cfg[ConfigProjectID],
-		DatasetID:         cfg[ConfigDatasetID],
-		TableID:           cfg[ConfigTableID],
-		Location:          cfg[ConfigLocation],
-		PollingTime:       cfg[ConfigPollingTime],
-		IncrementColName:  cfg[ConfigIncrementalColName],
-		PrimaryKeyColName: cfg[ConfigPrimaryKeyColName]}
+		ServiceAccount:            cfg[ConfigServiceAccount],
+		ServiceAccountJSON:        cfg[ConfigServiceAccountJSON],
+		ServiceAccountBase64:      cfg[ConfigServiceAccountBase64],
+		ImpersonateServiceAccount: cfg[ConfigImpersonateServiceAccount],
+		ImpersonateDelegates:      splitList(cfg[ConfigImpersonateDelegates]),
+		Scopes:                    splitList(cfg[ConfigScopes]),
+		Endpoint:                  cfg[ConfigEndpoint],
+		Insecure:                  insecure(cfg),
+		ProjectID:                 cfg[ConfigProjectID],
+		DatasetID:                 cfg[ConfigDatasetID],
+		TableIDs:                  splitList(cfg[ConfigTableID]),
+		TableIncludeRegex:         tableIncludeRegex,
+		TableExcludeRegex:         tableExcludeRegex,
+		Location:                  cfg[ConfigLocation],
+		LogLevel:                  cfg[ConfigLogLevel],
+		PollingTime:               cfg[ConfigPollingTime],
+		MaxPollingTime:            maxPollingTime,
+		HeartbeatInterval:         heartbeatInterval,
+		IncrementColNames:         incrementColNames,
+		TableIncrementColNames:    tableIncrementColNames,
+		IncrementOrder:            incrementOrder,
+		OrderBy:                   splitList(cfg[ConfigOrderBy]),
+		StartPosition:             startPosition,
+		EndPosition:               endPosition,
+		MaxConcurrentReads:        maxConcurrentReads,
+		BytesEncoding:             bytesEncoding,
+		JSONAsString:              jsonAsString,
+		TimestampFormat:           timestampFormat,
+		TimestampLocation:         timestampLocation,
+		Filter:                    strings.TrimSpace(cfg[ConfigFilter]),
+		SamplePercent:             samplePercent,
+		Query:                     query,
+		Columns:                   splitList(cfg[ConfigColumns]),
+		Partitions:                partitions,
+		PartitionField:            partitionField,
+		PartitionLookback:         partitionLookback,
+		IncludePseudoColumns:      includePseudoColumns,
+		ExcludeColumns:            excludeColumns,
+		BatchSize:                 batchSize,
+		MaxRows:                   maxRows,
+		BufferSize:                bufferSize,
+		ReadMode:                  readMode,
+		ReadStreams:               readStreams,
+		MaterializeSnapshot:       materializeSnapshot,
+		KeyCacheSize:              keyCacheSize,
+		Mode:                      mode,
+		CDCMode:                   cdcMode,
+		DetectDeletes:             detectDeletes,
+		SkipTableValidation:       skipTableValidation,
+		DetectDeletesInterval:     detectDeletesInterval,
+		MaxRetries:                maxRetries,
+		RetryDelay:                retryDelay,
+		JobTimeout:                jobTimeout,
+		QueryLabels:               queryLabels,
+		MaxBytesBilled:            maxBytesBilled,
+		DryRun:                    dryRun,
+		UseQueryCache:             useQueryCache,
+		QueryPriority:             queryPriority,
+		KeyColumns:                keyColumns,
+		KeyFormat:                 keyFormat,
+		NullHandling:              nullHandling,
+		OnConversionError:         onConversionError,
+		PrimaryKeyColNames:        primaryKeyColNames}
 
 	return SourceConfig{
 		Config: config,
 	}, nil
 }
 
+// DestinationConfig is config for destination
+type DestinationConfig struct {
+	Config     Config // Config holds the credentials, project, dataset and primary key shared with the source
+	TableID    string // TableID is the table records are written to
+	WriteMode  string // WriteMode decides if records are appended or merged by primary key
+	AutoCreate bool   // AutoCreate creates the table from the first record when it doesn't exist
+}
+
+// ParseDestinationConfig parses the config of the destination. Credentials are handled the same way
+// as for the source.
+func ParseDestinationConfig(cfg map[string]string) (DestinationConfig, error) {
+	if err := checkEmpty(cfg); err != nil {
+		return DestinationConfig{}, err
+	}
+	if err := checkUnknownKeys(cfg, DestinationParameters()); err != nil {
+		return DestinationConfig{}, err
+	}
+	if err := validateConnection(cfg); err != nil {
+		return DestinationConfig{}, err
+	}
+	if len(cfg[ConfigLocation]) == 0 {
+		return DestinationConfig{}, fmt.Errorf("%w: %s", ErrMissingParameter, ConfigLocation)
+	}
+
+	tableIDs := splitList(cfg[ConfigTableID])
+	if len(tableIDs) == 0 {
+		return DestinationConfig{}, fmt.Errorf("%w, set %s to the table the records are written to", ErrNoTableID, ConfigTableID)
+	}
+	if len(tableIDs) != 1 {
+		return DestinationConfig{}, fmt.Errorf("exactly one table ID should be provided, got %q", cfg[ConfigTableID])
+	}
+
+	writeMode := WriteModeInsert
+	if len(cfg[ConfigWriteMode]) > 0 {
+		writeMode = cfg[ConfigWriteMode]
+		if writeMode != WriteModeInsert && writeMode != WriteModeUpsert {
+			return DestinationConfig{}, fmt.Errorf("write mode should be %q or %q, got %q", WriteModeInsert, WriteModeUpsert, writeMode)
+		}
+	}
+
+	primaryKeyColNames := splitList(cfg[ConfigPrimaryKeyColName])
+	if writeMode == WriteModeUpsert && len(primaryKeyColNames) == 0 {
+		return DestinationConfig{}, errors.New("primary key columns should be provided to upsert records")
+	}
+
+	autoCreate := false
+	if len(cfg[ConfigAutoCreate]) > 0 {
+		var err error
+		autoCreate, err = strconv.ParseBool(cfg[ConfigAutoCreate])
+		if err != nil {
+			return DestinationConfig{}, fmt.Errorf("auto create should be a boolean, got %q", cfg[ConfigAutoCreate])
+		}
+	}
+
+	return DestinationConfig{
+		Config: Config{
+			ServiceAccount:            cfg[ConfigServiceAccount],
+			ServiceAccountJSON:        cfg[ConfigServiceAccountJSON],
+			ServiceAccountBase64:      cfg[ConfigServiceAccountBase64],
+			ImpersonateServiceAccount: cfg[ConfigImpersonateServiceAccount],
+			ImpersonateDelegates:      splitList(cfg[ConfigImpersonateDelegates]),
+			Scopes:                    splitList(cfg[ConfigScopes]),
+			Endpoint:                  cfg[ConfigEndpoint],
+			Insecure:                  insecure(cfg),
+			ProjectID:                 cfg[ConfigProjectID],
+			DatasetID:                 cfg[ConfigDatasetID],
+			TableIDs:                  tableIDs,
+			Location:                  cfg[ConfigLocation],
+			LogLevel:                  cfg[ConfigLogLevel],
+			PrimaryKeyColNames:        primaryKeyColNames,
+		},
+		TableID:    tableIDs[0],
+		WriteMode:  writeMode,
+		AutoCreate: autoCreate,
+	}, nil
+}
+
+// validateConnection validates the credentials, project and dataset shared by the source and the
+// destination. The source detects the location of the dataset when it is blank.
+func validateConnection(cfg map[string]string) error {
+	if serviceAccountJSON := cfg[ConfigServiceAccountJSON]; len(serviceAccountJSON) > 0 && !json.Valid([]byte(serviceAccountJSON)) {
+		return errors.New("service account JSON is not valid JSON")
+	}
+
+	if serviceAccountBase64 := cfg[ConfigServiceAccountBase64]; len(serviceAccountBase64) > 0 {
+		if _, err := base64.StdEncoding.DecodeString(serviceAccountBase64); err != nil {
+			return fmt.Errorf("service account base64 is malformed: %w", err)
+		}
+	}
+
+	if len(cfg[ConfigImpersonateDelegates]) > 0 && len(cfg[ConfigImpersonateServiceAccount]) == 0 {
+		return errors.New("impersonate delegates provided without an impersonate service account")
+	}
+
+	for _, key := range []string{ConfigProjectID, ConfigDatasetID} {
+		if len(cfg[key]) == 0 {
+			return fmt.Errorf("%w: %s", ErrMissingParameter, key)
+		}
+	}
+
+	if level := cfg[ConfigLogLevel]; len(level) > 0 {
+		if _, ok := logLevels[level]; !ok {
+			return fmt.Errorf("log level should be one of trace, debug, info, warn or error, got %q", level)
+		}
+	}
+
+	if endpoint := cfg[ConfigEndpoint]; len(endpoint) > 0 {
+		parsed, err := url.Parse(endpoint)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return fmt.Errorf("endpoint should be an absolute URL, eg. http://localhost:9050, got %q", endpoint)
+		}
+	}
+
+	if len(cfg[ConfigInsecure]) > 0 {
+		if _, err := strconv.ParseBool(cfg[ConfigInsecure]); err != nil {
+			return fmt.Errorf("insecure should be a boolean, got %q", cfg[ConfigInsecure])
+		}
+		// without a custom endpoint the requests would go to BigQuery itself, which rejects them
+		if insecure(cfg) && len(cfg[ConfigEndpoint]) == 0 {
+			return errors.New("insecure can only be used with a custom endpoint")
+		}
+	}
+	return nil
+}
+
+// insecure reports if requests are sent without credentials. The value is validated by validateConnection.
+func insecure(cfg map[string]string) bool {
+	value, _ := strconv.ParseBool(cfg[ConfigInsecure])
+	return value
+}
+
+// parseTableColumns parses column names given in the format table1:column1,table2:column2. An entry
+// without table name is returned as the default columns used for tables which are not listed.
+// Composite columns are wrapped in parentheses, eg. table1:(updated_at,id).
+func parseTableColumns(value string) (defaultColumns []string, tableColumns map[string][]string, err error) {
+	entries, err := splitColumnEntries(value)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tableColumns = make(map[string][]string)
+	for _, entry := range entries {
+		tableID, column := "", entry
+		if i := strings.Index(entry, ":"); i >= 0 {
+			tableID, column = strings.TrimSpace(entry[:i]), strings.TrimSpace(entry[i+1:])
+			if len(tableID) == 0 {
+				return nil, nil, fmt.Errorf("table name missing in %q", entry)
+			}
+		}
+
+		columns := []string{column}
+		if strings.HasPrefix(column, "(") && strings.HasSuffix(column, ")") {
+			columns = splitList(column[1 : len(column)-1])
+		}
+		if len(columns) == 0 || len(columns[0]) == 0 {
+			return nil, nil, fmt.Errorf("column name missing in %q", entry)
+		}
+
+		if len(tableID) == 0 {
+			if len(defaultColumns) > 0 {
+				return nil, nil, fmt.Errorf("more than one default column provided: %q, %q", strings.Join(defaultColumns, ","), column)
+			}
+			defaultColumns = columns
+			continue
+		}
+		if _, ok := tableColumns[tableID]; ok {
+			return nil, nil, fmt.Errorf("more than one column provided for table %q", tableID)
+		}
+		tableColumns[tableID] = columns
+	}
+	return defaultColumns, tableColumns, nil
+}
+
+// splitColumnEntries splits the comma separated entries of parseTableColumns. Commas inside
+// parentheses separate the columns of a composite entry and don't end the entry.
+func splitColumnEntries(value string) ([]string, error) {
+	var entries []string
+	depth, start := 0, 0
+	for i, c := range value {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced parentheses in %q", value)
+			}
+		case ',':
+			if depth == 0 {
+				entries = append(entries, value[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parentheses in %q", value)
+	}
+	entries = append(entries, value[start:])
+
+	var nonEmpty []string
+	for _, entry := range entries {
+		if entry = strings.TrimSpace(entry); len(entry) > 0 {
+			nonEmpty = append(nonEmpty, entry)
+		}
+	}
+	return nonEmpty, nil
+}
+
+// validateTimestampFormat checks the layout by formatting a reference time. A layout without any
+// time element would write the same constant string for every row.
+func validateTimestampFormat(format string) error {
+	if format == TimestampFormatRFC3339 || format == TimestampFormatUnix {
+		return nil
+	}
+	reference := time.Date(2022, time.March, 4, 5, 6, 7, 0, time.UTC)
+	if reference.Format(format) == format {
+		return fmt.Errorf("timestamp format %q does not contain any time element", format)
+	}
+	return nil
+}
+
+// partitionLayouts are the layouts of the IDs of yearly, monthly, daily and hourly partitions by their length
+var partitionLayouts = map[int]string{4: "2006", 6: "200601", 8: "20060102", 10: "2006010215"}
+
+// parsePartitions parses the comma separated partition IDs into the time ranges of their rows
+func parsePartitions(value string) ([]Partition, error) {
+	var partitions []Partition
+	for _, id := range splitList(value) {
+		layout, ok := partitionLayouts[len(id)]
+		if !ok {
+			return nil, fmt.Errorf("partition %q should be formatted as YYYY, YYYYMM, YYYYMMDD or YYYYMMDDHH", id)
+		}
+		start, err := time.Parse(layout, id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid partition %q: %w", id, err)
+		}
+
+		var end time.Time
+		literal := "2006-01-02"
+		switch len(id) {
+		case 4:
+			end = start.AddDate(1, 0, 0)
+		case 6:
+			end = start.AddDate(0, 1, 0)
+		case 8:
+			end = start.AddDate(0, 0, 1)
+		default:
+			end = start.Add(time.Hour)
+			literal = "2006-01-02 15:04:05"
+		}
+		partitions = append(partitions, Partition{ID: id, Start: start.Format(literal), End: end.Format(literal)})
+	}
+	return partitions, nil
+}
+
+// labelKeyRegex and labelValueRegex are the constraints BigQuery puts on the keys and values of labels
+var (
+	labelKeyRegex   = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,62}$`)
+	labelValueRegex = regexp.MustCompile(`^[a-z0-9_-]{0,63}$`)
+)
+
+// maxLabels is the maximum number of labels BigQuery allows on a job
+const maxLabels = 64
+
+// parseLabels parses labels given in the format key1=value1,key2=value2
+func parseLabels(value string) (map[string]string, error) {
+	entries := splitList(value)
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	if len(entries) > maxLabels {
+		return nil, fmt.Errorf("at most %d labels can be set, got %d", maxLabels, len(entries))
+	}
+
+	labels := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("label %q should be formatted as key=value", entry)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !labelKeyRegex.MatchString(key) {
+			return nil, fmt.Errorf("label key %q should start with a lowercase letter and only contain up to 63 lowercase letters, digits, underscores and dashes", key)
+		}
+		if !labelValueRegex.MatchString(value) {
+			return nil, fmt.Errorf("label value %q should only contain up to 63 lowercase letters, digits, underscores and dashes", value)
+		}
+		if _, ok := labels[key]; ok {
+			return nil, fmt.Errorf("label key %q is set more than once", key)
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// splitList splits a comma separated config value. Whitespace around the entries is trimmed and
+// empty entries are dropped.
+func splitList(value string) []string {
+	var list []string
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) > 0 {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
 func checkEmpty(cfg map[string]string) error {
 	if len(cfg) == 0 {
-		return fmt.Errorf("empty config found")
+		return ErrEmptyConfig
 	}
 	return nil
 }
+
+// checkUnknownKeys fails for keys which aren't parameters of the connector, eg. misspelled ones which
+// would otherwise be ignored. Keys of the SDK middleware, prefixed with sdk., are left to the SDK.
+func checkUnknownKeys(cfg map[string]string, params map[string]sdk.Parameter) error {
+	var unknown []string
+	for key := range cfg {
+		if _, ok := params[key]; !ok && !strings.HasPrefix(key, "sdk.") {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return fmt.Errorf("%w %s, check their spelling", ErrUnknownKeys, strings.Join(unknown, ", "))
+}
+
+// compositeColumns reports if any table is incremented by more than one column. Tables without
+// incrementing column are incremented by their primary key.
+func compositeColumns(incrementColNames []string, tableIncrementColNames map[string][]string, primaryKeyColNames []string) bool {
+	if len(incrementColNames) > 1 || (len(incrementColNames) == 0 && len(primaryKeyColNames) > 1) {
+		return true
+	}
+	for _, columns := range tableIncrementColNames {
+		if len(columns) > 1 {
+			return true
+		}
+	}
+	return false
+}
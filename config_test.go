@@ -15,7 +15,11 @@
 package googlebigquery
 
 import (
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestParseNoConfig(t *testing.T) {
@@ -41,6 +45,234 @@ func TestParseSourceConfigAllConfigPresent(t *testing.T) {
 	}
 }
 
+func TestParseSourceConfigWithoutServiceAccount(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigTableID] = "testTable"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.ServiceAccount != "" {
+		t.Errorf("expected blank service account, got %v", config.Config.ServiceAccount)
+	}
+}
+
+func TestParseSourceConfigInvalidServiceAccountJSON(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigServiceAccountJSON] = "{not json"
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigTableID] = "testTable"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	_, err := ParseSourceConfig(cfg)
+	if err == nil {
+		t.Errorf("parse source config, expected error for malformed service account JSON")
+	}
+}
+
+func TestParseSourceConfigInvalidServiceAccountBase64(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigServiceAccountBase64] = "not base64!"
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigTableID] = "testTable"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	_, err := ParseSourceConfig(cfg)
+	if err == nil {
+		t.Errorf("parse source config, expected error for malformed service account base64")
+	}
+}
+
+func TestParseSourceConfigImpersonateDelegates(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigTableID] = "testTable"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+	cfg[ConfigImpersonateDelegates] = "a@test.iam.gserviceaccount.com, ,b@test.iam.gserviceaccount.com"
+
+	_, err := ParseSourceConfig(cfg)
+	if err == nil {
+		t.Errorf("parse source config, expected error for delegates without impersonate service account")
+	}
+
+	cfg[ConfigImpersonateServiceAccount] = "target@test.iam.gserviceaccount.com"
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if len(config.Config.ImpersonateDelegates) != 2 {
+		t.Errorf("expected 2 delegates, got %v", config.Config.ImpersonateDelegates)
+	}
+}
+
+func TestParseSourceConfigScopes(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigTableID] = "testTable"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+	cfg[ConfigScopes] = " scope1 ,, scope2 , "
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if !reflect.DeepEqual(config.Config.Scopes, []string{"scope1", "scope2"}) {
+		t.Errorf("expected trimmed scopes, got %v", config.Config.Scopes)
+	}
+}
+
+func TestParseSourceConfigMultipleTables(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigTableID] = "table1, table2"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if !reflect.DeepEqual(config.Config.TableIDs, []string{"table1", "table2"}) {
+		t.Errorf("expected two tables, got %v", config.Config.TableIDs)
+	}
+
+	cfg[ConfigTableID] = " , "
+	config, err = ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if len(config.Config.TableIDs) != 0 {
+		t.Errorf("expected no tables so whole dataset is synced, got %v", config.Config.TableIDs)
+	}
+}
+
+func TestParseSourceConfigTableRegex(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+	cfg[ConfigTableIncludeRegex] = "^orders_"
+	cfg[ConfigTableExcludeRegex] = "_tmp$"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.TableIncludeRegex == nil || config.Config.TableExcludeRegex == nil {
+		t.Errorf("expected include and exclude regex to be set")
+	}
+
+	cfg[ConfigTableIncludeRegex] = "("
+	_, err = ParseSourceConfig(cfg)
+	if err == nil {
+		t.Errorf("parse source config, expected error for invalid regex")
+	}
+}
+
+func TestParseSourceConfigCompositeIncrementColumns(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigTableID] = "table1,table2"
+	cfg[ConfigPrimaryKeyColName] = "id"
+	cfg[ConfigIncrementalColName] = "table1:(updated_at, id), (created_at,id)"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if !reflect.DeepEqual(config.Config.IncrementColNames, []string{"created_at", "id"}) {
+		t.Errorf("expected default columns created_at,id, got %v", config.Config.IncrementColNames)
+	}
+	if !reflect.DeepEqual(config.Config.TableIncrementColNames, map[string][]string{"table1": {"updated_at", "id"}}) {
+		t.Errorf("expected columns per table, got %v", config.Config.TableIncrementColNames)
+	}
+
+	cfg[ConfigExcludeColumns] = "updated_at"
+	_, err = ParseSourceConfig(cfg)
+	if err == nil {
+		t.Errorf("parse source config, expected error for excluded incrementing column")
+	}
+}
+
+func TestParseSourceConfigIncrementColumnPerTable(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigTableID] = "table1,table2,table3"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+	cfg[ConfigIncrementalColName] = "table1:updated_at, table2:id, created_at"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if !reflect.DeepEqual(config.Config.IncrementColNames, []string{"created_at"}) {
+		t.Errorf("expected default column created_at, got %v", config.Config.IncrementColNames)
+	}
+	if !reflect.DeepEqual(config.Config.TableIncrementColNames, map[string][]string{"table1": {"updated_at"}, "table2": {"id"}}) {
+		t.Errorf("expected columns per table, got %v", config.Config.TableIncrementColNames)
+	}
+
+	for _, invalid := range []string{"id,updated_at", "table1:", ":id", "table1:id,table1:updated_at", "table1:()", "(updated_at,id", "updated_at,id)"} {
+		cfg[ConfigIncrementalColName] = invalid
+		_, err = ParseSourceConfig(cfg)
+		if err == nil {
+			t.Errorf("parse source config, expected error for %q", invalid)
+		}
+	}
+}
+
+func TestParseSourceConfigMaxConcurrentReads(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.MaxConcurrentReads != MaxConcurrentReads {
+		t.Errorf("expected default max concurrent reads, got %v", config.Config.MaxConcurrentReads)
+	}
+
+	cfg[ConfigMaxConcurrentReads] = "10"
+	config, err = ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.MaxConcurrentReads != 10 {
+		t.Errorf("expected 10 max concurrent reads, got %v", config.Config.MaxConcurrentReads)
+	}
+
+	for _, invalid := range []string{"0", "-1", "four"} {
+		cfg[ConfigMaxConcurrentReads] = invalid
+		_, err = ParseSourceConfig(cfg)
+		if err == nil {
+			t.Errorf("parse source config, expected error for %q", invalid)
+		}
+	}
+}
+
 func TestParseSourceConfigPartialConfig(t *testing.T) {
 	cfg := map[string]string{}
 	delete(cfg, ConfigServiceAccount)
@@ -74,12 +306,1456 @@ func TestParseSourceConfigPartialConfig(t *testing.T) {
 	cfg[ConfigDatasetID] = "test"
 	delete(cfg, ConfigLocation)
 
-	_, err = ParseSourceConfig(cfg)
-	if err == nil {
+	// the location of the dataset is detected on open
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
 		t.Errorf("parse source config, got error %v", err)
 	}
+	if config.Config.Location != "" {
+		t.Errorf("expected blank location, got %q", config.Config.Location)
+	}
 }
 
 func TestSpecification(t *testing.T) {
 	Specification()
 }
+
+func TestParseSourceConfigBytesEncoding(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.BytesEncoding != BytesEncodingBase64 {
+		t.Errorf("expected default bytes encoding base64, got %v", config.Config.BytesEncoding)
+	}
+
+	cfg[ConfigBytesEncoding] = BytesEncodingHex
+	config, err = ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.BytesEncoding != BytesEncodingHex {
+		t.Errorf("expected bytes encoding hex, got %v", config.Config.BytesEncoding)
+	}
+
+	cfg[ConfigBytesEncoding] = "base32"
+	_, err = ParseSourceConfig(cfg)
+	if err == nil {
+		t.Errorf("parse source config, expected error for unknown bytes encoding")
+	}
+}
+
+func TestParseSourceConfigJSONAsString(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.JSONAsString {
+		t.Errorf("expected JSON to be parsed by default")
+	}
+
+	cfg[ConfigJSONAsString] = "true"
+	config, err = ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if !config.Config.JSONAsString {
+		t.Errorf("expected JSON as string to be enabled")
+	}
+
+	cfg[ConfigJSONAsString] = "sometimes"
+	_, err = ParseSourceConfig(cfg)
+	if err == nil {
+		t.Errorf("parse source config, expected error for invalid boolean")
+	}
+}
+
+func TestParseSourceConfigTimestampFormat(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.TimestampFormat != DefaultTimestampLayout {
+		t.Errorf("expected default timestamp layout, got %v", config.Config.TimestampFormat)
+	}
+
+	for _, valid := range []string{TimestampFormatRFC3339, TimestampFormatUnix, "2006-01-02"} {
+		cfg[ConfigTimestampFormat] = valid
+		config, err = ParseSourceConfig(cfg)
+		if err != nil {
+			t.Errorf("parse source config, got error %v", err)
+		}
+		if config.Config.TimestampFormat != valid {
+			t.Errorf("expected timestamp format %v, got %v", valid, config.Config.TimestampFormat)
+		}
+	}
+
+	cfg[ConfigTimestampFormat] = "iso"
+	_, err = ParseSourceConfig(cfg)
+	if err == nil {
+		t.Errorf("parse source config, expected error for layout without time elements")
+	}
+}
+
+func TestParseSourceConfigTimestampLocation(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.TimestampLocation != time.UTC {
+		t.Errorf("expected UTC timestamp location, got %v", config.Config.TimestampLocation)
+	}
+
+	cfg[ConfigTimestampLocation] = "America/New_York"
+	config, err = ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.TimestampLocation.String() != "America/New_York" {
+		t.Errorf("expected America/New_York timestamp location, got %v", config.Config.TimestampLocation)
+	}
+
+	cfg[ConfigTimestampLocation] = "Mars/Olympus_Mons"
+	_, err = ParseSourceConfig(cfg)
+	if err == nil {
+		t.Errorf("parse source config, expected error for invalid time zone")
+	}
+}
+
+func TestParseSourceConfigQuery(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+	cfg[ConfigQuery] = " SELECT * FROM `test.test.orders`; "
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.Query != "SELECT * FROM `test.test.orders`" {
+		t.Errorf("expected trimmed query, got %q", config.Config.Query)
+	}
+
+	cfg[ConfigFilter] = "region = 'us'"
+	_, err = ParseSourceConfig(cfg)
+	if err == nil {
+		t.Errorf("parse source config, expected error for query combined with filter")
+	}
+}
+
+func TestParseSourceConfigExcludeColumns(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "id"
+	cfg[ConfigIncrementalColName] = "updated_at,table1:seq"
+	cfg[ConfigExcludeColumns] = "ssn, user.email"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if !reflect.DeepEqual(config.Config.ExcludeColumns, []string{"ssn", "user.email"}) {
+		t.Errorf("expected excluded columns, got %v", config.Config.ExcludeColumns)
+	}
+
+	for _, required := range []string{"id", "updated_at", "seq"} {
+		cfg[ConfigExcludeColumns] = "ssn," + required
+		_, err = ParseSourceConfig(cfg)
+		if err == nil {
+			t.Errorf("parse source config, expected error when excluding %q", required)
+		}
+	}
+}
+
+func TestParseSourceConfigBatchSize(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.BatchSize != CounterLimit {
+		t.Errorf("expected default batch size, got %v", config.Config.BatchSize)
+	}
+
+	cfg[ConfigBatchSize] = "10000"
+	config, err = ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.BatchSize != 10000 {
+		t.Errorf("expected batch size 10000, got %v", config.Config.BatchSize)
+	}
+
+	for _, invalid := range []string{"0", "-5", "many"} {
+		cfg[ConfigBatchSize] = invalid
+		_, err = ParseSourceConfig(cfg)
+		if err == nil {
+			t.Errorf("parse source config, expected error for %q", invalid)
+		}
+	}
+}
+
+func TestParseSourceConfigIncrementOrder(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.IncrementOrder != IncrementOrderAsc {
+		t.Errorf("expected ascending order by default, got %v", config.Config.IncrementOrder)
+	}
+
+	cfg[ConfigIncrementOrder] = IncrementOrderDesc
+	config, err = ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.IncrementOrder != IncrementOrderDesc {
+		t.Errorf("expected descending order, got %v", config.Config.IncrementOrder)
+	}
+
+	cfg[ConfigIncrementOrder] = "newest"
+	_, err = ParseSourceConfig(cfg)
+	if err == nil {
+		t.Errorf("parse source config, expected error for invalid order")
+	}
+
+	// descending order can't start from or merge into the greatest value
+	for key, value := range map[string]string{ConfigMode: ModeCDC, ConfigCDCMode: CDCModeChangeHistory} {
+		invalid := map[string]string{ConfigIncrementOrder: IncrementOrderDesc, key: value}
+		for k, v := range cfg {
+			if k != ConfigIncrementOrder {
+				invalid[k] = v
+			}
+		}
+		_, err = ParseSourceConfig(invalid)
+		if err == nil {
+			t.Errorf("parse source config, expected error for descending order with %s %s", key, value)
+		}
+	}
+	cfg[ConfigIncrementOrder] = IncrementOrderDesc
+	cfg[ConfigReadMode] = ReadModeStorage
+	cfg[ConfigReadStreams] = "4"
+	_, err = ParseSourceConfig(cfg)
+	if err == nil {
+		t.Errorf("parse source config, expected error for descending order with read streams")
+	}
+}
+
+func TestParseSourceConfigStartPosition(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+	cfg[ConfigStartPosition] = " 2023-01-01T00:00:00Z "
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.StartPosition != "2023-01-01T00:00:00Z" {
+		t.Errorf("expected start position, got %q", config.Config.StartPosition)
+	}
+
+	cfg[ConfigEndPosition] = "2023-02-01T00:00:00Z"
+	config, err = ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.EndPosition != "2023-02-01T00:00:00Z" {
+		t.Errorf("expected end position, got %q", config.Config.EndPosition)
+	}
+
+	for _, position := range []string{ConfigStartPosition, ConfigEndPosition} {
+		for key, value := range map[string]string{
+			ConfigQuery:             "SELECT * FROM `test.test.table`",
+			ConfigCDCMode:           CDCModeChangeHistory,
+			ConfigPrimaryKeyColName: "id,name",
+		} {
+			invalid := map[string]string{key: value, position: "1"}
+			for k, v := range cfg {
+				if k != key && k != ConfigStartPosition && k != ConfigEndPosition {
+					invalid[k] = v
+				}
+			}
+			_, err = ParseSourceConfig(invalid)
+			if err == nil {
+				t.Errorf("parse source config, expected error for %s with %s %s", position, key, value)
+			}
+		}
+	}
+}
+
+func TestParseSourceConfigKeyColumns(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "id"
+	cfg[ConfigKeyColumns] = "customer_id, region"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if !reflect.DeepEqual(config.Config.KeyColumns, []string{"customer_id", "region"}) {
+		t.Errorf("expected key columns, got %v", config.Config.KeyColumns)
+	}
+
+	cfg[ConfigDetectDeletes] = "true"
+	if _, err = ParseSourceConfig(cfg); err == nil {
+		t.Errorf("parse source config, expected error for key columns with detect deletes")
+	}
+}
+
+func TestParseSourceConfigNullHandling(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.NullHandling != NullHandlingNull {
+		t.Errorf("expected null handling %q by default, got %q", NullHandlingNull, config.Config.NullHandling)
+	}
+
+	cfg[ConfigNullHandling] = NullHandlingOmit
+	config, err = ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.NullHandling != NullHandlingOmit {
+		t.Errorf("expected null handling %q, got %q", NullHandlingOmit, config.Config.NullHandling)
+	}
+
+	cfg[ConfigNullHandling] = "skip"
+	if _, err = ParseSourceConfig(cfg); err == nil {
+		t.Errorf("parse source config, expected error for null handling skip")
+	}
+}
+
+func TestParseSourceConfigOnConversionError(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.OnConversionError != OnConversionErrorFail {
+		t.Errorf("expected %q by default, got %q", OnConversionErrorFail, config.Config.OnConversionError)
+	}
+
+	for _, policy := range []string{OnConversionErrorSkip, OnConversionErrorDLQ} {
+		cfg[ConfigOnConversionError] = policy
+		config, err = ParseSourceConfig(cfg)
+		if err != nil {
+			t.Errorf("parse source config, got error %v", err)
+		}
+		if config.Config.OnConversionError != policy {
+			t.Errorf("expected %q, got %q", policy, config.Config.OnConversionError)
+		}
+	}
+
+	cfg[ConfigOnConversionError] = "retry"
+	if _, err = ParseSourceConfig(cfg); err == nil {
+		t.Errorf("parse source config, expected error for %s retry", ConfigOnConversionError)
+	}
+}
+
+func TestParseSourceConfigIncludePseudoColumns(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+	cfg[ConfigIncludePseudoColumns] = "true"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if !config.Config.IncludePseudoColumns {
+		t.Errorf("expected pseudo columns to be included")
+	}
+
+	cfg[ConfigIncludePseudoColumns] = "yes please"
+	if _, err = ParseSourceConfig(cfg); err == nil {
+		t.Errorf("parse source config, expected error for include pseudo columns %q", cfg[ConfigIncludePseudoColumns])
+	}
+
+	for key, value := range map[string]string{
+		ConfigQuery:   "SELECT * FROM `test.test.table`",
+		ConfigCDCMode: CDCModeChangeHistory,
+	} {
+		invalid := map[string]string{key: value}
+		for k, v := range cfg {
+			invalid[k] = v
+		}
+		invalid[ConfigIncludePseudoColumns] = "true"
+		if _, err = ParseSourceConfig(invalid); err == nil {
+			t.Errorf("parse source config, expected error for pseudo columns with %s %s", key, value)
+		}
+	}
+}
+
+func TestParseSourceConfigMaxRows(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+	cfg[ConfigMaxRows] = "1000"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.MaxRows != 1000 {
+		t.Errorf("expected max rows of 1000, got %v", config.Config.MaxRows)
+	}
+
+	for _, value := range []string{"0", "-1", "many"} {
+		cfg[ConfigMaxRows] = value
+		_, err = ParseSourceConfig(cfg)
+		if err == nil {
+			t.Errorf("parse source config, expected error for max rows %q", value)
+		}
+	}
+}
+
+func TestParseSourceConfigSamplePercent(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+	cfg[ConfigSamplePercent] = "0.5"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.SamplePercent != 0.5 {
+		t.Errorf("expected sample percent of 0.5, got %v", config.Config.SamplePercent)
+	}
+
+	for _, value := range []string{"0", "-5", "101", "half"} {
+		cfg[ConfigSamplePercent] = value
+		_, err = ParseSourceConfig(cfg)
+		if err == nil {
+			t.Errorf("parse source config, expected error for sample percent %q", value)
+		}
+	}
+
+	cfg[ConfigSamplePercent] = "10"
+	cfg[ConfigQuery] = "SELECT * FROM dataset.table1"
+	if _, err = ParseSourceConfig(cfg); err == nil {
+		t.Errorf("parse source config, expected error for sample percent with custom query")
+	}
+}
+
+func TestParseSourceConfigHeartbeatInterval(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.HeartbeatInterval != 0 {
+		t.Errorf("expected no heartbeats by default, got %v", config.Config.HeartbeatInterval)
+	}
+
+	cfg[ConfigHeartbeatInterval] = "5m"
+	config, err = ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.HeartbeatInterval != 5*time.Minute {
+		t.Errorf("expected heartbeat interval of 5m, got %v", config.Config.HeartbeatInterval)
+	}
+
+	for _, value := range []string{"0s", "-1m", "often"} {
+		cfg[ConfigHeartbeatInterval] = value
+		_, err = ParseSourceConfig(cfg)
+		if err == nil {
+			t.Errorf("parse source config, expected error for heartbeat interval %q", value)
+		}
+	}
+}
+
+func TestParseSourceConfigJobTimeout(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.JobTimeout != 0 {
+		t.Errorf("expected no job timeout by default, got %v", config.Config.JobTimeout)
+	}
+
+	cfg[ConfigJobTimeout] = "10m"
+	config, err = ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.JobTimeout != 10*time.Minute {
+		t.Errorf("expected job timeout of 10m, got %v", config.Config.JobTimeout)
+	}
+
+	for _, value := range []string{"0s", "-1m", "ten minutes"} {
+		cfg[ConfigJobTimeout] = value
+		_, err = ParseSourceConfig(cfg)
+		if err == nil {
+			t.Errorf("parse source config, expected error for job timeout %q", value)
+		}
+	}
+}
+
+func TestParseSourceConfigQueryPriority(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.QueryPriority != QueryPriorityInteractive {
+		t.Errorf("expected interactive priority by default, got %v", config.Config.QueryPriority)
+	}
+
+	cfg[ConfigQueryPriority] = QueryPriorityBatch
+	config, err = ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.QueryPriority != QueryPriorityBatch {
+		t.Errorf("expected batch priority, got %v", config.Config.QueryPriority)
+	}
+
+	cfg[ConfigQueryPriority] = "urgent"
+	_, err = ParseSourceConfig(cfg)
+	if err == nil {
+		t.Errorf("parse source config, expected error for invalid priority")
+	}
+}
+
+func TestParseSourceConfigMaterializeSnapshot(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.MaterializeSnapshot {
+		t.Errorf("expected snapshots not to be materialized by default")
+	}
+
+	cfg[ConfigMaterializeSnapshot] = "true"
+	config, err = ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if !config.Config.MaterializeSnapshot {
+		t.Errorf("expected snapshots to be materialized")
+	}
+
+	cfg[ConfigReadMode] = ReadModeStorage
+	cfg[ConfigReadStreams] = "4"
+	_, err = ParseSourceConfig(cfg)
+	if err == nil {
+		t.Errorf("parse source config, expected error for materialized snapshot with read streams")
+	}
+
+	delete(cfg, ConfigReadStreams)
+	cfg[ConfigMaterializeSnapshot] = "yes please"
+	_, err = ParseSourceConfig(cfg)
+	if err == nil {
+		t.Errorf("parse source config, expected error for invalid boolean")
+	}
+}
+
+func TestParseSourceConfigUnknownKeys(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+	cfg["datsetID"] = "test"
+	cfg["pollingtime"] = "5s"
+
+	_, err := ParseSourceConfig(cfg)
+	if err == nil {
+		t.Fatalf("parse source config, expected error for unknown keys")
+	}
+	if !errors.Is(err, ErrUnknownKeys) {
+		t.Errorf("expected ErrUnknownKeys, got %v", err)
+	}
+	want := "unknown configuration keys datsetID, pollingtime, check their spelling"
+	if err.Error() != want {
+		t.Errorf("expected error %q, got %q", want, err.Error())
+	}
+
+	delete(cfg, "datsetID")
+	delete(cfg, "pollingtime")
+	cfg[ConfigDatasetID] = "test"
+	// keys of the SDK middleware are left to the SDK
+	cfg["sdk.batch.size"] = "10"
+	if _, err = ParseSourceConfig(cfg); err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+
+	// source parameters aren't known to the destination
+	dst := map[string]string{ConfigProjectID: "test", ConfigDatasetID: "test", ConfigLocation: "test", ConfigTableID: "table1"}
+	dst[ConfigPollingTime] = "5s"
+	if _, err = ParseDestinationConfig(dst); err == nil {
+		t.Errorf("parse destination config, expected error for source parameter")
+	}
+}
+
+func TestParseConfigSentinelErrors(t *testing.T) {
+	valid := func() map[string]string {
+		return map[string]string{ConfigProjectID: "test", ConfigDatasetID: "test", ConfigLocation: "test",
+			ConfigPrimaryKeyColName: "primaryKey"}
+	}
+
+	tests := []struct {
+		name   string
+		change func(cfg map[string]string)
+		want   error
+	}{
+		{name: "empty config", change: func(cfg map[string]string) {
+			for key := range cfg {
+				delete(cfg, key)
+			}
+		}, want: ErrEmptyConfig},
+		{name: "blank project", change: func(cfg map[string]string) { cfg[ConfigProjectID] = "" }, want: ErrMissingParameter},
+		{name: "blank dataset", change: func(cfg map[string]string) { delete(cfg, ConfigDatasetID) }, want: ErrMissingParameter},
+		{name: "blank primary key", change: func(cfg map[string]string) { cfg[ConfigPrimaryKeyColName] = "" }, want: ErrMissingParameter},
+		{name: "invalid polling time", change: func(cfg map[string]string) { cfg[ConfigPollingTime] = "soon" }, want: ErrInvalidPollingTime},
+		{name: "fast polling time", change: func(cfg map[string]string) { cfg[ConfigPollingTime] = "1ms" }, want: ErrInvalidPollingTime},
+		{name: "max polling time below polling time", change: func(cfg map[string]string) {
+			cfg[ConfigPollingTime] = "10m"
+			cfg[ConfigMaxPollingTime] = "5m"
+		}, want: ErrInvalidPollingTime},
+		{name: "unknown key", change: func(cfg map[string]string) { cfg["tableIDs"] = "table1" }, want: ErrUnknownKeys},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.change(cfg)
+			_, err := ParseSourceConfig(cfg)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	dst := map[string]string{ConfigProjectID: "test", ConfigDatasetID: "test", ConfigLocation: "test"}
+	if _, err := ParseDestinationConfig(dst); !errors.Is(err, ErrNoTableID) {
+		t.Errorf("expected ErrNoTableID, got %v", err)
+	}
+}
+
+func TestParseSourceConfigBufferSize(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.BufferSize != BufferSize {
+		t.Errorf("expected default buffer size, got %v", config.Config.BufferSize)
+	}
+
+	cfg[ConfigBufferSize] = "1000"
+	config, err = ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.BufferSize != 1000 {
+		t.Errorf("expected buffer size 1000, got %v", config.Config.BufferSize)
+	}
+
+	for _, invalid := range []string{"0", "-5", "many"} {
+		cfg[ConfigBufferSize] = invalid
+		_, err = ParseSourceConfig(cfg)
+		if err == nil {
+			t.Errorf("parse source config, expected error for %q", invalid)
+		}
+	}
+}
+
+func TestParseSourceConfigKeyCacheSize(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.KeyCacheSize != KeyCacheSize {
+		t.Errorf("expected default key cache size, got %v", config.Config.KeyCacheSize)
+	}
+
+	cfg[ConfigKeyCacheSize] = "0"
+	config, err = ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.KeyCacheSize != 0 {
+		t.Errorf("expected disabled key cache, got %v", config.Config.KeyCacheSize)
+	}
+
+	for _, invalid := range []string{"-1", "many"} {
+		cfg[ConfigKeyCacheSize] = invalid
+		_, err = ParseSourceConfig(cfg)
+		if err == nil {
+			t.Errorf("parse source config, expected error for %q", invalid)
+		}
+	}
+}
+
+func TestParseSourceConfigMode(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.Mode != ModeSnapshot {
+		t.Errorf("expected default mode snapshot, got %v", config.Config.Mode)
+	}
+
+	cfg[ConfigMode] = ModeCDC
+	config, err = ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.Mode != ModeCDC {
+		t.Errorf("expected mode cdc, got %v", config.Config.Mode)
+	}
+
+	cfg[ConfigMode] = "backfill"
+	_, err = ParseSourceConfig(cfg)
+	if err == nil {
+		t.Errorf("parse source config, expected error for unknown mode")
+	}
+}
+
+func TestParseSourceConfigCDCMode(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.CDCMode != CDCModePolling {
+		t.Errorf("expected default cdc mode polling, got %v", config.Config.CDCMode)
+	}
+
+	cfg[ConfigCDCMode] = CDCModeChangeHistory
+	config, err = ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.CDCMode != CDCModeChangeHistory {
+		t.Errorf("expected cdc mode changeHistory, got %v", config.Config.CDCMode)
+	}
+
+	cfg[ConfigQuery] = "SELECT * FROM orders"
+	_, err = ParseSourceConfig(cfg)
+	if err == nil {
+		t.Errorf("parse source config, expected error for change history of a custom query")
+	}
+
+	delete(cfg, ConfigQuery)
+	cfg[ConfigCDCMode] = "triggers"
+	_, err = ParseSourceConfig(cfg)
+	if err == nil {
+		t.Errorf("parse source config, expected error for unknown cdc mode")
+	}
+}
+
+func TestParseSourceConfigDetectDeletes(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.DetectDeletes || config.Config.DetectDeletesInterval != DetectDeletesInterval {
+		t.Errorf("expected deletes not to be detected by default, got %v every %v", config.Config.DetectDeletes, config.Config.DetectDeletesInterval)
+	}
+
+	cfg[ConfigDetectDeletes] = "true"
+	cfg[ConfigDetectDeletesInterval] = "15m"
+	config, err = ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if !config.Config.DetectDeletes || config.Config.DetectDeletesInterval != 15*time.Minute {
+		t.Errorf("expected deletes detected every 15m, got %v every %v", config.Config.DetectDeletes, config.Config.DetectDeletesInterval)
+	}
+
+	for key, invalid := range map[string]string{ConfigDetectDeletes: "sometimes", ConfigDetectDeletesInterval: "0s"} {
+		cfg := map[string]string{ConfigProjectID: "test", ConfigDatasetID: "test", ConfigLocation: "test", ConfigPrimaryKeyColName: "primaryKey"}
+		cfg[key] = invalid
+		_, err = ParseSourceConfig(cfg)
+		if err == nil {
+			t.Errorf("parse source config, expected error for %v %q", key, invalid)
+		}
+	}
+}
+
+func TestParseSourceConfigSkipTableValidation(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil || config.Config.SkipTableValidation {
+		t.Errorf("expected tables validated by default, got %v and error %v", config.Config.SkipTableValidation, err)
+	}
+
+	cfg[ConfigSkipTableValidation] = "true"
+	config, err = ParseSourceConfig(cfg)
+	if err != nil || !config.Config.SkipTableValidation {
+		t.Errorf("expected table validation skipped, got %v and error %v", config.Config.SkipTableValidation, err)
+	}
+
+	cfg[ConfigSkipTableValidation] = "maybe"
+	_, err = ParseSourceConfig(cfg)
+	if err == nil {
+		t.Errorf("parse source config, expected error for invalid skip table validation")
+	}
+}
+
+func TestParseSourceConfigLogLevel(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil || config.Config.LogLevel != "" {
+		t.Errorf("expected no log level by default, got %q and error %v", config.Config.LogLevel, err)
+	}
+
+	for _, level := range []string{"trace", "debug", "info", "warn", "error"} {
+		cfg[ConfigLogLevel] = level
+		config, err = ParseSourceConfig(cfg)
+		if err != nil || config.Config.LogLevel != level {
+			t.Errorf("expected log level %q, got %q and error %v", level, config.Config.LogLevel, err)
+		}
+	}
+
+	cfg[ConfigLogLevel] = "verbose"
+	_, err = ParseSourceConfig(cfg)
+	if err == nil {
+		t.Errorf("parse source config, expected error for invalid log level")
+	}
+	_, err = ParseDestinationConfig(map[string]string{ConfigProjectID: "test", ConfigDatasetID: "test", ConfigLocation: "test", ConfigTableID: "table1", ConfigLogLevel: "verbose"})
+	if err == nil {
+		t.Errorf("parse destination config, expected error for invalid log level")
+	}
+}
+
+func TestParseSourceConfigEndpoint(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil || config.Config.Endpoint != "" || config.Config.Insecure {
+		t.Errorf("expected default endpoint with credentials, got %v and error %v", config.Config, err)
+	}
+
+	cfg[ConfigEndpoint] = "http://localhost:9050"
+	cfg[ConfigInsecure] = "true"
+	config, err = ParseSourceConfig(cfg)
+	if err != nil || config.Config.Endpoint != "http://localhost:9050" || !config.Config.Insecure {
+		t.Errorf("expected insecure endpoint, got %v and error %v", config.Config, err)
+	}
+
+	cfg[ConfigReadMode] = ReadModeStorage
+	if _, err = ParseSourceConfig(cfg); err == nil {
+		t.Errorf("expected error for storage read mode with custom endpoint")
+	}
+	delete(cfg, ConfigReadMode)
+
+	cfg[ConfigInsecure] = "maybe"
+	if _, err = ParseSourceConfig(cfg); err == nil {
+		t.Errorf("expected error for invalid insecure")
+	}
+
+	cfg[ConfigInsecure] = "true"
+	cfg[ConfigEndpoint] = "localhost:9050"
+	if _, err = ParseSourceConfig(cfg); err == nil {
+		t.Errorf("expected error for endpoint without scheme")
+	}
+
+	delete(cfg, ConfigEndpoint)
+	if _, err = ParseSourceConfig(cfg); err == nil {
+		t.Errorf("expected error for insecure without endpoint")
+	}
+
+	destination, err := ParseDestinationConfig(map[string]string{ConfigProjectID: "test", ConfigDatasetID: "test", ConfigLocation: "test",
+		ConfigTableID: "table1", ConfigEndpoint: "http://localhost:9050", ConfigInsecure: "true"})
+	if err != nil || destination.Config.Endpoint != "http://localhost:9050" || !destination.Config.Insecure {
+		t.Errorf("expected insecure endpoint for destination, got %v and error %v", destination.Config, err)
+	}
+}
+
+func TestParseSourceConfigQueryLabels(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil || config.Config.QueryLabels != nil {
+		t.Errorf("expected no labels by default, got %v and error %v", config.Config.QueryLabels, err)
+	}
+
+	cfg[ConfigQueryLabels] = "team=data-eng, pipeline=orders_sync,empty="
+	config, err = ParseSourceConfig(cfg)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := map[string]string{"team": "data-eng", "pipeline": "orders_sync", "empty": ""}
+	if !reflect.DeepEqual(config.Config.QueryLabels, want) {
+		t.Errorf("expected labels %v, got %v", want, config.Config.QueryLabels)
+	}
+
+	for _, labels := range []string{"team", "Team=data", "1team=data", "team=Data", "team=a,team=b", "team=" + strings.Repeat("a", 64)} {
+		cfg[ConfigQueryLabels] = labels
+		if _, err = ParseSourceConfig(cfg); err == nil {
+			t.Errorf("expected error for labels %q", labels)
+		}
+	}
+}
+
+func TestParseSourceConfigMaxBytesBilled(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil || config.Config.MaxBytesBilled != 0 {
+		t.Errorf("expected no limit by default, got %v and error %v", config.Config.MaxBytesBilled, err)
+	}
+
+	cfg[ConfigMaxBytesBilled] = "10737418240"
+	config, err = ParseSourceConfig(cfg)
+	if err != nil || config.Config.MaxBytesBilled != 10737418240 {
+		t.Errorf("expected limit of 10 GiB, got %v and error %v", config.Config.MaxBytesBilled, err)
+	}
+
+	for _, value := range []string{"0", "-1", "10GB"} {
+		cfg[ConfigMaxBytesBilled] = value
+		if _, err = ParseSourceConfig(cfg); err == nil {
+			t.Errorf("expected error for max bytes billed %q", value)
+		}
+	}
+}
+
+func TestParseSourceConfigDryRun(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil || config.Config.DryRun {
+		t.Errorf("expected dry run to be off by default, got %v and error %v", config.Config.DryRun, err)
+	}
+
+	cfg[ConfigDryRun] = "true"
+	config, err = ParseSourceConfig(cfg)
+	if err != nil || !config.Config.DryRun {
+		t.Errorf("expected dry run, got %v and error %v", config.Config.DryRun, err)
+	}
+
+	cfg[ConfigDryRun] = "maybe"
+	if _, err = ParseSourceConfig(cfg); err == nil {
+		t.Errorf("expected error for invalid dry run")
+	}
+}
+
+func TestParseSourceConfigUseQueryCache(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil || !config.Config.UseQueryCache {
+		t.Errorf("expected query cache to be used by default, got %v and error %v", config.Config.UseQueryCache, err)
+	}
+
+	cfg[ConfigUseQueryCache] = "false"
+	config, err = ParseSourceConfig(cfg)
+	if err != nil || config.Config.UseQueryCache {
+		t.Errorf("expected query cache to be disabled, got %v and error %v", config.Config.UseQueryCache, err)
+	}
+
+	cfg[ConfigUseQueryCache] = "sometimes"
+	if _, err = ParseSourceConfig(cfg); err == nil {
+		t.Errorf("expected error for invalid use query cache")
+	}
+}
+
+func TestParseSourceConfigPartitions(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil || config.Config.Partitions != nil || config.Config.PartitionField != DefaultPartitionField {
+		t.Errorf("expected all partitions of %s by default, got %v and error %v", DefaultPartitionField, config.Config, err)
+	}
+
+	cfg[ConfigPartitions] = "2023, 202312,20231231,2023123123"
+	cfg[ConfigPartitionField] = "event_time"
+	config, err = ParseSourceConfig(cfg)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := []Partition{
+		{ID: "2023", Start: "2023-01-01", End: "2024-01-01"},
+		{ID: "202312", Start: "2023-12-01", End: "2024-01-01"},
+		{ID: "20231231", Start: "2023-12-31", End: "2024-01-01"},
+		{ID: "2023123123", Start: "2023-12-31 23:00:00", End: "2024-01-01 00:00:00"},
+	}
+	if !reflect.DeepEqual(config.Config.Partitions, want) || config.Config.PartitionField != "event_time" {
+		t.Errorf("expected partitions %v of event_time, got %v of %v", want, config.Config.Partitions, config.Config.PartitionField)
+	}
+
+	for _, partitions := range []string{"2023-12-31", "20231332", "yesterday"} {
+		cfg[ConfigPartitions] = partitions
+		if _, err = ParseSourceConfig(cfg); err == nil {
+			t.Errorf("expected error for partitions %q", partitions)
+		}
+	}
+
+	cfg[ConfigPartitions] = "20231231"
+	cfg[ConfigQuery] = "SELECT * FROM table"
+	if _, err = ParseSourceConfig(cfg); err == nil {
+		t.Errorf("expected error for partitions with custom query")
+	}
+}
+
+func TestParseSourceConfigPartitionLookback(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil || config.Config.PartitionLookback != 0 {
+		t.Errorf("expected no lookback by default, got %v and error %v", config.Config.PartitionLookback, err)
+	}
+
+	cfg[ConfigPartitionLookback] = "168h"
+	config, err = ParseSourceConfig(cfg)
+	if err != nil || config.Config.PartitionLookback != 168*time.Hour {
+		t.Errorf("expected lookback of a week, got %v and error %v", config.Config.PartitionLookback, err)
+	}
+
+	for _, lookback := range []string{"0s", "-1h", "7d"} {
+		cfg[ConfigPartitionLookback] = lookback
+		if _, err = ParseSourceConfig(cfg); err == nil {
+			t.Errorf("expected error for partition lookback %q", lookback)
+		}
+	}
+}
+
+func TestParseSourceConfigRetries(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.MaxRetries != MaxRetries || config.Config.RetryDelay != RetryDelay {
+		t.Errorf("expected %v retries after %v by default, got %v after %v", MaxRetries, RetryDelay, config.Config.MaxRetries, config.Config.RetryDelay)
+	}
+
+	cfg[ConfigMaxRetries] = "0"
+	cfg[ConfigRetryDelay] = "250ms"
+	config, err = ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.MaxRetries != 0 || config.Config.RetryDelay != 250*time.Millisecond {
+		t.Errorf("expected no retries after 250ms, got %v after %v", config.Config.MaxRetries, config.Config.RetryDelay)
+	}
+
+	for key, invalid := range map[string]string{ConfigMaxRetries: "-1", ConfigRetryDelay: "soon"} {
+		cfg := map[string]string{ConfigProjectID: "test", ConfigDatasetID: "test", ConfigLocation: "test", ConfigPrimaryKeyColName: "primaryKey"}
+		cfg[key] = invalid
+		_, err = ParseSourceConfig(cfg)
+		if err == nil {
+			t.Errorf("parse source config, expected error for %v %q", key, invalid)
+		}
+	}
+}
+
+func TestParseSourceConfigPollingTime(t *testing.T) {
+	for _, invalid := range []string{"0s", "-5s", "1ms", "often"} {
+		cfg := map[string]string{ConfigProjectID: "test", ConfigDatasetID: "test", ConfigLocation: "test", ConfigPrimaryKeyColName: "primaryKey"}
+		cfg[ConfigPollingTime] = invalid
+		_, err := ParseSourceConfig(cfg)
+		if err == nil {
+			t.Errorf("parse source config, expected error for polling time %q", invalid)
+		}
+	}
+
+	cfg := map[string]string{ConfigProjectID: "test", ConfigDatasetID: "test", ConfigLocation: "test", ConfigPrimaryKeyColName: "primaryKey"}
+	cfg[ConfigPollingTime] = "1ms"
+	cfg[ConfigAllowFastPolling] = "true"
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, expected fast polling to be allowed, got error %v", err)
+	}
+	if config.Config.PollingTime != "1ms" {
+		t.Errorf("expected polling time 1ms, got %v", config.Config.PollingTime)
+	}
+
+	for _, invalid := range []string{"0s", "-5s"} {
+		cfg[ConfigPollingTime] = invalid
+		_, err = ParseSourceConfig(cfg)
+		if err == nil {
+			t.Errorf("parse source config, expected error for polling time %q with fast polling", invalid)
+		}
+	}
+}
+
+func TestParseSourceConfigMaxPollingTime(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+	cfg[ConfigPollingTime] = "1m"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.MaxPollingTime != 0 {
+		t.Errorf("expected no backoff by default, got %v", config.Config.MaxPollingTime)
+	}
+
+	cfg[ConfigMaxPollingTime] = "1h"
+	config, err = ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.MaxPollingTime != time.Hour {
+		t.Errorf("expected max polling time 1h, got %v", config.Config.MaxPollingTime)
+	}
+
+	for _, invalid := range []string{"30s", "0s", "soon"} {
+		cfg[ConfigMaxPollingTime] = invalid
+		_, err = ParseSourceConfig(cfg)
+		if err == nil {
+			t.Errorf("parse source config, expected error for %q", invalid)
+		}
+	}
+}
+
+func TestParseSourceConfigReadMode(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.ReadMode != ReadModeQuery {
+		t.Errorf("expected default read mode query, got %v", config.Config.ReadMode)
+	}
+
+	cfg[ConfigReadMode] = ReadModeStorage
+	config, err = ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.ReadMode != ReadModeStorage {
+		t.Errorf("expected read mode storage, got %v", config.Config.ReadMode)
+	}
+
+	cfg[ConfigReadMode] = "export"
+	_, err = ParseSourceConfig(cfg)
+	if err == nil {
+		t.Errorf("parse source config, expected error for unknown read mode")
+	}
+}
+
+func TestParseSourceConfigReadStreams(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "primaryKey"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.ReadStreams != 1 {
+		t.Errorf("expected a single read stream by default, got %v", config.Config.ReadStreams)
+	}
+
+	cfg[ConfigReadStreams] = "4"
+	_, err = ParseSourceConfig(cfg)
+	if err == nil {
+		t.Errorf("parse source config, expected error for read streams without storage read mode")
+	}
+
+	cfg[ConfigReadMode] = ReadModeStorage
+	config, err = ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if config.Config.ReadStreams != 4 {
+		t.Errorf("expected 4 read streams, got %v", config.Config.ReadStreams)
+	}
+
+	for _, invalid := range []string{"0", "-2", "all"} {
+		cfg[ConfigReadStreams] = invalid
+		_, err = ParseSourceConfig(cfg)
+		if err == nil {
+			t.Errorf("parse source config, expected error for %q", invalid)
+		}
+	}
+}
+
+func TestParseSourceConfigCompositePrimaryKey(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigPrimaryKeyColName] = "order_id, line_no"
+
+	config, err := ParseSourceConfig(cfg)
+	if err != nil {
+		t.Errorf("parse source config, got error %v", err)
+	}
+	if !reflect.DeepEqual(config.Config.PrimaryKeyColNames, []string{"order_id", "line_no"}) {
+		t.Errorf("expected composite primary key, got %v", config.Config.PrimaryKeyColNames)
+	}
+
+	cfg[ConfigExcludeColumns] = "line_no"
+	_, err = ParseSourceConfig(cfg)
+	if err == nil {
+		t.Errorf("parse source config, expected error when excluding a primary key column")
+	}
+
+	cfg[ConfigExcludeColumns] = ""
+	cfg[ConfigPrimaryKeyColName] = " , "
+	_, err = ParseSourceConfig(cfg)
+	if err == nil {
+		t.Errorf("parse source config, expected error for blank primary key")
+	}
+}
+
+func TestParseDestinationConfig(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigTableID] = "table1"
+	cfg[ConfigScopes] = "scope1,scope2"
+
+	config, err := ParseDestinationConfig(cfg)
+	if err != nil {
+		t.Errorf("parse destination config, got error %v", err)
+	}
+	if config.TableID != "table1" || config.Config.ProjectID != "test" {
+		t.Errorf("expected table1 of project test, got %v", config)
+	}
+	if !reflect.DeepEqual(config.Config.Scopes, []string{"scope1", "scope2"}) {
+		t.Errorf("expected scopes, got %v", config.Config.Scopes)
+	}
+
+	for _, invalid := range []string{"", "table1,table2"} {
+		cfg[ConfigTableID] = invalid
+		_, err = ParseDestinationConfig(cfg)
+		if err == nil {
+			t.Errorf("parse destination config, expected error for table ID %q", invalid)
+		}
+	}
+
+	cfg[ConfigTableID] = "table1"
+	cfg[ConfigServiceAccountJSON] = "not json"
+	_, err = ParseDestinationConfig(cfg)
+	if err == nil {
+		t.Errorf("parse destination config, expected error for invalid service account JSON")
+	}
+}
+
+func TestParseDestinationConfigWriteMode(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigTableID] = "table1"
+
+	config, err := ParseDestinationConfig(cfg)
+	if err != nil {
+		t.Errorf("parse destination config, got error %v", err)
+	}
+	if config.WriteMode != WriteModeInsert {
+		t.Errorf("expected default write mode %v, got %v", WriteModeInsert, config.WriteMode)
+	}
+
+	cfg[ConfigWriteMode] = WriteModeUpsert
+	_, err = ParseDestinationConfig(cfg)
+	if err == nil {
+		t.Errorf("parse destination config, expected error for upsert without primary key")
+	}
+
+	cfg[ConfigPrimaryKeyColName] = "id,line"
+	config, err = ParseDestinationConfig(cfg)
+	if err != nil {
+		t.Errorf("parse destination config, got error %v", err)
+	}
+	if config.WriteMode != WriteModeUpsert || !reflect.DeepEqual(config.Config.PrimaryKeyColNames, []string{"id", "line"}) {
+		t.Errorf("expected upsert by id and line, got %v", config)
+	}
+
+	cfg[ConfigWriteMode] = "replace"
+	_, err = ParseDestinationConfig(cfg)
+	if err == nil {
+		t.Errorf("parse destination config, expected error for invalid write mode")
+	}
+}
+
+func TestParseDestinationConfigAutoCreate(t *testing.T) {
+	cfg := map[string]string{}
+	cfg[ConfigProjectID] = "test"
+	cfg[ConfigDatasetID] = "test"
+	cfg[ConfigLocation] = "test"
+	cfg[ConfigTableID] = "table1"
+
+	config, err := ParseDestinationConfig(cfg)
+	if err != nil || config.AutoCreate {
+		t.Errorf("expected auto create disabled by default, got %v and error %v", config.AutoCreate, err)
+	}
+
+	cfg[ConfigAutoCreate] = "true"
+	config, err = ParseDestinationConfig(cfg)
+	if err != nil || !config.AutoCreate {
+		t.Errorf("expected auto create enabled, got %v and error %v", config.AutoCreate, err)
+	}
+
+	cfg[ConfigAutoCreate] = "yes"
+	_, err = ParseDestinationConfig(cfg)
+	if err == nil {
+		t.Errorf("parse destination config, expected error for invalid auto create")
+	}
+}
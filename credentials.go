@@ -0,0 +1,104 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googlebigquery
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"google.golang.org/api/impersonate"
+	"google.golang.org/api/option"
+)
+
+// ClientOptions returns the options used to create the BigQuery client. A custom endpoint is used
+// instead of the BigQuery API when configured, without any credentials when insecure is set.
+func ClientOptions(ctx context.Context, config Config) ([]option.ClientOption, error) {
+	if config.Insecure {
+		sdk.Logger(ctx).Warn().Str("endpoint", config.Endpoint).Msg("sending requests without credentials")
+		return []option.ClientOption{option.WithEndpoint(config.Endpoint), option.WithoutAuthentication()}, nil
+	}
+
+	opts, err := credentialOptions(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	if len(config.Endpoint) > 0 {
+		opts = append(opts, option.WithEndpoint(config.Endpoint))
+	}
+	return opts, nil
+}
+
+// credentialOptions returns the options authenticating the client. Credentials are resolved in
+// the order: inline JSON, base64 encoded JSON, serviceAccount. serviceAccount holds the key as JSON
+// like in earlier versions, or the path of a key file. When none is provided no credentials option
+// is passed, so the client falls back to Application Default Credentials. If impersonation is configured
+// the resolved credentials are only used to fetch tokens for the target service account.
+func credentialOptions(ctx context.Context, config Config) ([]option.ClientOption, error) {
+	var opts []option.ClientOption
+
+	credentialsSet := 0
+	for _, credentials := range []string{config.ServiceAccountJSON, config.ServiceAccountBase64, config.ServiceAccount} {
+		if len(credentials) > 0 {
+			credentialsSet++
+		}
+	}
+	if credentialsSet > 1 {
+		sdk.Logger(ctx).Warn().Msg("multiple service account credentials provided. Using the first one set out of serviceAccountJSON, serviceAccountBase64, serviceAccount")
+	}
+
+	switch {
+	case len(config.ServiceAccountJSON) > 0:
+		opts = append(opts, option.WithCredentialsJSON([]byte(config.ServiceAccountJSON)))
+	case len(config.ServiceAccountBase64) > 0:
+		credentials, err := base64.StdEncoding.DecodeString(config.ServiceAccountBase64)
+		if err != nil {
+			return nil, fmt.Errorf("error while decoding base64 service account: %w", err)
+		}
+		opts = append(opts, option.WithCredentialsJSON(credentials))
+	case inlineCredentials(config.ServiceAccount):
+		opts = append(opts, option.WithCredentialsJSON([]byte(config.ServiceAccount)))
+	case len(config.ServiceAccount) > 0:
+		opts = append(opts, option.WithCredentialsFile(config.ServiceAccount))
+	}
+
+	// the read-only BigQuery scope can't insert the query jobs the source reads the tables with, nor
+	// the rows written by the destination, so the full BigQuery scope is requested by default
+	scopes := config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{bigquery.Scope}
+	}
+
+	if len(config.ImpersonateServiceAccount) > 0 {
+		ts, err := impersonate.CredentialsTokenSource(ctx, impersonate.CredentialsConfig{
+			TargetPrincipal: config.ImpersonateServiceAccount,
+			Scopes:          scopes,
+			Delegates:       config.ImpersonateDelegates,
+		}, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("error while creating impersonated credentials: %w", err)
+		}
+		return []option.ClientOption{option.WithTokenSource(ts)}, nil
+	}
+	return append(opts, option.WithScopes(scopes...)), nil
+}
+
+// inlineCredentials reports if the serviceAccount value is the key as JSON instead of the path of a key file
+func inlineCredentials(serviceAccount string) bool {
+	return strings.HasPrefix(strings.TrimSpace(serviceAccount), "{")
+}
@@ -0,0 +1,267 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googledestination
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	googlebigquery "github.com/neha-Gupta1/conduit-connector-bigquery"
+)
+
+const (
+	// DefaultBatchSize is the default number of records buffered before they are inserted
+	DefaultBatchSize = 500
+	// DefaultBatchDelay is the default time records are buffered before an incomplete batch is inserted
+	DefaultBatchDelay = time.Second
+
+	// maxInsertRows is the number of rows sent by a single insert request. BigQuery recommends 500
+	// rows per request, bigger batches are split.
+	maxInsertRows = 500
+)
+
+// Destination writes records into a BigQuery table
+type Destination struct {
+	sdk.UnimplementedDestination
+	destinationConfig googlebigquery.DestinationConfig
+	client            *bigquery.Client
+	// interface to insert rows into BigQuery. In testing this will be used to mock the inserter
+	inserter inserter
+	// interface to run the MERGE queries of upserts. In testing this will be used to mock the client
+	querier querier
+	// interface to read and create the table. In testing this will be used to mock the client
+	table tableClient
+	// missing is set while the table doesn't exist and will be created from the first record
+	missing bool
+	// schema is the schema the table was created with, records are checked against it
+	schema bigquery.Schema
+}
+
+// inserter inserts rows into a table
+type inserter interface {
+	Put(ctx context.Context, src interface{}) error
+}
+
+// NewDestination returns the destination. Records are buffered by the SDK batch middleware, which
+// calls Write with up to `sdk.batch.size` records or the records received within `sdk.batch.delay`,
+// flushes the buffer on teardown and acknowledges the records once Write returns.
+func NewDestination() sdk.Destination {
+	return sdk.DestinationWithMiddleware(
+		&Destination{},
+		sdk.DestinationWithRateLimit{},
+		sdk.DestinationWithRecordFormat{},
+		sdk.DestinationWithBatch{
+			DefaultBatchSize:  DefaultBatchSize,
+			DefaultBatchDelay: DefaultBatchDelay,
+		},
+	)
+}
+
+// Parameters returns a map of named sdk.Parameters that describe how to configure the Destination.
+func (d *Destination) Parameters() map[string]sdk.Parameter {
+	return googlebigquery.DestinationParameters()
+}
+
+func (d *Destination) Configure(ctx context.Context, cfg map[string]string) error {
+	sdk.Logger(ctx).Trace().Msg("Configuring a Destination Connector.")
+	destinationConfig, err := googlebigquery.ParseDestinationConfig(cfg)
+	if err != nil {
+		sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("invalid config provided")
+		return err
+	}
+	d.destinationConfig = destinationConfig
+	return nil
+}
+
+func (d *Destination) Open(ctx context.Context) error {
+	ctx = googlebigquery.WithLogLevel(ctx, d.destinationConfig.Config.LogLevel)
+	config := d.destinationConfig.Config
+	opts, err := googlebigquery.ClientOptions(ctx, config)
+	if err != nil {
+		sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("invalid credentials provided")
+		return err
+	}
+
+	client, err := bigquery.NewClient(ctx, config.ProjectID, opts...)
+	if err != nil {
+		sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("error found while creating connection. ")
+		return fmt.Errorf("error while creating bigquery client: %w", err)
+	}
+	d.client = client
+	d.inserter = client.Dataset(config.DatasetID).Table(d.destinationConfig.TableID).Inserter()
+	d.querier = clientQuerier{client: client, location: config.Location}
+	d.table = client.Dataset(config.DatasetID).Table(d.destinationConfig.TableID)
+
+	if d.destinationConfig.AutoCreate {
+		_, err := d.table.Metadata(ctx)
+		if hasStatus(err, http.StatusNotFound) {
+			sdk.Logger(ctx).Info().Str("tableID", d.destinationConfig.TableID).Msg("table doesn't exist, creating it from the first record")
+			d.missing = true
+		} else if err != nil {
+			sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("error while reading table metadata")
+			return fmt.Errorf("error while reading metadata of table %s: %w", d.destinationConfig.TableID, err)
+		}
+	}
+	return nil
+}
+
+// Write inserts the payloads of the records into the table with as few insert requests as possible.
+// Deletes can't be appended to the table and are skipped. In upsert mode the records are merged by
+// primary key instead. A missing table is created from the first record when auto create is enabled.
+// Returns the number of records written before an error occurred.
+func (d *Destination) Write(ctx context.Context, records []sdk.Record) (int, error) {
+	ctx = googlebigquery.WithLogLevel(ctx, d.destinationConfig.Config.LogLevel)
+	if d.missing {
+		if err := d.createTable(ctx, records); err != nil {
+			return 0, err
+		}
+	}
+	if d.schema != nil {
+		for i, record := range records {
+			if record.Operation == sdk.OperationDelete || heartbeat(record) {
+				continue
+			}
+			row, err := payloadRow(record)
+			if err != nil {
+				// invalid payloads are reported when written
+				break
+			}
+			if err := fitSchema(d.schema, row); err != nil {
+				if n, err := d.write(ctx, records[:i]); err != nil {
+					return n, err
+				}
+				return i, fmt.Errorf("record at position %s doesn't fit the schema of the created table: %w", record.Position, err)
+			}
+		}
+	}
+	return d.write(ctx, records)
+}
+
+// write writes the records to the table
+func (d *Destination) write(ctx context.Context, records []sdk.Record) (int, error) {
+	if d.destinationConfig.WriteMode == googlebigquery.WriteModeUpsert {
+		return d.upsert(ctx, records)
+	}
+
+	rows := make([]rowSaver, 0, len(records))
+	indexes := make([]int, 0, len(records)) // indexes holds the index of the record of each row
+	for i, record := range records {
+		if record.Operation == sdk.OperationDelete {
+			sdk.Logger(ctx).Debug().Str("position", string(record.Position)).Msg("skipping delete record")
+			continue
+		}
+		if heartbeat(record) {
+			sdk.Logger(ctx).Debug().Str("position", string(record.Position)).Msg("skipping heartbeat record")
+			continue
+		}
+
+		row, err := payloadRow(record)
+		if err != nil {
+			// the records before are written, so only they are acknowledged
+			if n, err := d.insert(ctx, rows, indexes); err != nil {
+				return n, err
+			}
+			return i, err
+		}
+		rows = append(rows, row)
+		indexes = append(indexes, i)
+	}
+
+	if n, err := d.insert(ctx, rows, indexes); err != nil {
+		return n, err
+	}
+	return len(records), nil
+}
+
+// insert inserts the rows in requests of at most maxInsertRows rows. On error it returns the index of
+// the record of the first row which was not inserted.
+func (d *Destination) insert(ctx context.Context, rows []rowSaver, indexes []int) (int, error) {
+	for start := 0; start < len(rows); start += maxInsertRows {
+		end := start + maxInsertRows
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		err := d.inserter.Put(ctx, rows[start:end])
+		if err == nil {
+			continue
+		}
+		sdk.Logger(ctx).Error().Str("err", err.Error()).Int("rows", end-start).Msg("error while inserting rows")
+
+		failed := start
+		var multiErr bigquery.PutMultiError
+		if errors.As(err, &multiErr) && len(multiErr) > 0 {
+			// rows which didn't fail are inserted, the records from the first failed row on are retried
+			failed = end
+			for _, rowErr := range multiErr {
+				if start+rowErr.RowIndex < failed {
+					failed = start + rowErr.RowIndex
+				}
+			}
+		}
+		return indexes[failed], fmt.Errorf("error while inserting rows: %w", err)
+	}
+	return len(rows), nil
+}
+
+func (d *Destination) Teardown(ctx context.Context) error {
+	ctx = googlebigquery.WithLogLevel(ctx, d.destinationConfig.Config.LogLevel)
+	if d.client != nil {
+		if err := d.client.Close(); err != nil {
+			sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("got error while closing BigQuery client")
+			return err
+		}
+	}
+	return nil
+}
+
+// rowSaver saves the payload of a record as row. BigQuery generates the insert ID.
+type rowSaver map[string]bigquery.Value
+
+func (r rowSaver) Save() (map[string]bigquery.Value, string, error) {
+	return r, "", nil
+}
+
+// heartbeat reports if the record is a heartbeat of the source, which only carries the position and
+// isn't written to the table
+func heartbeat(record sdk.Record) bool {
+	return record.Metadata[googlebigquery.MetadataHeartbeat] == "true"
+}
+
+// payloadRow returns the row holding the payload of the record. Raw payloads need to be JSON objects.
+func payloadRow(record sdk.Record) (rowSaver, error) {
+	switch payload := record.Payload.After.(type) {
+	case sdk.StructuredData:
+		row := make(rowSaver, len(payload))
+		for column, value := range payload {
+			row[column] = value
+		}
+		return row, nil
+	case nil:
+		return nil, fmt.Errorf("record at position %s has no payload", record.Position)
+	default:
+		var row rowSaver
+		if err := json.Unmarshal(payload.Bytes(), &row); err != nil {
+			return nil, fmt.Errorf("payload of record at position %s is not a JSON object: %w", record.Position, err)
+		}
+		return row, nil
+	}
+}
@@ -0,0 +1,414 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googledestination
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	googlebigquery "github.com/neha-Gupta1/conduit-connector-bigquery"
+)
+
+// mockInserter collects the rows and the size of every insert request. Fails once err is set.
+type mockInserter struct {
+	rows  *[]map[string]bigquery.Value
+	calls *[]int
+	err   error
+}
+
+func (m mockInserter) Put(ctx context.Context, src interface{}) error {
+	if m.err != nil {
+		return m.err
+	}
+	rows := src.([]rowSaver)
+	if m.calls != nil {
+		*m.calls = append(*m.calls, len(rows))
+	}
+	for _, row := range rows {
+		saved, _, err := row.Save()
+		if err != nil {
+			return err
+		}
+		*m.rows = append(*m.rows, saved)
+	}
+	return nil
+}
+
+func TestConfigure(t *testing.T) {
+	dst := NewDestination()
+	err := dst.Configure(context.Background(), map[string]string{
+		googlebigquery.ConfigProjectID: "project",
+		googlebigquery.ConfigDatasetID: "dataset",
+		googlebigquery.ConfigLocation:  "US",
+		googlebigquery.ConfigTableID:   "table1",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, name := range []string{googlebigquery.ConfigTableID, sdk.DestinationWithBatch{}.BatchSizeParameterName()} {
+		if _, ok := dst.Parameters()[name]; !ok {
+			t.Errorf("expected %s parameter, got %v", name, dst.Parameters())
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var rows []map[string]bigquery.Value
+	dst := Destination{inserter: mockInserter{rows: &rows}}
+
+	records := []sdk.Record{
+		sdk.Util.Source.NewRecordCreate(sdk.Position("1"), nil, sdk.RawData("1"), sdk.StructuredData{"id": 1, "name": "one"}),
+		sdk.Util.Source.NewRecordSnapshot(sdk.Position("2"), nil, sdk.RawData("2"), sdk.RawData(`{"id":2,"name":"two"}`)),
+		sdk.Util.Source.NewRecordDelete(sdk.Position("3"), nil, sdk.RawData("1")),
+	}
+	n, err := dst.Write(context.Background(), records)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if n != 3 {
+		t.Errorf("expected 3 records written, got %v", n)
+	}
+
+	want := []map[string]bigquery.Value{
+		{"id": 1, "name": "one"},
+		{"id": float64(2), "name": "two"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("expected rows %v, got %v", want, rows)
+	}
+}
+
+func TestWriteSkipsHeartbeats(t *testing.T) {
+	heartbeat := sdk.Util.Source.NewRecordCreate(sdk.Position("2"), sdk.Metadata{googlebigquery.MetadataHeartbeat: "true"}, nil, nil)
+	records := []sdk.Record{
+		sdk.Util.Source.NewRecordCreate(sdk.Position("1"), nil, sdk.RawData("1"), sdk.StructuredData{"id": 1}),
+		heartbeat,
+		sdk.Util.Source.NewRecordCreate(sdk.Position("3"), nil, sdk.RawData("3"), sdk.StructuredData{"id": 3}),
+	}
+
+	var rows []map[string]bigquery.Value
+	dst := Destination{inserter: mockInserter{rows: &rows}}
+	n, err := dst.Write(context.Background(), records)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if n != 3 {
+		t.Errorf("expected the heartbeat to be acknowledged, got %v records written", n)
+	}
+	want := []map[string]bigquery.Value{{"id": 1}, {"id": 3}}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("expected rows %v, got %v", want, rows)
+	}
+
+	var queries []string
+	var params [][]bigquery.QueryParameter
+	dst = upsertDestination(&queries, &params, "id")
+	n, err = dst.Write(context.Background(), records)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if n != 3 || len(queries) != 1 {
+		t.Errorf("expected the records to be merged by a single query, got %v records written by %v", n, queries)
+	}
+
+	table := &mockTable{}
+	rows = nil
+	dst = Destination{inserter: mockInserter{rows: &rows}, table: table, missing: true}
+	if _, err := dst.Write(context.Background(), []sdk.Record{heartbeat, records[0]}); err != nil {
+		t.Fatalf("expected the schema to be inferred from the record after the heartbeat, got %v", err)
+	}
+}
+
+func TestWriteInvalidPayload(t *testing.T) {
+	var rows []map[string]bigquery.Value
+	dst := Destination{inserter: mockInserter{rows: &rows}}
+
+	records := []sdk.Record{
+		sdk.Util.Source.NewRecordCreate(sdk.Position("1"), nil, sdk.RawData("1"), sdk.StructuredData{"id": 1}),
+		sdk.Util.Source.NewRecordCreate(sdk.Position("2"), nil, sdk.RawData("2"), sdk.RawData("not json")),
+	}
+	n, err := dst.Write(context.Background(), records)
+	if err == nil {
+		t.Errorf("expected error for payload which isn't a JSON object")
+	}
+	if n != 1 {
+		t.Errorf("expected 1 record written, got %v", n)
+	}
+}
+
+func TestWriteInsertError(t *testing.T) {
+	var rows []map[string]bigquery.Value
+	dst := Destination{inserter: mockInserter{rows: &rows, err: errors.New("quota exceeded")}}
+
+	n, err := dst.Write(context.Background(), []sdk.Record{
+		sdk.Util.Source.NewRecordCreate(sdk.Position("1"), nil, sdk.RawData("1"), sdk.StructuredData{"id": 1}),
+	})
+	if err == nil || n != 0 {
+		t.Errorf("expected error and no record written, got %v records and error %v", n, err)
+	}
+}
+
+func TestWriteBatch(t *testing.T) {
+	var rows []map[string]bigquery.Value
+	var calls []int
+	dst := Destination{inserter: mockInserter{rows: &rows, calls: &calls}}
+
+	records := make([]sdk.Record, 10000)
+	for i := range records {
+		records[i] = sdk.Util.Source.NewRecordCreate(sdk.Position(strconv.Itoa(i)), nil, sdk.RawData(strconv.Itoa(i)), sdk.StructuredData{"id": i})
+	}
+	n, err := dst.Write(context.Background(), records)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if n != len(records) {
+		t.Errorf("expected %v records written, got %v", len(records), n)
+	}
+
+	if len(calls) != len(records)/maxInsertRows {
+		t.Errorf("expected %v insert requests, got %v", len(records)/maxInsertRows, len(calls))
+	}
+	for _, size := range calls {
+		if size != maxInsertRows {
+			t.Errorf("expected insert requests of %v rows, got %v", maxInsertRows, size)
+		}
+	}
+	if len(rows) != len(records) || rows[len(rows)-1]["id"] != len(records)-1 {
+		t.Errorf("expected %v rows in order, got %v", len(records), len(rows))
+	}
+}
+
+// failingRowInserter fails to insert the row at index failed of every request
+type failingRowInserter struct {
+	failed int
+}
+
+func (f failingRowInserter) Put(ctx context.Context, src interface{}) error {
+	return bigquery.PutMultiError{{RowIndex: f.failed, Errors: bigquery.MultiError{errors.New("invalid row")}}}
+}
+
+func TestWriteRowError(t *testing.T) {
+	dst := Destination{inserter: failingRowInserter{failed: 1}}
+
+	n, err := dst.Write(context.Background(), []sdk.Record{
+		sdk.Util.Source.NewRecordDelete(sdk.Position("1"), nil, sdk.RawData("1")),
+		sdk.Util.Source.NewRecordCreate(sdk.Position("2"), nil, sdk.RawData("2"), sdk.StructuredData{"id": 2}),
+		sdk.Util.Source.NewRecordCreate(sdk.Position("3"), nil, sdk.RawData("3"), sdk.StructuredData{"id": 3}),
+	})
+	if err == nil {
+		t.Errorf("expected error for failed row")
+	}
+	// the second row belongs to the third record
+	if n != 2 {
+		t.Errorf("expected 2 records written, got %v", n)
+	}
+}
+
+// mockQuerier collects the queries run
+type mockQuerier struct {
+	queries *[]string
+	params  *[][]bigquery.QueryParameter
+}
+
+func (m mockQuerier) Run(ctx context.Context, query string, params []bigquery.QueryParameter) error {
+	*m.queries = append(*m.queries, query)
+	*m.params = append(*m.params, params)
+	return nil
+}
+
+func upsertDestination(queries *[]string, params *[][]bigquery.QueryParameter, keyColumns ...string) Destination {
+	return Destination{
+		destinationConfig: googlebigquery.DestinationConfig{
+			Config: googlebigquery.Config{
+				ProjectID:          "project",
+				DatasetID:          "dataset",
+				PrimaryKeyColNames: keyColumns,
+			},
+			TableID:   "table1",
+			WriteMode: googlebigquery.WriteModeUpsert,
+		},
+		querier: mockQuerier{queries: queries, params: params},
+	}
+}
+
+func TestWriteUpsert(t *testing.T) {
+	var queries []string
+	var params [][]bigquery.QueryParameter
+	dst := upsertDestination(&queries, &params, "id")
+
+	n, err := dst.Write(context.Background(), []sdk.Record{
+		sdk.Util.Source.NewRecordCreate(sdk.Position("1"), nil, sdk.RawData("1"), sdk.StructuredData{"id": 1, "name": "a"}),
+		sdk.Util.Source.NewRecordUpdate(sdk.Position("2"), nil, sdk.RawData("1"), nil, sdk.StructuredData{"id": 1, "name": "b"}),
+		sdk.Util.Source.NewRecordCreate(sdk.Position("3"), nil, sdk.RawData("2"), sdk.StructuredData{"id": 2, "name": "c"}),
+		sdk.Util.Source.NewRecordDelete(sdk.Position("4"), nil, sdk.RawData(`{"id":2}`)),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if n != 4 {
+		t.Errorf("expected 4 records written, got %v", n)
+	}
+
+	want := "MERGE `project.dataset.table1` T USING UNNEST([" +
+		"STRUCT(@p0 AS `id`, @p1 AS `name`, false AS _conduit_deleted), " +
+		"STRUCT(@p2 AS `id`, NULL AS `name`, true AS _conduit_deleted)]) S " +
+		"ON T.`id` = S.`id` WHEN MATCHED AND S._conduit_deleted THEN DELETE " +
+		"WHEN MATCHED THEN UPDATE SET `name` = S.`name` " +
+		"WHEN NOT MATCHED AND NOT S._conduit_deleted THEN INSERT (`id`, `name`) VALUES (S.`id`, S.`name`)"
+	if len(queries) != 1 || queries[0] != want {
+		t.Fatalf("expected query %v, got %v", want, queries)
+	}
+	wantParams := []bigquery.QueryParameter{
+		{Name: "p0", Value: 1},
+		{Name: "p1", Value: "b"},
+		{Name: "p2", Value: float64(2)},
+	}
+	if !reflect.DeepEqual(params[0], wantParams) {
+		t.Errorf("expected params %v, got %v", wantParams, params[0])
+	}
+}
+
+func TestWriteUpsertColumnsChange(t *testing.T) {
+	var queries []string
+	var params [][]bigquery.QueryParameter
+	dst := upsertDestination(&queries, &params, "id")
+
+	_, err := dst.Write(context.Background(), []sdk.Record{
+		sdk.Util.Source.NewRecordCreate(sdk.Position("1"), nil, sdk.RawData("1"), sdk.StructuredData{"id": 1, "name": "a"}),
+		sdk.Util.Source.NewRecordDelete(sdk.Position("2"), nil, sdk.StructuredData{"id": 3}),
+		sdk.Util.Source.NewRecordCreate(sdk.Position("3"), nil, sdk.RawData("2"), sdk.StructuredData{"id": 2, "age": 5}),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(queries) != 2 {
+		t.Fatalf("expected a query per columns, got %v", queries)
+	}
+	if !strings.Contains(queries[1], "INSERT (`age`, `id`)") {
+		t.Errorf("expected second query to insert age, got %v", queries[1])
+	}
+}
+
+func TestWriteUpsertMissingKey(t *testing.T) {
+	var queries []string
+	var params [][]bigquery.QueryParameter
+	dst := upsertDestination(&queries, &params, "id", "line")
+
+	n, err := dst.Write(context.Background(), []sdk.Record{
+		sdk.Util.Source.NewRecordCreate(sdk.Position("1"), nil, sdk.RawData("1"), sdk.StructuredData{"id": 1, "line": 1}),
+		sdk.Util.Source.NewRecordDelete(sdk.Position("2"), nil, sdk.RawData("1")),
+	})
+	if err == nil {
+		t.Errorf("expected error for key which isn't a JSON object")
+	}
+	if n != 1 || len(queries) != 1 {
+		t.Errorf("expected first record merged, got %v records and queries %v", n, queries)
+	}
+}
+
+// mockTable collects the metadata the table is created with
+type mockTable struct {
+	created *bigquery.TableMetadata
+}
+
+func (m *mockTable) Metadata(ctx context.Context, opts ...bigquery.TableMetadataOption) (*bigquery.TableMetadata, error) {
+	return m.created, nil
+}
+
+func (m *mockTable) Create(ctx context.Context, tm *bigquery.TableMetadata) error {
+	m.created = tm
+	return nil
+}
+
+func TestInferSchema(t *testing.T) {
+	schema, err := inferSchema(map[string]bigquery.Value{
+		"name":    "a",
+		"price":   1.5,
+		"count":   3,
+		"active":  true,
+		"created": time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC),
+		"empty":   nil,
+		"tags":    []interface{}{"x", "y"},
+		"address": map[string]interface{}{"city": "Berlin", "zip": 10115},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := bigquery.Schema{
+		{Name: "active", Type: bigquery.BooleanFieldType},
+		{Name: "address", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+			{Name: "city", Type: bigquery.StringFieldType},
+			{Name: "zip", Type: bigquery.IntegerFieldType},
+		}},
+		{Name: "count", Type: bigquery.IntegerFieldType},
+		{Name: "created", Type: bigquery.TimestampFieldType},
+		{Name: "empty", Type: bigquery.StringFieldType},
+		{Name: "name", Type: bigquery.StringFieldType},
+		{Name: "price", Type: bigquery.FloatFieldType},
+		{Name: "tags", Type: bigquery.StringFieldType, Repeated: true},
+	}
+	if !reflect.DeepEqual(schema, want) {
+		t.Errorf("expected schema %v, got %v", want, schema)
+	}
+
+	if _, err := inferSchema(map[string]bigquery.Value{"tags": []interface{}{}}); err == nil {
+		t.Errorf("expected error for empty list")
+	}
+}
+
+func TestWriteAutoCreate(t *testing.T) {
+	var rows []map[string]bigquery.Value
+	table := &mockTable{}
+	dst := Destination{inserter: mockInserter{rows: &rows}, table: table, missing: true}
+
+	n, err := dst.Write(context.Background(), []sdk.Record{
+		sdk.Util.Source.NewRecordDelete(sdk.Position("1"), nil, sdk.RawData("1")),
+		sdk.Util.Source.NewRecordCreate(sdk.Position("2"), nil, sdk.RawData("2"), sdk.StructuredData{"id": 2, "price": 1.5}),
+		sdk.Util.Source.NewRecordCreate(sdk.Position("3"), nil, sdk.RawData("3"), sdk.StructuredData{"id": 3, "price": 2}),
+		sdk.Util.Source.NewRecordCreate(sdk.Position("4"), nil, sdk.RawData("4"), sdk.StructuredData{"id": "4"}),
+	})
+	if table.created == nil {
+		t.Fatalf("expected table to be created")
+	}
+	want := bigquery.Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType},
+		{Name: "price", Type: bigquery.FloatFieldType},
+	}
+	if !reflect.DeepEqual(table.created.Schema, want) {
+		t.Errorf("expected schema %v, got %v", want, table.created.Schema)
+	}
+
+	if err == nil || !strings.Contains(err.Error(), "column id should be of type INTEGER") {
+		t.Errorf("expected error for record not fitting the schema, got %v", err)
+	}
+	if n != 3 || len(rows) != 2 {
+		t.Errorf("expected records before the invalid record written, got %v records and rows %v", n, rows)
+	}
+
+	_, err = dst.Write(context.Background(), []sdk.Record{
+		sdk.Util.Source.NewRecordCreate(sdk.Position("5"), nil, sdk.RawData("5"), sdk.StructuredData{"id": 5, "name": "a"}),
+	})
+	if err == nil {
+		t.Errorf("expected error for column missing in the schema")
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googledestination
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+const (
+	// deletedColumn flags the merged rows whose record is a delete
+	deletedColumn = "_conduit_deleted"
+
+	// maxMergeParameters caps the query parameters of a MERGE query, BigQuery allows 10000
+	maxMergeParameters = 10000
+)
+
+// querier runs a query job till it's done
+type querier interface {
+	Run(ctx context.Context, query string, params []bigquery.QueryParameter) error
+}
+
+// clientQuerier runs the queries with the BigQuery client
+type clientQuerier struct {
+	client   *bigquery.Client
+	location string
+}
+
+func (c clientQuerier) Run(ctx context.Context, query string, params []bigquery.QueryParameter) error {
+	q := c.client.Query(query)
+	q.Parameters = params
+	q.Location = c.location
+
+	job, err := q.Run(ctx)
+	if err != nil {
+		return err
+	}
+	status, err := job.Wait(ctx)
+	if err != nil {
+		return err
+	}
+	return status.Err()
+}
+
+// mergeRow is a row merged into the table
+type mergeRow struct {
+	index   int                       // index is the index of the record of the row
+	values  map[string]bigquery.Value // values holds the columns of the row, only the key for deletes
+	deleted bool
+}
+
+// upsert merges the records into the table by primary key. Records are merged with one MERGE query
+// per run of consecutive records with the same columns. Returns the number of records written before
+// an error occurred.
+func (d *Destination) upsert(ctx context.Context, records []sdk.Record) (int, error) {
+	var run []mergeRow
+	var columns []string // columns are the columns of the current run, nil while it only holds deletes
+	for i, record := range records {
+		if heartbeat(record) {
+			sdk.Logger(ctx).Debug().Str("position", string(record.Position)).Msg("skipping heartbeat record")
+			continue
+		}
+		row, err := d.mergeRow(record)
+		if err != nil {
+			if err := d.merge(ctx, run, columns); err != nil {
+				return firstIndex(run), err
+			}
+			return i, err
+		}
+		row.index = i
+
+		if !row.deleted {
+			rowColumns := sortedColumns(row.values)
+			if columns != nil && strings.Join(rowColumns, ",") != strings.Join(columns, ",") {
+				if err := d.merge(ctx, run, columns); err != nil {
+					return firstIndex(run), err
+				}
+				run = nil
+			}
+			columns = rowColumns
+		}
+		run = append(run, row)
+	}
+
+	if err := d.merge(ctx, run, columns); err != nil {
+		return firstIndex(run), err
+	}
+	return len(records), nil
+}
+
+// mergeRow returns the row of the record. Deletes only hold the key, which is read from the record
+// key. The key of other records is read from their payload.
+func (d *Destination) mergeRow(record sdk.Record) (mergeRow, error) {
+	keyColumns := d.destinationConfig.Config.PrimaryKeyColNames
+	if record.Operation == sdk.OperationDelete {
+		key, err := recordKey(record, keyColumns)
+		if err != nil {
+			return mergeRow{}, err
+		}
+		return mergeRow{values: key, deleted: true}, nil
+	}
+
+	row, err := payloadRow(record)
+	if err != nil {
+		return mergeRow{}, err
+	}
+	for _, column := range keyColumns {
+		if row[column] == nil {
+			return mergeRow{}, fmt.Errorf("payload of record at position %s has no primary key column %s", record.Position, column)
+		}
+	}
+	return mergeRow{values: row}, nil
+}
+
+// recordKey returns the primary key columns of the record key. Keys are structured data or JSON
+// objects holding the columns. A raw key which isn't a JSON object is taken as value of a single
+// primary key column.
+func recordKey(record sdk.Record, columns []string) (map[string]bigquery.Value, error) {
+	key := make(map[string]bigquery.Value, len(columns))
+	switch recordKey := record.Key.(type) {
+	case sdk.StructuredData:
+		for column, value := range recordKey {
+			key[column] = value
+		}
+	case nil:
+		return nil, fmt.Errorf("delete record at position %s has no key", record.Position)
+	default:
+		if err := json.Unmarshal(recordKey.Bytes(), &key); err != nil {
+			if len(columns) != 1 {
+				return nil, fmt.Errorf("key of delete record at position %s is not a JSON object: %w", record.Position, err)
+			}
+			key[columns[0]] = string(recordKey.Bytes())
+		}
+	}
+
+	keyColumns := make(map[string]bigquery.Value, len(columns))
+	for _, column := range columns {
+		if key[column] == nil {
+			return nil, fmt.Errorf("key of delete record at position %s has no primary key column %s", record.Position, column)
+		}
+		keyColumns[column] = key[column]
+	}
+	return keyColumns, nil
+}
+
+// merge merges the rows into the table. Only the last row of every key is merged, as MERGE fails
+// when several rows match the same row of the table.
+func (d *Destination) merge(ctx context.Context, rows []mergeRow, columns []string) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	keyColumns := d.destinationConfig.Config.PrimaryKeyColNames
+	if columns == nil {
+		columns = append([]string(nil), keyColumns...)
+		sort.Strings(columns)
+	}
+
+	last := make(map[string]int, len(rows))
+	for i, row := range rows {
+		key, err := json.Marshal(keyValues(row.values, keyColumns))
+		if err != nil {
+			return fmt.Errorf("error marshalling key: %w", err)
+		}
+		last[string(key)] = i
+	}
+	// deletes come last, so the types of the columns are taken from a row holding them
+	merged := make([]mergeRow, 0, len(last))
+	for _, deleted := range []bool{false, true} {
+		for i, row := range rows {
+			key, _ := json.Marshal(keyValues(row.values, keyColumns))
+			if last[string(key)] == i && row.deleted == deleted {
+				merged = append(merged, row)
+			}
+		}
+	}
+
+	chunkSize := maxMergeParameters / len(columns)
+	for start := 0; start < len(merged); start += chunkSize {
+		end := start + chunkSize
+		if end > len(merged) {
+			end = len(merged)
+		}
+		query, params := d.mergeQuery(merged[start:end], columns)
+		if err := d.querier.Run(ctx, query, params); err != nil {
+			sdk.Logger(ctx).Error().Str("err", err.Error()).Int("rows", end-start).Msg("error while merging rows")
+			return fmt.Errorf("error while merging rows: %w", err)
+		}
+	}
+	return nil
+}
+
+// mergeQuery returns the MERGE query of the rows. The rows are passed as array of structs, the
+// columns missing in a delete are NULL.
+func (d *Destination) mergeQuery(rows []mergeRow, columns []string) (string, []bigquery.QueryParameter) {
+	config := d.destinationConfig.Config
+	params := make([]bigquery.QueryParameter, 0, len(rows)*len(columns))
+	structs := make([]string, 0, len(rows))
+	for _, row := range rows {
+		fields := make([]string, 0, len(columns)+1)
+		for _, column := range columns {
+			value := row.values[column]
+			if value == nil {
+				fields = append(fields, "NULL AS `"+column+"`")
+				continue
+			}
+			name := "p" + strconv.Itoa(len(params))
+			params = append(params, bigquery.QueryParameter{Name: name, Value: value})
+			fields = append(fields, "@"+name+" AS `"+column+"`")
+		}
+		fields = append(fields, strconv.FormatBool(row.deleted)+" AS "+deletedColumn)
+		structs = append(structs, "STRUCT("+strings.Join(fields, ", ")+")")
+	}
+
+	keys := make(map[string]bool, len(config.PrimaryKeyColNames))
+	on := make([]string, 0, len(config.PrimaryKeyColNames))
+	for _, column := range config.PrimaryKeyColNames {
+		keys[column] = true
+		on = append(on, "T.`"+column+"` = S.`"+column+"`")
+	}
+	var set, insertColumns, insertValues []string
+	for _, column := range columns {
+		if !keys[column] {
+			set = append(set, "`"+column+"` = S.`"+column+"`")
+		}
+		insertColumns = append(insertColumns, "`"+column+"`")
+		insertValues = append(insertValues, "S.`"+column+"`")
+	}
+
+	query := "MERGE `" + config.ProjectID + "." + config.DatasetID + "." + d.destinationConfig.TableID + "` T" +
+		" USING UNNEST([" + strings.Join(structs, ", ") + "]) S" +
+		" ON " + strings.Join(on, " AND ") +
+		" WHEN MATCHED AND S." + deletedColumn + " THEN DELETE"
+	if len(set) > 0 {
+		query += " WHEN MATCHED THEN UPDATE SET " + strings.Join(set, ", ")
+	}
+	query += " WHEN NOT MATCHED AND NOT S." + deletedColumn + " THEN INSERT (" + strings.Join(insertColumns, ", ") +
+		") VALUES (" + strings.Join(insertValues, ", ") + ")"
+	return query, params
+}
+
+// keyValues returns the values of the key columns in order
+func keyValues(values map[string]bigquery.Value, columns []string) []bigquery.Value {
+	key := make([]bigquery.Value, 0, len(columns))
+	for _, column := range columns {
+		key = append(key, values[column])
+	}
+	return key
+}
+
+// sortedColumns returns the columns of the row in alphabetical order
+func sortedColumns(values map[string]bigquery.Value) []string {
+	columns := make([]string, 0, len(values))
+	for column := range values {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// firstIndex returns the index of the record of the first row
+func firstIndex(rows []mergeRow) int {
+	if len(rows) == 0 {
+		return 0
+	}
+	return rows[0].index
+}
@@ -0,0 +1,221 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googledestination
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"google.golang.org/api/googleapi"
+)
+
+// tableClient reads and creates the metadata of the table
+type tableClient interface {
+	Metadata(ctx context.Context, opts ...bigquery.TableMetadataOption) (*bigquery.TableMetadata, error)
+	Create(ctx context.Context, tm *bigquery.TableMetadata) error
+}
+
+// createTable creates the missing table with the schema inferred from the first record holding a
+// payload. The table stays missing while there's no such record.
+func (d *Destination) createTable(ctx context.Context, records []sdk.Record) error {
+	for _, record := range records {
+		if record.Operation == sdk.OperationDelete || heartbeat(record) {
+			continue
+		}
+		row, err := payloadRow(record)
+		if err != nil {
+			return err
+		}
+		schema, err := inferSchema(row)
+		if err != nil {
+			return fmt.Errorf("error while inferring schema from record at position %s: %w", record.Position, err)
+		}
+
+		err = d.table.Create(ctx, &bigquery.TableMetadata{Schema: schema})
+		if hasStatus(err, http.StatusConflict) {
+			// the table was created in the meantime, records are checked by BigQuery against its schema
+			sdk.Logger(ctx).Info().Str("tableID", d.destinationConfig.TableID).Msg("table already exists")
+			d.missing = false
+			return nil
+		}
+		if err != nil {
+			sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("error while creating table")
+			return fmt.Errorf("error while creating table %s: %w", d.destinationConfig.TableID, err)
+		}
+		sdk.Logger(ctx).Info().Str("tableID", d.destinationConfig.TableID).Msg("created table")
+		d.missing = false
+		d.schema = schema
+		return nil
+	}
+	return nil
+}
+
+// inferSchema returns the schema of the row. Columns are ordered by name.
+func inferSchema(row map[string]bigquery.Value) (bigquery.Schema, error) {
+	columns := sortedColumns(row)
+	schema := make(bigquery.Schema, 0, len(columns))
+	for _, column := range columns {
+		field, err := inferField(column, row[column])
+		if err != nil {
+			return nil, err
+		}
+		schema = append(schema, field)
+	}
+	return schema, nil
+}
+
+// inferField returns the field of the value. Nil values are inferred as STRING, as their type is
+// unknown, nested maps as RECORD and slices as repeated field of the type of their first element.
+func inferField(name string, value interface{}) (*bigquery.FieldSchema, error) {
+	field := &bigquery.FieldSchema{Name: name}
+	switch value.(type) {
+	case nil:
+		field.Type = bigquery.StringFieldType
+		return field, nil
+	case time.Time:
+		field.Type = bigquery.TimestampFieldType
+		return field, nil
+	case []byte:
+		field.Type = bigquery.BytesFieldType
+		return field, nil
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String:
+		field.Type = bigquery.StringFieldType
+	case reflect.Bool:
+		field.Type = bigquery.BooleanFieldType
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		field.Type = bigquery.IntegerFieldType
+	case reflect.Float32, reflect.Float64:
+		field.Type = bigquery.FloatFieldType
+	case reflect.Map:
+		values, ok := mapValues(v)
+		if !ok {
+			return nil, fmt.Errorf("column %s has unsupported type %T", name, value)
+		}
+		schema, err := inferSchema(values)
+		if err != nil {
+			return nil, err
+		}
+		field.Type = bigquery.RecordFieldType
+		field.Schema = schema
+	case reflect.Slice, reflect.Array:
+		if v.Len() == 0 {
+			return nil, fmt.Errorf("can't infer the type of column %s from an empty list", name)
+		}
+		element, err := inferField(name, v.Index(0).Interface())
+		if err != nil {
+			return nil, err
+		}
+		if element.Repeated {
+			return nil, fmt.Errorf("column %s is a list of lists, which BigQuery doesn't support", name)
+		}
+		element.Repeated = true
+		return element, nil
+	default:
+		return nil, fmt.Errorf("column %s has unsupported type %T", name, value)
+	}
+	return field, nil
+}
+
+// fitSchema checks if the row can be written to a table of the schema
+func fitSchema(schema bigquery.Schema, row map[string]bigquery.Value) error {
+	fields := make(map[string]*bigquery.FieldSchema, len(schema))
+	for _, field := range schema {
+		fields[field.Name] = field
+	}
+	for column, value := range row {
+		field, ok := fields[column]
+		if !ok {
+			return fmt.Errorf("column %s is not in the table schema", column)
+		}
+		if err := fitField(field, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fitField checks if the value can be written to the field. Nil fits every field and integers fit
+// FLOAT fields.
+func fitField(field *bigquery.FieldSchema, value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	v := reflect.ValueOf(value)
+
+	if field.Repeated {
+		if _, ok := value.([]byte); ok || (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) {
+			return fmt.Errorf("column %s should be a list of %s, got %T", field.Name, field.Type, value)
+		}
+		element := *field
+		element.Repeated = false
+		for i := 0; i < v.Len(); i++ {
+			if err := fitField(&element, v.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if field.Type == bigquery.RecordFieldType {
+		values, ok := mapValues(v)
+		if !ok {
+			return fmt.Errorf("column %s should be of type %s, got %T", field.Name, field.Type, value)
+		}
+		if err := fitSchema(field.Schema, values); err != nil {
+			return fmt.Errorf("column %s: %w", field.Name, err)
+		}
+		return nil
+	}
+
+	inferred, err := inferField(field.Name, value)
+	if err != nil {
+		return err
+	}
+	if inferred.Repeated || (inferred.Type != field.Type &&
+		!(inferred.Type == bigquery.IntegerFieldType && field.Type == bigquery.FloatFieldType)) {
+		return fmt.Errorf("column %s should be of type %s, got %T", field.Name, field.Type, value)
+	}
+	return nil
+}
+
+// mapValues returns the values of a map keyed by strings
+func mapValues(v reflect.Value) (map[string]bigquery.Value, bool) {
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+	values := make(map[string]bigquery.Value, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		values[iter.Key().String()] = iter.Value().Interface()
+	}
+	return values, true
+}
+
+// hasStatus reports if the error is an API error with the status code
+func hasStatus(err error, code int) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
@@ -15,9 +15,7 @@
 package googlesource
 
 import (
-	"bytes"
 	"context"
-	"encoding/gob"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -44,6 +42,7 @@ func TestAcceptance(t *testing.T) {
 		googlebigquery.ConfigPrimaryKeyColName:  "created_at",
 		googlebigquery.ConfigIncrementalColName: "created_at",
 		googlebigquery.ConfigPollingTime:        "1ms",
+		googlebigquery.ConfigAllowFastPolling:   "true",
 	}
 
 	// create a dataset once and clean up later
@@ -163,7 +162,7 @@ func dataSetupWithRecord(t *testing.T, config map[string]string, record []sdk.Re
 	defer client.Close()
 
 	var query string
-	positions := ""
+	positions := make(map[string]string)
 
 	for i := 0; i < len(record); i++ {
 		createdAt := time.Now().AddDate(0, 0, globalCounter).UTC()
@@ -176,22 +175,28 @@ func dataSetupWithRecord(t *testing.T, config map[string]string, record []sdk.Re
 		data["name"] = name
 		data["created_at"] = createdAtBQFormat
 
-		key := createdAtBQFormat
-
-		buffer := &bytes.Buffer{}
-		if err := gob.NewEncoder(buffer).Encode(key); err != nil {
+		byteKey, err := json.Marshal(createdAtBQFormat)
+		if err != nil {
 			return result, err
 		}
-		byteKey := buffer.Bytes()
 
-		positions = fmt.Sprintf("'%s'", createdAtBQFormat)
-		positionRecord, err := json.Marshal(&positions)
+		positions[tableID] = "TIMESTAMP " + createdAt.Format("2006-01-02 15:04:05.999999-07:00")
+		mode := PositionModeCDC
+		if record[i].Operation == sdk.OperationSnapshot {
+			mode = PositionModeSnapshot
+		}
+		positionRecord, err := json.Marshal(&Position{Version: PositionVersion, Mode: mode, Table: tableID, Offsets: positions})
 		if err != nil {
 			t.Log("error found", err)
 			return result, err
 		}
 
-		result = append(result, sdk.Record{Payload: data, Key: sdk.RawData(byteKey), Position: positionRecord})
+		result = append(result, sdk.Record{
+			Operation: record[i].Operation,
+			Payload:   sdk.Change{After: data},
+			Key:       sdk.RawData(byteKey),
+			Position:  positionRecord,
+		})
 		q := client.Query(query)
 		q.Location = location
 
@@ -220,7 +225,7 @@ func cleantUpTableForAcceptance(t *testing.T, client *bigquery.Client, tables []
 	for _, tableID := range tables {
 		table := client.Dataset(datasetID).Table(tableID)
 		err := table.Delete(ctx)
-		if err != nil && strings.Contains(err.Error(), "Not found") {
+		if err != nil && notFound(err) {
 			return err
 		}
 	}
@@ -0,0 +1,267 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googlesource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	googlebigquery "github.com/neha-Gupta1/conduit-connector-bigquery"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	// changesFunction returns inserts, updates and deletes of tables with change history enabled
+	changesFunction = "CHANGES"
+	// appendsFunction returns the inserts of any table
+	appendsFunction = "APPENDS"
+
+	// changeTypeColumn and changeTimestampColumn are the pseudo columns returned by the functions
+	changeTypeColumn      = "_CHANGE_TYPE"
+	changeTimestampColumn = "_CHANGE_TIMESTAMP"
+
+	// changesDelay is how old changes need to be before CHANGES returns them
+	changesDelay = 10 * time.Minute
+)
+
+// changeHistory reports if the changes of the table are read from its change history. External
+// tables have no change history, they are polled instead.
+func (s *Source) changeHistory(ctx context.Context, tableID string) bool {
+	if s.sourceConfig.Config.CDCMode != googlebigquery.CDCModeChangeHistory || s.changeFunction(tableID) == "" {
+		return false
+	}
+	if s.externalTable(ctx, tableID) {
+		s.warnExternal(ctx, tableID, googlebigquery.ConfigCDCMode, "polling the table instead")
+		s.changeFunctions.Store(tableID, "")
+		return false
+	}
+	return true
+}
+
+// changeFunction returns the function the changes of the table are read with. Empty once the table
+// fell back to polling.
+func (s *Source) changeFunction(tableID string) string {
+	if function, ok := s.changeFunctions.Load(tableID); ok {
+		return function.(string)
+	}
+	return changesFunction
+}
+
+// snapshotPositionKey is the key the start of a snapshot is stored under in the position
+func snapshotPositionKey(tableID string) string {
+	return tableID + "#snapshot"
+}
+
+// changesPositionKey is the key the change history watermark is stored under in the position
+func changesPositionKey(tableID string) string {
+	return tableID + "#changes"
+}
+
+// timestampOffset returns the offset of the timestamp
+func timestampOffset(t time.Time) string {
+	return getType(bigquery.TimestampFieldType) + " " + t.UTC().Format(timestampOffsetLayout)
+}
+
+// readChangeHistory reads the snapshot of the table followed by its changes. The time the snapshot
+// started is kept in the position till the snapshot is done, it is then used as watermark of the
+// changes so changes made while the snapshot was read aren't missed.
+func (s *Source) readChangeHistory(ctx context.Context, tableID string) error {
+	if len(s.getPosition(changesPositionKey(tableID))) == 0 && s.sourceConfig.Config.Mode == googlebigquery.ModeCDC {
+		// without snapshot only the changes made from now on are read
+		s.setPosition(changesPositionKey(tableID), timestampOffset(time.Now()))
+	}
+	if len(s.getPosition(changesPositionKey(tableID))) == 0 {
+		snapshotKey := snapshotPositionKey(tableID)
+		if len(s.getPosition(snapshotKey)) == 0 {
+			s.setPosition(snapshotKey, timestampOffset(time.Now()))
+		}
+		if err := s.readTable(ctx, tableID, true); err != nil {
+			return err
+		}
+		if s.iteratorStopped() {
+			return nil
+		}
+
+		s.position.lock.Lock()
+		s.position.positions[changesPositionKey(tableID)] = s.position.positions[snapshotKey]
+		delete(s.position.positions, snapshotKey)
+		s.position.lock.Unlock()
+	}
+	return s.readChanges(ctx, tableID)
+}
+
+// readChanges reads the changes made to the table since the watermark. Tables which don't support
+// the change function fall back to APPENDS and then to polling.
+func (s *Source) readChanges(ctx context.Context, tableID string) error {
+	changesKey := changesPositionKey(tableID)
+	watermark := s.getPosition(changesKey)
+	_, value := parseOffset(watermark)
+	start, err := time.Parse(timestampOffsetLayout, value)
+	if err != nil {
+		return fmt.Errorf("invalid change history watermark %q: %w", watermark, err)
+	}
+
+	function := s.changeFunction(tableID)
+	end := time.Now().UTC()
+	if function == changesFunction {
+		end = end.Add(-changesDelay)
+	}
+	if !end.After(start) {
+		return nil
+	}
+
+	query := "SELECT " + s.changesSelectClause(tableID) + " FROM " + function + "(TABLE " + s.fromClause(tableID) +
+		", CAST(@start AS TIMESTAMP), CAST(@end AS TIMESTAMP)) " +
+		whereClause(changeTimestampColumn+" > CAST(@start AS TIMESTAMP)", s.filterCondition(ctx, tableID)) +
+		" ORDER BY " + changeTimestampColumn
+	params := []bigquery.QueryParameter{
+		{Name: "start", Value: value},
+		{Name: "end", Value: end.Format(timestampOffsetLayout)},
+	}
+	it, err := s.query(ctx, query, params...)
+	if err != nil {
+		if !invalidQuery(err) {
+			return err
+		}
+		fallback := ""
+		if function == changesFunction {
+			fallback = appendsFunction
+		}
+		sdk.Logger(ctx).Warn().Str("err", err.Error()).Str("tableID", tableID).Str("function", function).
+			Msg("Could not read change history of table, falling back")
+		s.changeFunctions.Store(tableID, fallback)
+		return s.ReadGoogleRow(ctx, tableID)
+	}
+
+	// the key columns are checked with the first row, as the schema of the table can change between polls
+	resolved := false
+	for {
+		if s.iteratorStopped() {
+			return nil
+		}
+		var row []bigquery.Value
+		err := it.Next(&row)
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("error while iterating changes")
+			return err
+		}
+
+		if !resolved {
+			_, err = columnIndexes(tableID, it.Schema(), s.sourceConfig.Config.PrimaryKeyColNames)
+			if err != nil {
+				sdk.Logger(ctx).Error().Str("err", err.Error()).Str("tableID", tableID).Msg("Error while resolving columns")
+				return err
+			}
+			resolved = true
+		}
+
+		record, err := s.changeRecord(ctx, tableID, it.Schema(), row, watermark)
+		if err != nil {
+			return err
+		}
+
+		if !s.emit(ctx, record) {
+			return nil
+		}
+	}
+
+	// records carry the watermark the changes were read from, so a restart reads them again
+	s.setPosition(changesKey, timestampOffset(end))
+	return nil
+}
+
+// changeRecord builds the record of a row returned by the change function. Deletes only hold the key.
+func (s *Source) changeRecord(ctx context.Context, tableID string, schema bigquery.Schema, row []bigquery.Value, watermark string) (sdk.Record, error) {
+	data := make(sdk.StructuredData)
+	var changeType string
+	// changes are created at the time they were committed
+	createdAt := s.clock()
+	for i, value := range row {
+		switch schema[i].Name {
+		case changeTypeColumn:
+			changeType, _ = value.(string)
+			continue
+		case changeTimestampColumn:
+			if t, ok := value.(time.Time); ok {
+				createdAt = t
+			}
+			continue
+		}
+
+		r, err := s.convertValue(ctx, schema[i], value)
+		if err != nil {
+			sdk.Logger(ctx).Error().Str("err", err.Error()).Str("column", schema[i].Name).Msg("Error while converting value")
+			return sdk.Record{}, err
+		}
+		data[schema[i].Name] = r
+	}
+
+	key, err := s.recordKey(data)
+	if err == nil {
+		key, err = s.outputKey(data, key)
+	}
+	if err != nil {
+		return sdk.Record{}, fmt.Errorf("error marshalling key: %w", err)
+	}
+	for _, column := range s.sourceConfig.Config.ExcludeColumns {
+		removeColumn(data, strings.Split(column, "."))
+	}
+	s.dropNulls(data)
+
+	recPosition, err := s.writePosition(tableID, changesPositionKey(tableID), watermark, false)
+	if err != nil {
+		return sdk.Record{}, fmt.Errorf("error marshalling position: %w", err)
+	}
+
+	metadata := s.recordMetadata(tableID)
+	metadata.SetCreatedAt(createdAt.UTC())
+	if changeType == "DELETE" {
+		return sdk.Util.Source.NewRecordDelete(recPosition, metadata, sdk.RawData(key)), nil
+	}
+	metadata[MetadataSchema] = s.schemaMetadata(ctx, tableID, schema)
+	if changeType == "UPDATE" {
+		return sdk.Util.Source.NewRecordUpdate(recPosition, metadata, sdk.RawData(key), nil, data), nil
+	}
+	return sdk.Util.Source.NewRecordCreate(recPosition, metadata, sdk.RawData(key), data), nil
+}
+
+// changesSelectClause returns the columns to query from the change function
+func (s *Source) changesSelectClause(tableID string) string {
+	columns := s.selectClause(tableID)
+	if columns == "*" {
+		return columns
+	}
+	return columns + ", " + changeTypeColumn + ", " + changeTimestampColumn
+}
+
+// invalidQuery reports if BigQuery rejected the query, eg. because the table has no change history
+func invalidQuery(err error) bool {
+	var bqErr *bigquery.Error
+	if errors.As(err, &bqErr) {
+		return bqErr.Reason == "invalidQuery"
+	}
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusBadRequest
+}
@@ -0,0 +1,50 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googlesource
+
+import (
+	"context"
+	"errors"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	googlebigquery "github.com/neha-Gupta1/conduit-connector-bigquery"
+)
+
+// ErrConversion is returned for rows whose values or key can't be converted to a record
+var ErrConversion = errors.New("row can't be converted")
+
+// rejectRow handles a row which can't be converted to a record as configured by onConversionError.
+// Skipped rows are logged, dead-letter records carry the error in their metadata and the row with its
+// unconvertible values formatted as string. Returns the error failing the sync, and false once the
+// source is stopping.
+func (s *Source) rejectRow(ctx context.Context, tableID string, position sdk.Position, key sdk.Data, data sdk.StructuredData, cause error) (bool, error) {
+	switch s.sourceConfig.Config.OnConversionError {
+	case googlebigquery.OnConversionErrorSkip:
+		sdk.Logger(ctx).Error().Str("err", cause.Error()).Str("tableID", tableID).Msg("Row skipped, it can't be converted")
+		return true, nil
+	case googlebigquery.OnConversionErrorDLQ:
+		sdk.Logger(ctx).Warn().Str("err", cause.Error()).Str("tableID", tableID).Msg("Row emitted as dead-letter record, it can't be converted")
+		metadata := s.recordMetadata(tableID)
+		metadata[MetadataConversionError] = cause.Error()
+		return s.emit(ctx, sdk.Util.Source.NewRecordCreate(position, metadata, key, data)), nil
+	}
+	return false, cause
+}
+
+// failOnConversionError reports if rows which can't be converted fail the sync
+func (s *Source) failOnConversionError() bool {
+	policy := s.sourceConfig.Config.OnConversionError
+	return policy != googlebigquery.OnConversionErrorSkip && policy != googlebigquery.OnConversionErrorDLQ
+}
@@ -0,0 +1,135 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googlesource
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"google.golang.org/api/iterator"
+)
+
+// keySet holds the primary keys of each table known since the last scan for deleted rows
+type keySet struct {
+	lock    sync.Mutex
+	keys    map[string]map[string]struct{} // keys holds the encoded keys keyed by table ID
+	scanned map[string]time.Time           // scanned holds when the keys of a table were last scanned
+}
+
+// add adds the key to the known keys of the table. Keys are only tracked once the table was scanned.
+func (k *keySet) add(tableID string, key []byte) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	if keys, ok := k.keys[tableID]; ok {
+		keys[string(key)] = struct{}{}
+	}
+}
+
+// due reports if the keys of the table should be scanned again
+func (k *keySet) due(tableID string, interval time.Duration) bool {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	scanned, ok := k.scanned[tableID]
+	return !ok || time.Since(scanned) >= interval
+}
+
+// replace sets the keys of the table and returns the keys which were known before but are missing
+// now. Nothing is missing on the first scan of the table.
+func (k *keySet) replace(tableID string, keys map[string]struct{}) (missing []string) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	if k.keys == nil {
+		k.keys = make(map[string]map[string]struct{})
+		k.scanned = make(map[string]time.Time)
+	}
+	for key := range k.keys[tableID] {
+		if _, ok := keys[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	k.keys[tableID] = keys
+	k.scanned[tableID] = time.Now()
+	return missing
+}
+
+// detectDeletes scans the primary keys of the table once the interval passed and emits a delete
+// record for every key which disappeared since the previous scan.
+func (s *Source) detectDeletes(ctx context.Context, tableID string) error {
+	if !s.sourceConfig.Config.DetectDeletes || !s.knownKeys.due(tableID, s.sourceConfig.Config.DetectDeletesInterval) {
+		return nil
+	}
+
+	columns := make([]string, 0, len(s.sourceConfig.Config.PrimaryKeyColNames))
+	for _, column := range s.sourceConfig.Config.PrimaryKeyColNames {
+		columns = append(columns, "`"+column+"`")
+	}
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM " + s.fromClause(tableID)
+	if where := whereClause(s.filterCondition(ctx, tableID)); len(where) > 0 {
+		query += " " + where
+	}
+	it, err := s.query(ctx, query)
+	if err != nil {
+		return fmt.Errorf("error while scanning primary keys: %w", err)
+	}
+
+	keys := make(map[string]struct{})
+	for {
+		// a partial scan would report the keys not read yet as deleted
+		if s.iteratorStopped() {
+			return nil
+		}
+		var row []bigquery.Value
+		err := it.Next(&row)
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error while scanning primary keys: %w", err)
+		}
+
+		schema := it.Schema()
+		data := make(sdk.StructuredData)
+		for i, value := range row {
+			r, err := s.convertValue(ctx, schema[i], value)
+			if err != nil {
+				return fmt.Errorf("error while converting primary key %s: %w", schema[i].Name, err)
+			}
+			data[schema[i].Name] = r
+		}
+		key, err := s.recordKey(data)
+		if err != nil {
+			return fmt.Errorf("error marshalling key: %w", err)
+		}
+		keys[string(key)] = struct{}{}
+	}
+
+	for _, key := range s.knownKeys.replace(tableID, keys) {
+		recPosition, err := s.writePosition(tableID, tableID, s.getPosition(tableID), false)
+		if err != nil {
+			return fmt.Errorf("error marshalling position: %w", err)
+		}
+		record := sdk.Util.Source.NewRecordDelete(recPosition, s.recordMetadata(tableID), sdk.RawData(key))
+
+		if !s.emit(ctx, record) {
+			return nil
+		}
+	}
+	return nil
+}
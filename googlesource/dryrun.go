@@ -0,0 +1,64 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googlesource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/bigquery"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+// queryEstimator dry runs queries to estimate the bytes they process
+type queryEstimator interface {
+	Estimate(ctx context.Context, s *Source, query string, params ...bigquery.QueryParameter) (int64, error)
+}
+
+// runDryRun estimates the bytes processed by the first query of every table without reading any row.
+// It stops without error once all the tables are estimated, Read returns no records then.
+func (s *Source) runDryRun(ctx context.Context) error {
+	client, _ := s.readClient()
+	estimator, ok := client.(queryEstimator)
+	if !ok {
+		return errors.New("BigQuery client can't dry run queries")
+	}
+
+	tables, err := s.getTables(ctx)
+	if err != nil {
+		sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("error while getting tables")
+		return err
+	}
+
+	var total int64
+	for _, tableID := range tables {
+		query, params, err := s.rowQuery(ctx, "", "", tableID, "", true, 0)
+		if err != nil {
+			return err
+		}
+		bytes, err := estimator.Estimate(ctx, s, query, params...)
+		if err != nil {
+			sdk.Logger(ctx).Error().Str("err", err.Error()).Str("tableID", tableID).Msg("Error while dry running query")
+			return fmt.Errorf("error while estimating table %s: %w", tableID, classifyError(err))
+		}
+		total += bytes
+		// LIMIT doesn't reduce the bytes processed, so every page of the snapshot processes about as much
+		sdk.Logger(ctx).Info().Str("tableID", tableID).Int64("bytesProcessed", bytes).
+			Msg("dry run estimate of the bytes processed by every query of the table")
+	}
+	sdk.Logger(ctx).Info().Int("tables", len(tables)).Int64("bytesProcessed", total).Msg("dry run done")
+	return nil
+}
@@ -0,0 +1,117 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googlesource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/bigquery"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	googlebigquery "github.com/neha-Gupta1/conduit-connector-bigquery"
+)
+
+// endOffset returns the offset of the configured end position for the table. It is parsed with the
+// type of the incrementing column once per table, tables discovered after start included.
+func (s *Source) endOffset(ctx context.Context, tableID string) (string, error) {
+	if cached, ok := s.endOffsets.Load(tableID); ok {
+		return cached.(string), nil
+	}
+
+	client, _ := s.readClient()
+	metadataClient, ok := client.(tableMetadataClient)
+	if !ok {
+		return "", errors.New("BigQuery client can't fetch table metadata")
+	}
+	field, err := s.incrementField(ctx, metadataClient, tableID)
+	if err != nil {
+		return "", err
+	}
+	end, err := parsePosition(field, s.sourceConfig.Config.EndPosition)
+	if err == nil {
+		var offset string
+		offset, err = s.positionOffset(ctx, field, end)
+		if err == nil {
+			s.endOffsets.Store(tableID, offset)
+			return offset, nil
+		}
+	}
+	return "", fmt.Errorf("invalid %s for column %s of table %s: %w", googlebigquery.ConfigEndPosition, field.Name, tableID, err)
+}
+
+// endCondition returns the condition selecting the rows up to the end position, if configured
+func (s *Source) endCondition(ctx context.Context, tableID string) (string, []bigquery.QueryParameter, error) {
+	if len(s.sourceConfig.Config.EndPosition) == 0 {
+		return "", nil, nil
+	}
+	offset, err := s.endOffset(ctx, tableID)
+	if err != nil {
+		return "", nil, err
+	}
+	value, param := offsetParameter("end", offset)
+	return "`" + s.incrementColNames(tableID)[0] + "` <= " + value, []bigquery.QueryParameter{param}, nil
+}
+
+// endReached reports if the table was read up to the end position and isn't queried anymore
+func (s *Source) endReached(tableID string) bool {
+	_, ok := s.tablesEnded.Load(tableID)
+	return ok
+}
+
+// markEndReached records that the table was read till the end. With an end position no rows are
+// left to read then.
+func (s *Source) markEndReached(ctx context.Context, tableID string) {
+	if len(s.sourceConfig.Config.EndPosition) == 0 {
+		return
+	}
+	if _, ended := s.tablesEnded.LoadOrStore(tableID, true); !ended {
+		sdk.Logger(ctx).Info().Str("tableID", tableID).Str("endPosition", s.sourceConfig.Config.EndPosition).
+			Msg("table read up to the end position")
+	}
+}
+
+// endPositionReached reports if all the tables were read up to the end position, polling is stopped then
+func (s *Source) endPositionReached(ctx context.Context) (bool, error) {
+	if len(s.sourceConfig.Config.EndPosition) == 0 {
+		return false, nil
+	}
+	tables, err := s.getTables(ctx)
+	if err != nil || len(tables) == 0 {
+		return false, err
+	}
+	for _, tableID := range tables {
+		if !s.endReached(tableID) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// waitAfterEnd stops polling once all the tables were read up to the end position. The iterator
+// waits for the source to stop then, the records left in the buffer are still read.
+func (s *Source) waitAfterEnd(ctx context.Context) error {
+	reached, err := s.endPositionReached(ctx)
+	if err != nil {
+		sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("error while getting tables")
+		return classifyError(err)
+	}
+	if !reached {
+		return nil
+	}
+	sdk.Logger(ctx).Info().Str("endPosition", s.sourceConfig.Config.EndPosition).
+		Msg("all tables read up to the end position, polling stopped")
+	return s.waitStopped(ctx)
+}
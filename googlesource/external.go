@@ -0,0 +1,49 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googlesource
+
+import (
+	"context"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+// externalMetadata reports if the metadata is of an external table, whose data is stored outside of
+// BigQuery, eg. in files on GCS. External tables have no partition pseudo columns nor change history.
+func externalMetadata(md *bigquery.TableMetadata) bool {
+	return md.Type == bigquery.ExternalTable || md.ExternalDataConfig != nil
+}
+
+// externalTable reports if the table is an external table
+func (s *Source) externalTable(ctx context.Context, tableID string) bool {
+	return s.tablePartitioning(ctx, tableID).external
+}
+
+// pseudoColumn reports if the column is a partition pseudo column like _PARTITIONTIME
+func pseudoColumn(column string) bool {
+	return strings.HasPrefix(strings.ToUpper(column), "_PARTITION")
+}
+
+// warnExternal logs once per table and option that the option isn't supported by the external table
+// and what is done instead
+func (s *Source) warnExternal(ctx context.Context, tableID, option, fallback string) {
+	if _, warned := s.externalWarnings.LoadOrStore(tableID+"#"+option, true); warned {
+		return
+	}
+	sdk.Logger(ctx).Warn().Str("tableID", tableID).Str("option", option).
+		Msgf("%s isn't supported by external tables, %s", option, fallback)
+}
@@ -17,37 +17,81 @@ package googlesource
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/gob"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
 	sdk "github.com/conduitio/conduit-connector-sdk"
 	googlebigquery "github.com/neha-Gupta1/conduit-connector-bigquery"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
 
+// timestampOffsetLayout is the layout of TIMESTAMP offsets compared in the WHERE clause
+const timestampOffsetLayout = "2006-01-02 15:04:05.999999-07:00"
+
+const (
+	// MetadataTable is a Record.Metadata key for the table the record was read from
+	MetadataTable = "bigquery.table"
+	// MetadataDataset is a Record.Metadata key for the dataset the record was read from
+	MetadataDataset = "bigquery.dataset"
+	// MetadataProject is a Record.Metadata key for the project the record was read from
+	MetadataProject = "bigquery.project"
+	// MetadataCollection is the OpenCDC Record.Metadata key for the collection (table) the record belongs to
+	MetadataCollection = "opencdc.collection"
+	// MetadataSchema is a Record.Metadata key for the BigQuery schema of the payload, encoded as JSON
+	// the same way as by `bq show --schema`
+	MetadataSchema = "bigquery.schema"
+	// MetadataHeartbeat is a Record.Metadata key set to true on heartbeat records, which only carry the position
+	MetadataHeartbeat = googlebigquery.MetadataHeartbeat
+	// MetadataConversionError is a Record.Metadata key for the error of a row which can't be converted,
+	// set on the dead-letter records emitted with onConversionError dlq
+	MetadataConversionError = "bigquery.conversionError"
+)
+
 // clientFactory provides function to create BigQuery Client
 type clientFactory interface {
 	Client() (*bigquery.Client, error)
 }
 
 type client struct {
-	ctx       context.Context
-	projectID string
-	opts      []option.ClientOption
+	ctx         context.Context
+	projectID   string
+	opts        []option.ClientOption
+	storageRead bool // storageRead streams query results using the BigQuery Storage Read API
 }
 
 func (client *client) Client() (*bigquery.Client, error) {
-	return bigquery.NewClient(client.ctx, client.projectID, client.opts...)
+	bqClient, err := bigquery.NewClient(client.ctx, client.projectID, client.opts...)
+	if err != nil {
+		return nil, err
+	}
+	if client.storageRead {
+		if err := bqClient.EnableStorageReadClient(client.ctx, client.opts...); err != nil {
+			bqClient.Close()
+			return nil, fmt.Errorf("error while enabling storage read client: %w", err)
+		}
+	}
+	return bqClient, nil
 }
 
 type bqClient interface {
-	Query(s *Source, query string) (it rowIterator, err error)
+	Query(ctx context.Context, s *Source, query string, params ...bigquery.QueryParameter) (it rowIterator, err error)
+	Tables(ctx context.Context, s *Source) (tableIDs []string, err error)
 	Close() error
 }
 
@@ -55,36 +99,121 @@ type bqClientStruct struct {
 	client *bigquery.Client
 }
 
-func (bq bqClientStruct) Query(s *Source, query string) (it rowIterator, err error) {
-	ctx := s.ctx
-	q := bq.client.Query(query)
-	sdk.Logger(ctx).Trace().Str("q ", q.Q)
-	q.Location = s.sourceConfig.Config.Location
-
-	job, err := q.Run(ctx)
+func (bq bqClientStruct) Query(ctx context.Context, s *Source, query string, params ...bigquery.QueryParameter) (it rowIterator, err error) {
+	q := s.newQuery(bq.client, query, params)
+	job, err := s.runJob(ctx, q)
 	if err != nil {
-		sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("Error while running the job")
 		return it, err
 	}
 
-	status, err := job.Wait(ctx)
+	bqIter, err := job.Read(ctx)
 	if err != nil {
-		sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("Error while running job")
+		sdk.Logger(ctx).Error().Str("err", err.Error()).Str("jobID", job.ID()).Msg("Error while running job")
 		return it, err
 	}
+	it = rowIter{it: bqIter}
+	return
+}
+
+// runJob runs the query job and waits for it to finish
+func (s *Source) runJob(ctx context.Context, q *bigquery.Query) (*bigquery.Job, error) {
+	sdk.Logger(ctx).Debug().Str("query", q.Q).Str("params", formatParams(q.Parameters)).Msg("running query")
+
+	// the job timeout bounds running the job, the rows are read afterwards
+	jobCtx, cancel := s.jobContext(ctx)
+	defer cancel()
+	job, err := q.Run(jobCtx)
+	if err != nil {
+		if jobTimedOut(ctx, jobCtx) {
+			err = fmt.Errorf("%w: job wasn't started within %s: %w", ErrJobTimeout, s.sourceConfig.Config.JobTimeout, err)
+		}
+		sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("Error while running the job")
+		return nil, err
+	}
+	sdk.Logger(ctx).Debug().Str("jobID", job.ID()).Str("location", job.Location()).Msg("query job started")
+
+	status, err := s.waitJob(ctx, jobCtx, job)
+	if err != nil {
+		sdk.Logger(ctx).Error().Str("err", err.Error()).Str("jobID", job.ID()).Msg("Error while running job")
+		return nil, err
+	}
 
 	if err := status.Err(); err != nil {
-		sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("Error while running job")
-		return it, err
+		sdk.Logger(ctx).Error().Str("err", err.Error()).Str("jobID", job.ID()).Msg("Error while running job")
+		return nil, err
+	}
+	if stats := status.Statistics; stats != nil {
+		s.addBytesScanned(stats.TotalBytesProcessed)
 	}
+	return job, nil
+}
 
-	bqIter, err := job.Read(ctx)
+// newQuery creates the query job running in the dataset location with the configured labels, limit
+// of bytes billed, query cache usage and priority
+func (s *Source) newQuery(client *bigquery.Client, query string, params []bigquery.QueryParameter) *bigquery.Query {
+	q := client.Query(query)
+	q.Parameters = params
+	q.Location = s.sourceConfig.Config.Location
+	q.Labels = s.sourceConfig.Config.QueryLabels
+	q.MaxBytesBilled = s.sourceConfig.Config.MaxBytesBilled
+	q.DisableQueryCache = !s.sourceConfig.Config.UseQueryCache
+	q.Priority = bigquery.InteractivePriority
+	if s.sourceConfig.Config.QueryPriority == googlebigquery.QueryPriorityBatch {
+		q.Priority = bigquery.BatchPriority
+	}
+	return q
+}
+
+// formatParams formats the query parameters as @name=value list, eg. @offset0=5, @offset1=2022-01-02
+func formatParams(params []bigquery.QueryParameter) string {
+	formatted := make([]string, 0, len(params))
+	for _, param := range params {
+		formatted = append(formatted, fmt.Sprintf("@%s=%v", param.Name, param.Value))
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// Tables lists the IDs of all the tables in the dataset
+func (bq bqClientStruct) Tables(ctx context.Context, s *Source) (tableIDs []string, err error) {
+	it := bq.client.Dataset(s.sourceConfig.Config.DatasetID).Tables(ctx)
+	for {
+		table, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("Error while listing tables")
+			return nil, err
+		}
+		tableIDs = append(tableIDs, table.TableID)
+	}
+	return tableIDs, nil
+}
+
+// Estimate dry runs the query and returns the number of bytes it would process
+func (bq bqClientStruct) Estimate(ctx context.Context, s *Source, query string, params ...bigquery.QueryParameter) (int64, error) {
+	q := s.newQuery(bq.client, query, params)
+	q.DryRun = true
+	sdk.Logger(ctx).Debug().Str("query", q.Q).Str("params", formatParams(params)).Msg("dry running query")
+
+	job, err := q.Run(ctx)
 	if err != nil {
-		sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("Error while running job")
-		return it, err
+		return 0, err
 	}
-	it = rowIter{it: bqIter}
-	return
+	// dry runs aren't executed, so their final status is returned right away
+	status := job.LastStatus()
+	if err := status.Err(); err != nil {
+		return 0, err
+	}
+	if status.Statistics == nil {
+		return 0, nil
+	}
+	return status.Statistics.TotalBytesProcessed, nil
+}
+
+// TableMetadata fetches the metadata of the table
+func (bq bqClientStruct) TableMetadata(ctx context.Context, s *Source, tableID string) (*bigquery.TableMetadata, error) {
+	return bq.client.Dataset(s.sourceConfig.Config.DatasetID).Table(tableID).Metadata(ctx)
 }
 
 func (bq bqClientStruct) Close() error {
@@ -92,70 +221,369 @@ func (bq bqClientStruct) Close() error {
 }
 
 // checkInitialPos helps in creating the query to fetch data from endpoint
-func (s *Source) checkInitialPos() (firstSync, userDefinedOffset, userDefinedKey bool) {
-	// if its the firstSync no offset is applied
-	if s.getPosition() == "" {
+func (s *Source) checkInitialPos(tableID, positionKey string) (firstSync, userDefinedOffset, userDefinedKey bool) {
+	// if its the firstSync no offset is applied. Tables whose snapshot is done are read incrementally,
+	// even without offset as they were empty.
+	if s.getPosition(positionKey) == "" && !s.snapshotDone(tableID) {
 		firstSync = true
 	}
 
-	// if incrementColName set - we orderBy the provided column name
-	if len(s.sourceConfig.Config.IncrementColName) > 0 {
+	// if incrementColNames set - we orderBy the provided column names
+	if len(s.incrementColNames(tableID)) > 0 {
 		userDefinedOffset = true
 	}
 
 	// if primaryColName set - we orderBy the provided column name
-	if len(s.sourceConfig.Config.PrimaryKeyColName) > 0 {
+	if len(s.sourceConfig.Config.PrimaryKeyColNames) > 0 {
 		userDefinedKey = true
 	}
 
 	return
 }
 
-func (s *Source) getPosition() string {
+// incrementColNames returns the incrementing columns of the table. Falls back to the default columns
+// if no column is configured specifically for the table. Tables without incrementing column are
+// paginated by their primary key columns so BigQuery doesn't rescan the skipped rows of an OFFSET.
+func (s *Source) incrementColNames(tableID string) []string {
+	if columnNames, ok := s.sourceConfig.Config.TableIncrementColNames[tableID]; ok {
+		return columnNames
+	}
+	if len(s.sourceConfig.Config.IncrementColNames) > 0 {
+		return s.sourceConfig.Config.IncrementColNames
+	}
+	return s.sourceConfig.Config.PrimaryKeyColNames
+}
+
+func (s *Source) setPosition(tableID, offset string) {
 	s.position.lock.Lock()
 	defer s.position.lock.Unlock()
-	return s.position.positions
+	s.position.positions[tableID] = offset
 }
 
-// ReadGoogleRow fetches data from endpoint. It creates sdk.record and puts it in response channel
-func (s *Source) ReadGoogleRow(ctx context.Context) (err error) {
-	sdk.Logger(ctx).Trace().Msg("Inside read google row")
-	var userDefinedOffset, userDefinedKey, firstSync bool
+func (s *Source) getPosition(tableID string) string {
+	s.position.lock.Lock()
+	defer s.position.lock.Unlock()
+	return s.position.positions[tableID]
+}
+
+// snapshotDone reports if the snapshot of the table was read till the end
+func (s *Source) snapshotDone(tableID string) bool {
+	s.position.lock.Lock()
+	defer s.position.lock.Unlock()
+	return s.position.snapshotsDone[tableID]
+}
 
-	offset := s.getPosition()
-	tableID := s.sourceConfig.Config.TableID
+// markSnapshotDone remembers that the snapshot of the table was read till the end. It is written to
+// the position of the next record, so a restart doesn't snapshot the table again even when it was
+// empty and no record of it was read since.
+func (s *Source) markSnapshotDone(tableID string) {
+	s.position.lock.Lock()
+	defer s.position.lock.Unlock()
+	if s.position.snapshotsDone == nil {
+		s.position.snapshotsDone = make(map[string]bool)
+	}
+	s.position.snapshotsDone[tableID] = true
+}
 
-	firstSync, userDefinedOffset, userDefinedKey = s.checkInitialPos()
-	lastRow := false
+// tableRead describes which rows of a table are read and where their offset is stored
+type tableRead struct {
+	positionKey string // positionKey is the key the offset is stored under in the position
+	partition   string // partition is the condition selecting the rows of a read stream
+	snapshot    bool   // snapshot forces the records to be emitted as snapshot
+}
 
-	for {
-		// Keep on reading till end of table
-		sdk.Logger(ctx).Trace().Str("tableID", tableID).Msg("inside read google row infinite for loop")
-		if lastRow {
-			sdk.Logger(ctx).Trace().Str("tableID", tableID).Msg("Its the last row. Done processing table")
-			break
+// tableMetadataClient fetches the metadata of tables
+type tableMetadataClient interface {
+	TableMetadata(ctx context.Context, s *Source, tableID string) (*bigquery.TableMetadata, error)
+}
+
+// validateTables checks that the configured tables exist and hold the incrementing and primary key
+// columns, so a misspelled table or column fails the start instead of never producing records or
+// failing with a cryptic query error. All the missing tables are listed in the error.
+func (s *Source) validateTables(ctx context.Context, client tableMetadataClient) error {
+	config := s.sourceConfig.Config
+	if config.SkipTableValidation || len(config.Query) > 0 {
+		return nil
+	}
+
+	var missing []string
+	for _, tableID := range config.TableIDs {
+		md, err := client.TableMetadata(ctx, s, tableID)
+		if notFound(err) {
+			missing = append(missing, tableID)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error while fetching metadata of table %s: %w", tableID, err)
+		}
+		if externalMetadata(md) && len(md.Schema) == 0 {
+			// the schema of external tables can be detected when they are queried
+			sdk.Logger(ctx).Warn().Str("tableID", tableID).Msg("external table has no schema, skipping the validation of its columns")
+			continue
+		}
+		if err := s.validateColumns(tableID, md.Schema); err != nil {
+			return err
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: tables %s not found in dataset %s.%s, check tableID or set %s to skip this check",
+			ErrTableNotFound, strings.Join(missing, ", "), config.ProjectID, config.DatasetID, googlebigquery.ConfigSkipTableValidation)
+	}
+	return nil
+}
+
+// orderableTypes are the column types rows can be ordered and paginated by
+var orderableTypes = map[bigquery.FieldType]bool{
+	bigquery.IntegerFieldType:    true,
+	bigquery.FloatFieldType:      true,
+	bigquery.NumericFieldType:    true,
+	bigquery.BigNumericFieldType: true,
+	bigquery.BooleanFieldType:    true,
+	bigquery.StringFieldType:     true,
+	bigquery.TimestampFieldType:  true,
+	bigquery.DateFieldType:       true,
+	bigquery.TimeFieldType:       true,
+	bigquery.DateTimeFieldType:   true,
+}
+
+// validateColumns checks that the incrementing and primary key columns are in the schema of the
+// table and that the rows can be ordered by the incrementing columns
+func (s *Source) validateColumns(tableID string, schema bigquery.Schema) error {
+	fields := make(map[string]*bigquery.FieldSchema, len(schema))
+	for _, field := range schema {
+		fields[field.Name] = field
+	}
+
+	for _, column := range s.sourceConfig.Config.PrimaryKeyColNames {
+		if _, ok := fields[column]; !ok {
+			return fmt.Errorf("%w: primary key column %s not found in table %s", ErrColumnMissing, column, tableID)
+		}
+	}
+	for _, column := range s.incrementColNames(tableID) {
+		field, ok := fields[column]
+		if !ok {
+			return fmt.Errorf("%w: incrementing column %s not found in table %s", ErrColumnMissing, column, tableID)
+		}
+		if field.Repeated || !orderableTypes[field.Type] {
+			fieldType := string(field.Type)
+			if field.Repeated {
+				fieldType = "repeated " + fieldType
+			}
+			return fmt.Errorf("incrementing column %s of table %s has type %s, which rows can't be ordered by", column, tableID, fieldType)
+		}
+	}
+	for _, column := range s.sourceConfig.Config.OrderBy {
+		if _, ok := fields[column]; !ok {
+			return fmt.Errorf("%w: order by column %s not found in table %s", ErrColumnMissing, column, tableID)
+		}
+	}
+	for _, column := range s.sourceConfig.Config.KeyColumns {
+		if _, ok := fields[column]; !ok {
+			return fmt.Errorf("%w: key column %s not found in table %s", ErrColumnMissing, column, tableID)
+		}
+	}
+	return nil
+}
+
+// ErrTableNotFound is returned when a configured table doesn't exist at start, or disappeared while
+// it was synced, eg. because it was deleted or renamed.
+var ErrTableNotFound = errors.New("table not found")
+
+// tableNotFoundError is the error of a query on a table which doesn't exist. It is ErrTableNotFound
+// and wraps the error returned by BigQuery.
+type tableNotFoundError struct {
+	tableID string
+	err     error
+}
+
+func (e tableNotFoundError) Error() string {
+	return fmt.Sprintf("%s: %s, it was deleted or renamed: %s", ErrTableNotFound, e.tableID, e.err)
+}
+
+func (e tableNotFoundError) Unwrap() error {
+	return e.err
+}
+
+func (e tableNotFoundError) Is(target error) bool {
+	return target == ErrTableNotFound
+}
+
+// notFound reports if BigQuery rejected the request because the table or dataset doesn't exist
+func notFound(err error) bool {
+	var bqErr *bigquery.Error
+	if errors.As(err, &bqErr) {
+		return bqErr.Reason == "notFound"
+	}
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
+
+// ReadGoogleRow fetches data of a table from endpoint. It creates sdk.record and puts it in response
+// channel. Tables which disappeared while they were synced are reported with ErrTableNotFound, tables
+// discovered in the dataset are skipped instead as they are dropped from the next listing.
+func (s *Source) ReadGoogleRow(ctx context.Context, tableID string) error {
+	err := s.readGoogleRow(ctx, tableID)
+	if err == nil || errors.Is(err, ErrTableNotFound) || !notFound(err) {
+		return err
+	}
+	if len(s.sourceConfig.Config.TableIDs) == 0 && len(s.sourceConfig.Config.Query) == 0 {
+		sdk.Logger(ctx).Warn().Str("err", err.Error()).Str("tableID", tableID).Msg("discovered table not found, skipping it")
+		return nil
+	}
+	return tableNotFoundError{tableID: tableID, err: err}
+}
+
+func (s *Source) readGoogleRow(ctx context.Context, tableID string) (err error) {
+	if s.changeHistory(ctx, tableID) {
+		return s.readChangeHistory(ctx, tableID)
+	}
+	if s.endReached(tableID) {
+		return nil
+	}
+	if err := s.seedWatermark(ctx, tableID); err != nil {
+		return err
+	}
+	if err := s.readTable(ctx, tableID, false); err != nil {
+		return err
+	}
+	return s.detectDeletes(ctx, tableID)
+}
+
+// seedWatermark sets the offset of a table without position to its greatest incrementing column
+// value when the snapshot is skipped, so only rows added afterwards are read. The watermark is only
+// queried once, tables which were empty are read from their first row on the next polls.
+func (s *Source) seedWatermark(ctx context.Context, tableID string) error {
+	if s.sourceConfig.Config.Mode != googlebigquery.ModeCDC || len(s.getPosition(tableID)) > 0 {
+		return nil
+	}
+	if _, seeded := s.seeded.LoadOrStore(tableID, true); seeded {
+		return nil
+	}
+
+	columnNames := s.incrementColNames(tableID)
+	if len(columnNames) == 0 {
+		return fmt.Errorf("no incrementing or primary key column to order table %s by", tableID)
+	}
+	quoted := make([]string, 0, len(columnNames))
+	for _, column := range columnNames {
+		quoted = append(quoted, "`"+column+"`")
+	}
+	requiredPartitions, err := s.requiredPartitionCondition(ctx, tableID, columnNames, false)
+	if err != nil {
+		return err
+	}
+	query := "SELECT " + strings.Join(quoted, ", ") + " FROM " + s.fromClause(tableID) + " "
+	if where := whereClause(requiredPartitions, s.filterCondition(ctx, tableID)); len(where) > 0 {
+		query += where + " "
+	}
+	query += "ORDER BY " + strings.Join(quoted, " DESC, ") + " DESC LIMIT 1"
+
+	it, err := s.query(ctx, query)
+	if err != nil {
+		s.seeded.Delete(tableID)
+		return fmt.Errorf("error while querying the watermark of table %s: %w", tableID, err)
+	}
+	var row []bigquery.Value
+	err = it.Next(&row)
+	if err == iterator.Done {
+		sdk.Logger(ctx).Trace().Str("tableID", tableID).Msg("table is empty, reading it from the first row")
+		return nil
+	}
+	if err != nil {
+		s.seeded.Delete(tableID)
+		return fmt.Errorf("error while querying the watermark of table %s: %w", tableID, err)
+	}
+
+	offsets := make([]string, len(row))
+	for i, value := range row {
+		field := it.Schema()[i]
+		converted, err := s.convertValue(ctx, field, value)
+		if err != nil {
+			return fmt.Errorf("error while converting the watermark of table %s: %w", tableID, err)
 		}
+		offsets[i] = formatOffset(field, value, converted)
+	}
+	s.setPosition(tableID, joinOffsets(offsets))
+	return nil
+}
+
+// readTable reads the rows of the table after its offset. snapshot forces the records to be
+// emitted as snapshot.
+func (s *Source) readTable(ctx context.Context, tableID string, snapshot bool) error {
+	if s.streamSnapshot(tableID) {
+		return s.readStreams(ctx, tableID)
+	}
+	return s.readRows(ctx, tableID, tableRead{positionKey: tableID, snapshot: snapshot})
+}
+
+// readRows reads the rows of the table selected by read till the end of the table
+func (s *Source) readRows(ctx context.Context, tableID string, read tableRead) (err error) {
+	sdk.Logger(ctx).Trace().Str("tableID", tableID).Str("position", read.positionKey).Msg("Inside read google row")
+	var userDefinedOffset, userDefinedKey, firstSync bool
+
+	offset := s.getPosition(read.positionKey)
+	incrementColNames := s.incrementColNames(tableID)
 
-		counter := 0
+	firstSync, userDefinedOffset, userDefinedKey = s.checkInitialPos(tableID, read.positionKey)
+	// rows read while the table is synced for the first time are part of the snapshot
+	// rows read before the snapshot of the table is done are part of it, also when it is resumed from an offset
+	snapshot := (s.sourceConfig.Config.Mode != googlebigquery.ModeCDC && !s.snapshotDone(tableID)) || read.snapshot
+	// the rows equal to the offset are read again and the ones read before are skipped
+	inclusive := userDefinedKey && s.inclusiveOffset(tableID)
+	boundary := s.watermarkBoundary(read.positionKey)
+	materialized := s.materializedSnapshot(tableID)
+	descending := s.sourceConfig.Config.IncrementOrder == googlebigquery.IncrementOrderDesc
+	// rows ordered by orderBy are paginated by the order columns of the last row read, the offset
+	// advances to the greatest offset read once the rows after it were read to the end
+	cursorKey := orderCursorPositionKey(read.positionKey)
+	var greatest greatestOffset
+	var orderIndexes []int
+
+	// pages are read till one isn't full. Every page queries one row more than it holds, the page is full
+	// when that row is returned. It is read again as first row of the next page.
+	for more := true; more; {
+		more = false
+		// snapshots read with the storage API or from a temporary table are not paginated, the whole
+		// table is streamed at once
+		unbounded := s.storageSnapshot(firstSync) || materialized
+		skip := 0
+		if inclusive && !firstSync && !s.customOrder() {
+			skip = boundary.skip(offset)
+		}
 		// iterator
-		it, err := s.getRowIterator(ctx, offset, tableID, firstSync)
+		it, err := s.getRowIterator(ctx, offset, s.getPosition(cursorKey), tableID, read.partition, firstSync, skip)
 		if err != nil {
 			sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("Error while running job")
 			return err
 		}
 
+		// the columns are resolved by name with the first row, as the schema of the table can change between polls
+		var offsetIndexes []int
+		var schemaJSON string
+		resolved := false
+		rows := 0
 		for {
+			// the rows left in the page are not read once the source is stopped
+			if s.iteratorStopped() {
+				return nil
+			}
 			var row []bigquery.Value
 
 			err := it.Next(&row)
 			schema := it.Schema()
 
 			if err == iterator.Done {
-				sdk.Logger(ctx).Trace().Str("counter", fmt.Sprintf("%d", counter)).Msg("iterator is done.")
-				if counter < googlebigquery.CounterLimit {
-					// if counter is smaller than the limit we have reached the end of
-					// iterator. And will break the for loop now.
-					lastRow = true
+				sdk.Logger(ctx).Trace().Int("rows", rows).Msg("iterator is done.")
+				if len(greatest.offset) > 0 {
+					// the records read carry the offset the pages started from, the next ones the greatest one
+					offset = greatest.offset
+					s.setPosition(read.positionKey, offset)
+					for key := range greatest.keys {
+						boundary.add(offset, []byte(key))
+					}
+				}
+				if s.customOrder() {
+					s.clearOrderCursor(read.positionKey)
 				}
 				break
 			}
@@ -163,218 +591,1393 @@ func (s *Source) ReadGoogleRow(ctx context.Context) (err error) {
 				sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("error while iterating")
 				return err
 			}
+			rows++
+			if !unbounded && rows > s.batchSize()+skip {
+				// the look-ahead row, the next page starts at it
+				more = true
+				break
+			}
+			if len(row) != len(schema) {
+				return fmt.Errorf("row of table %s has %d values, its schema %d columns", tableID, len(row), len(schema))
+			}
 
-			data := make(sdk.StructuredData)
-			var key string
-
-			for i, r := range row {
-				// handle dates
-				if schema[i].Type == bigquery.TimestampFieldType {
-					dateR := fmt.Sprintf("%v", r)
-					dateLocal, err := time.Parse("2006-01-02 15:04:05.999999 -0700 MST", dateR)
+			if !resolved {
+				offsetIndexes, err = s.resolveColumns(tableID, schema, incrementColNames, userDefinedOffset, userDefinedKey)
+				if err != nil {
+					sdk.Logger(ctx).Error().Str("err", err.Error()).Str("tableID", tableID).Msg("Error while resolving columns")
+					return err
+				}
+				schemaJSON = s.schemaMetadata(ctx, tableID, schema)
+				if s.customOrder() {
+					orderIndexes, err = columnIndexes(tableID, schema, s.orderColumns(tableID))
 					if err != nil {
-						sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("Error while converting to time format")
 						return err
 					}
-					r = dateLocal.Format("2006-01-02 15:04:05.999999 MST")
+					// the pages read before a restart are not read again, their greatest offset is kept
+					if stored := s.getPosition(greatestPositionKey(read.positionKey)); len(stored) > 0 && greatest.values == nil && userDefinedOffset {
+						if err := greatest.restore(schema, offsetIndexes, stored); err != nil {
+							// the offset advances to the greatest offset read after the restart, earlier rows can be read again
+							sdk.Logger(ctx).Warn().Str("err", err.Error()).Str("tableID", tableID).
+								Msg("greatest offset of the rows read before the restart can't be restored")
+						}
+					}
 				}
-				data[schema[i].Name] = r
+				resolved = true
+			}
+
+			data := make(sdk.StructuredData)
+			converted := make([]bigquery.Value, len(row))
 
-				// if we have found the user provided incremental key that would be used as offset
-				if userDefinedOffset {
-					if schema[i].Name == s.sourceConfig.Config.IncrementColName {
-						offset = fmt.Sprint(data[schema[i].Name])
-						offset = getType(schema[i].Type, offset)
+			// convErr is the first value which can't be converted, the row is rejected once its offset is read
+			var convErr error
+			for i, value := range row {
+				r, err := s.convertValue(ctx, schema[i], value)
+				if err != nil {
+					sdk.Logger(ctx).Error().Str("err", err.Error()).Str("column", schema[i].Name).Msg("Error while converting value")
+					err = fmt.Errorf("%w: column %s of table %s: %w", ErrConversion, schema[i].Name, tableID, err)
+					// the position can't advance past a row without offset
+					if s.failOnConversionError() || containsInt(offsetIndexes, i) || containsInt(orderIndexes, i) {
+						return err
 					}
-				} else {
-					offset, err = calcOffset(firstSync, offset)
-					if err != nil {
-						sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("Error marshalling key")
-						continue
+					if convErr == nil {
+						convErr = err
 					}
+					r = fmt.Sprint(value)
 				}
+				data[schema[i].Name] = r
+				converted[i] = r
+			}
 
-				// if we have found the user provided incremental key that would be used as offset
-				if userDefinedKey {
-					key = matchColumnName(schema[i].Name, s.sourceConfig.Config.PrimaryKeyColName, data)
+			if s.customOrder() {
+				cursor, err := orderCursor(tableID, schema, row, converted, orderIndexes)
+				if err != nil {
+					return err
 				}
+				s.setPosition(cursorKey, cursor)
 			}
 
-			buffer := &bytes.Buffer{}
-			if err := gob.NewEncoder(buffer).Encode(key); err != nil {
-				sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("Error marshalling key")
-				continue
+			// the user provided incremental columns are used as offset
+			rowOffset := offset
+			var offsetValues []bigquery.Value
+			// rows are created at the time of their incrementing column, the time they are read otherwise
+			createdAt, timed := time.Time{}, false
+			column, nullRow := nullOffset(schema, row, offsetIndexes)
+			nullRow = nullRow && userDefinedOffset
+			if nullRow {
+				// the row keeps the offset of the row read before it, NULL would break the next query
+				sdk.Logger(ctx).Warn().Str("tableID", tableID).Str("column", column).
+					Msg("incrementing column is NULL, the position isn't advanced")
+				s.markNullOffset(tableID)
+			} else if userDefinedOffset {
+				createdAt, timed = rowTime(schema[offsetIndexes[0]], row[offsetIndexes[0]])
+				offsets := make([]string, len(offsetIndexes))
+				offsetValues = make([]bigquery.Value, len(offsetIndexes))
+				for j, i := range offsetIndexes {
+					offsets[j] = formatOffset(schema[i], row[i], converted[i])
+					offsetValues[j] = row[i]
+				}
+				if !descending {
+					// rows read descending get older with every row, which doesn't tell how far the table is behind
+					s.lag.observe(tableID, schema[offsetIndexes[0]], row[offsetIndexes[0]])
+				}
+				rowOffset = joinOffsets(offsets)
+				if !s.customOrder() {
+					offset = rowOffset
+				}
+			}
+
+			// if user provided primary key columns, their values are used as key
+			var keyColumns []string
+			if userDefinedKey {
+				keyColumns = s.sourceConfig.Config.PrimaryKeyColNames
+			}
+
+			var emittedKey []byte
+			byteKey, keyErr := s.encodeKey(data, keyColumns)
+			if keyErr == nil {
+				emittedKey, keyErr = s.outputKey(data, byteKey)
+			}
+			if keyErr != nil {
+				sdk.Logger(ctx).Error().Str("err", keyErr.Error()).Msg("Error marshalling key")
+				keyErr = fmt.Errorf("%w: key of table %s: %w", ErrConversion, tableID, keyErr)
+				if s.failOnConversionError() {
+					return keyErr
+				}
+			}
+
+			// excluded columns are dropped once the offset and key are read from the row
+			for _, column := range s.sourceConfig.Config.ExcludeColumns {
+				removeColumn(data, strings.Split(column, "."))
 			}
-			byteKey := buffer.Bytes()
+			s.dropNulls(data)
 
-			counter++
 			firstSync = false
+			if keyErr != nil {
+				// rows without key can't be told apart, they aren't tracked by key
+				recPosition, err := s.writePosition(tableID, read.positionKey, offset, snapshot)
+				if err != nil {
+					sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("Error marshalling data")
+					continue
+				}
+				ok, err := s.rejectRow(ctx, tableID, recPosition, nil, data, keyErr)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return nil
+				}
+				continue
+			}
+			if userDefinedKey && s.sourceConfig.Config.DetectDeletes {
+				// rows created after the last scan for deleted rows can be deleted before the next one
+				s.knownKeys.add(tableID, byteKey)
+			}
+			if inclusive {
+				if boundary.seen(rowOffset, byteKey) {
+					continue
+				}
+				// rows with NULL incrementing columns aren't equal to the offset, they aren't read again by it
+				if !s.customOrder() && !nullRow {
+					boundary.add(offset, byteKey)
+				}
+			}
+			if offsetValues != nil && s.customOrder() {
+				greatest.observe(offsetValues, rowOffset, byteKey, descending)
+				s.setPosition(greatestPositionKey(read.positionKey), greatest.offset)
+			}
 
 			// keep the track of last rows fetched for each table.
 			// this helps in implementing incremental syncing.
-			recPosition, err := s.writePosition(offset)
+			recPosition, err := s.writePosition(tableID, read.positionKey, offset, snapshot)
 			if err != nil {
 				sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("Error marshalling data")
 				continue
 			}
+			if convErr != nil {
+				ok, err := s.rejectRow(ctx, tableID, recPosition, sdk.RawData(emittedKey), data, convErr)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return nil
+				}
+				continue
+			}
+
+			// rows are only read again when their incrementing column grew, so a known key is an update
+			seen := userDefinedKey && s.seenKeys.seen(tableID, byteKey, s.sourceConfig.Config.KeyCacheSize)
 
-			record := sdk.Record{
-				CreatedAt: time.Now().UTC(),
-				Payload:   data,
-				Key:       sdk.RawData(byteKey),
-				Position:  recPosition}
+			metadata := s.recordMetadata(tableID)
+			metadata[MetadataSchema] = schemaJSON
+			if !timed {
+				createdAt = s.clock()
+			}
+			metadata.SetCreatedAt(createdAt.UTC())
+			var record sdk.Record
+			switch {
+			case snapshot:
+				record = sdk.Util.Source.NewRecordSnapshot(recPosition, metadata, sdk.RawData(emittedKey), data)
+			case seen:
+				record = sdk.Util.Source.NewRecordUpdate(recPosition, metadata, sdk.RawData(emittedKey), nil, data)
+			default:
+				record = sdk.Util.Source.NewRecordCreate(recPosition, metadata, sdk.RawData(emittedKey), data)
+			}
 
-			// select statement to make sure channel was not closed by teardown stage
-			if s.iteratorClosed {
-				sdk.Logger(ctx).Trace().Msg("recieved closed channel")
+			if !s.emit(ctx, record) {
 				return nil
 			}
-			s.records <- record
 		}
 	}
-	return
+
+	sdk.Logger(ctx).Trace().Str("tableID", tableID).Msg("Done processing table")
+	if materialized {
+		s.dropMaterialized(ctx, s.materializedTableID(tableID))
+	}
+	if read.positionKey == tableID {
+		s.markSnapshotDone(tableID)
+		s.markEndReached(ctx, tableID)
+	}
+	return nil
 }
 
-// matchColumnName matches if the column name is equal to the user defined primary key column.
-// if it is so assign this column name data to key for the record
-func matchColumnName(dataName, columnName string, data sdk.StructuredData) (key string) {
-	if dataName == columnName {
-		key = fmt.Sprintf("%v", data[dataName])
+// emit sends the record to the records channel and counts it. Returns false once the source is
+// stopping, the context is done or maxRows records were emitted, the record isn't buffered then.
+func (s *Source) emit(ctx context.Context, record sdk.Record) bool {
+	if !s.countRecord() {
+		return false
 	}
-	return key
+	return s.send(ctx, record)
 }
 
-func calcOffset(firstSync bool, offset string) (string, error) {
-	// if user doesn't provide any incremental key we manually create offsets to pull data
-	if firstSync {
-		offset = "0"
+// send sends the record to the records channel without counting it against maxRows
+func (s *Source) send(ctx context.Context, record sdk.Record) bool {
+	if s.iteratorStopped() {
+		sdk.Logger(ctx).Trace().Msg("recieved closed channel")
+		return false
 	}
-	offsetInt, err := strconv.Atoi(offset)
-	if err != nil {
-		return offset, err
+	var stopping <-chan struct{}
+	if s.tomb != nil {
+		stopping = s.tomb.Dying()
+	}
+	select {
+	case s.records <- record:
+		atomic.AddUint64(&s.emitted, 1)
+		atomic.StoreInt64(&s.lastEmitted, s.clock().UnixNano())
+		return true
+	case <-s.iteratorClosed:
+		sdk.Logger(ctx).Trace().Msg("recieved closed channel")
+		return false
+	case <-stopping:
+		sdk.Logger(ctx).Trace().Msg("source is stopping, record isn't buffered")
+		return false
+	case <-ctx.Done():
+		sdk.Logger(ctx).Trace().Msg("context is done, record isn't buffered")
+		return false
 	}
-	offsetInt++
-	offset = fmt.Sprintf("%d", offsetInt)
-	return offset, err
 }
 
-func getType(fieldType bigquery.FieldType, offset string) string {
-	switch fieldType {
-	case bigquery.IntegerFieldType:
-		return offset
-	case bigquery.FloatFieldType:
-		return offset
-	case bigquery.NumericFieldType:
-		return offset
-	case bigquery.BigNumericFieldType:
-		return offset
-	case bigquery.TimeFieldType:
-		return fmt.Sprintf("'%s'", offset)
-
+// iteratorStopped reports if StopIterator was called since the source was opened
+func (s *Source) iteratorStopped() bool {
+	select {
+	case <-s.iteratorClosed:
+		return true
 	default:
-		return fmt.Sprintf("'%s'", offset)
+		return false
 	}
 }
 
-// writePosition prevents race condition happening while using map inside goroutine
-func (s *Source) writePosition(offset string) (recPosition []byte, err error) {
-	s.position.lock.Lock()
-	defer s.position.lock.Unlock()
-	s.position.positions = offset
-	return json.Marshal(&s.position.positions)
+// closeIterator closes the iteratorClosed channel, it can be called more than once
+func (s *Source) closeIterator() {
+	s.clientLock.Lock()
+	defer s.clientLock.Unlock()
+	if s.iteratorClosed != nil && !s.iteratorStopped() {
+		close(s.iteratorClosed)
+	}
 }
 
-type rowIter struct {
-	it *bigquery.RowIterator
-}
-type rowIterator interface {
-	Next(dst interface{}) error
-	Schema() bigquery.Schema
+// formatOffset returns the offset of the incrementing column value. The offset holds the SQL type
+// followed by the value, eg. "DATE 2022-01-02", so it can be compared without knowing the schema.
+// value is the value read from BigQuery and converted the value written to the record.
+func formatOffset(field *bigquery.FieldSchema, value, converted bigquery.Value) string {
+	offset := fmt.Sprint(converted)
+	if timestamp, ok := value.(time.Time); ok {
+		// offsets use a fixed layout whatever the configured timestamp format is
+		offset = timestamp.UTC().Format(timestampOffsetLayout)
+	}
+	return getType(field.Type) + " " + offset
 }
 
-func (iterator rowIter) Schema() bigquery.Schema {
-	return iterator.it.Schema
+// parseOffset splits the offset into its SQL type and value
+func parseOffset(offset string) (sqlType, value string) {
+	i := strings.Index(offset, " ")
+	if i < 0 {
+		return "STRING", offset
+	}
+	return offset[:i], offset[i+1:]
 }
 
-func (iterator rowIter) Next(dst interface{}) error {
-	return iterator.it.Next(dst)
+// joinOffsets combines the offsets of the incrementing columns. The offset of a single column is
+// kept as is, offsets of composite columns are stored as JSON array.
+func joinOffsets(offsets []string) string {
+	if len(offsets) == 1 {
+		return offsets[0]
+	}
+	joined, err := json.Marshal(offsets)
+	if err != nil {
+		// marshalling a list of strings doesn't fail
+		return ""
+	}
+	return string(joined)
 }
 
-// getRowIterator sync data for bigquery using bigquery client jobs
-func (s *Source) getRowIterator(ctx context.Context, offset string, tableID string, firstSync bool) (it rowIterator, err error) {
-	// check for config `IncrementColNames`. User can provide the column name which
-	// would be used as orderBy as well as incremental or offset value. Orderby is not mandatory though
-
-	var query string
-	if len(s.sourceConfig.Config.IncrementColName) > 0 {
-		columnName := s.sourceConfig.Config.IncrementColName
-		if firstSync {
-			query = "SELECT * FROM `" + s.sourceConfig.Config.ProjectID + "." + s.sourceConfig.Config.DatasetID + "." + tableID + "` " +
-				" ORDER BY " + columnName + " LIMIT " + strconv.Itoa(googlebigquery.CounterLimit)
-		} else {
-			query = "SELECT * FROM `" + s.sourceConfig.Config.ProjectID + "." + s.sourceConfig.Config.DatasetID + "." + tableID + "` WHERE " + columnName +
-				" > " + offset + " ORDER BY " + columnName + " LIMIT " + strconv.Itoa(googlebigquery.CounterLimit)
-		}
-	} else {
-		// add default value if none specified
-		if len(offset) == 0 {
-			offset = "0"
-		}
-		// if no incremental value provided using default offset which is created by incrementing a counter each time a row is sync.
-		query = "SELECT * FROM `" + s.sourceConfig.Config.ProjectID + "." + s.sourceConfig.Config.DatasetID + "." + tableID + "` " +
-			" LIMIT " + strconv.Itoa(googlebigquery.CounterLimit) + " OFFSET " + offset
+// splitOffsets returns the offsets of the incrementing columns combined by joinOffsets
+func splitOffsets(offset string) []string {
+	var offsets []string
+	if strings.HasPrefix(offset, "[") && json.Unmarshal([]byte(offset), &offsets) == nil {
+		return offsets
 	}
+	return []string{offset}
+}
 
-	return s.bqReadClient.Query(s, query)
+// offsetParameter returns the condition comparing the column with the offset and the query
+// parameter holding the offset value. The value is passed as parameter so values read from the
+// table can't change the query.
+func offsetParameter(name, offset string) (condition string, param bigquery.QueryParameter) {
+	sqlType, value := parseOffset(offset)
+	return fmt.Sprintf("CAST(@%s AS %s)", name, sqlType), bigquery.QueryParameter{Name: name, Value: value}
 }
 
-// Next returns the next record from the buffer.
-func (s *Source) Next(ctx context.Context) (sdk.Record, error) {
-	select {
-	case <-s.tomb.Dead():
-		return sdk.Record{}, s.tomb.Err()
-	case r := <-s.records:
-		return r, nil
-	case <-ctx.Done():
-		return sdk.Record{}, ctx.Err()
-	default:
-		return sdk.Record{}, sdk.ErrBackoffRetry
-	}
+// streamSnapshot reports if the snapshot of the table is split across parallel read streams. This
+// is the case till all the streams are done, even after a restart.
+func (s *Source) streamSnapshot(tableID string) bool {
+	return s.sourceConfig.Config.ReadStreams > 1 && s.getPosition(tableID) == "" &&
+		s.sourceConfig.Config.Mode != googlebigquery.ModeCDC && !s.snapshotDone(tableID)
 }
 
-// fetchPos unmarshal position
-func fetchPos(s *Source, pos sdk.Position) {
-	s.position.lock = new(sync.Mutex)
-	s.position.lock.Lock()
-	defer s.position.lock.Unlock()
-	s.position.positions = ""
+// streamPositionKey is the key the offset of a read stream is stored under in the position
+func streamPositionKey(tableID string, stream int) string {
+	return fmt.Sprintf("%s#stream%d", tableID, stream)
+}
 
-	err := json.Unmarshal(pos, &s.position.positions)
+// readStreams reads the snapshot of a table with ReadStreams parallel streams. Rows are assigned to
+// the streams by a hash of their primary key and each stream keeps its own offset, so a restart
+// resumes every stream where it stopped. Once all streams are done their offsets are merged into
+// the offset of the table which is used by the following polls. Every stream is a query scanning
+// the whole table, and the streams take the read slots of the tables, so at most MaxConcurrentReads
+// streams and tables are read at the same time.
+func (s *Source) readStreams(ctx context.Context, tableID string) error {
+	slots := s.readSlots
+	if slots != nil {
+		// the slot of the table is handed to its streams while they are read, so the table doesn't
+		// hold a slot while waiting for them
+		<-slots
+		defer func() {
+			slots <- struct{}{}
+		}()
+	}
+
+	streams := s.sourceConfig.Config.ReadStreams
+	errs := make(chan error, streams)
+	var wg sync.WaitGroup
+dispatch:
+	for i := 0; i < streams; i++ {
+		read := tableRead{
+			positionKey: streamPositionKey(tableID, i),
+			partition: fmt.Sprintf("MOD(ABS(FARM_FINGERPRINT(TO_JSON_STRING(%s))), %d) = %d",
+				s.primaryKeyExpression(), streams, i),
+			snapshot: true,
+		}
+		if slots != nil {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				break dispatch
+			}
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				if slots != nil {
+					<-slots
+				}
+				wg.Done()
+			}()
+			if err := s.readRows(ctx, tableID, read); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	if err := <-errs; err != nil {
+		return err
+	}
+	// streams which weren't started are read once the source is opened again
+	if s.iteratorStopped() || ctx.Err() != nil {
+		return nil
+	}
+	s.markSnapshotDone(tableID)
+	return s.mergeStreamPositions(ctx, tableID)
+}
+
+// primaryKeyExpression returns the SQL expression of the primary key. Composite keys are combined
+// into a STRUCT.
+func (s *Source) primaryKeyExpression() string {
+	columns := make([]string, 0, len(s.sourceConfig.Config.PrimaryKeyColNames))
+	for _, column := range s.sourceConfig.Config.PrimaryKeyColNames {
+		columns = append(columns, "`"+column+"`")
+	}
+	if len(columns) == 1 {
+		return columns[0]
+	}
+	return "STRUCT(" + strings.Join(columns, ", ") + ")"
+}
+
+// mergeStreamPositions sets the offset of the table to the greatest offset of its read streams
+// and removes the offsets of the streams.
+func (s *Source) mergeStreamPositions(ctx context.Context, tableID string) error {
+	var offsets []string
+	for i := 0; i < s.sourceConfig.Config.ReadStreams; i++ {
+		if offset := s.getPosition(streamPositionKey(tableID, i)); len(offset) > 0 {
+			offsets = append(offsets, offset)
+		}
+	}
+	if len(offsets) == 0 {
+		// table is empty, its rows are read from the first one on the next poll
+		return nil
+	}
+
+	// the greatest offset is computed by BigQuery as it knows how to compare all the column types.
+	// Offsets of composite columns are compared column by column.
+	columns := s.incrementColNames(tableID)
+	quoted := make([]string, 0, len(columns))
+	for _, column := range columns {
+		quoted = append(quoted, "`"+column+"`")
+	}
+	var structs []string
+	var params []bigquery.QueryParameter
+	for i, offset := range offsets {
+		values := splitOffsets(offset)
+		if len(values) != len(columns) {
+			return fmt.Errorf("offset %q doesn't match the incrementing columns %v", offset, columns)
+		}
+		var fields []string
+		for j, value := range values {
+			field, param := offsetParameter(fmt.Sprintf("offset%d_%d", i, j), value)
+			fields = append(fields, field+" AS "+quoted[j])
+			params = append(params, param)
+		}
+		structs = append(structs, "STRUCT("+strings.Join(fields, ", ")+")")
+	}
+	query := "SELECT * FROM UNNEST([" + strings.Join(structs, ", ") + "]) ORDER BY " +
+		strings.Join(quoted, " DESC, ") + " DESC LIMIT 1"
+	it, err := s.query(ctx, query, params...)
 	if err != nil {
-		sdk.Logger(s.ctx).Info().Msg("Could not get position. Will start with offset 0")
+		return fmt.Errorf("error while merging read stream offsets: %w", err)
+	}
+	var row []bigquery.Value
+	if err := it.Next(&row); err != nil {
+		return fmt.Errorf("error while merging read stream offsets: %w", err)
+	}
+	merged := make([]string, len(row))
+	for i, value := range row {
+		field := it.Schema()[i]
+		converted, err := s.convertValue(ctx, field, value)
+		if err != nil {
+			return fmt.Errorf("error while merging read stream offsets: %w", err)
+		}
+		merged[i] = formatOffset(field, value, converted)
+	}
+
+	s.position.lock.Lock()
+	defer s.position.lock.Unlock()
+	s.position.positions[tableID] = joinOffsets(merged)
+	for i := 0; i < s.sourceConfig.Config.ReadStreams; i++ {
+		delete(s.position.positions, streamPositionKey(tableID, i))
 	}
+	return nil
 }
 
-func (s *Source) runIterator() (err error) {
-	// Snapshot sync. Start were we left last
-	ctx := s.ctx
-	err = s.ReadGoogleRow(ctx)
+// removeColumn removes the column at the given path. Nested RECORD fields are addressed by their
+// path, eg. user.email, and are removed from every element of REPEATED records.
+func removeColumn(data map[string]interface{}, path []string) {
+	if len(path) == 1 {
+		delete(data, path[0])
+		return
+	}
+
+	switch nested := data[path[0]].(type) {
+	case map[string]interface{}:
+		removeColumn(nested, path[1:])
+	case []interface{}:
+		for _, element := range nested {
+			if record, ok := element.(map[string]interface{}); ok {
+				removeColumn(record, path[1:])
+			}
+		}
+	}
+}
+
+// recordMetadata returns the metadata identifying where the record of the table came from
+func (s *Source) recordMetadata(tableID string) sdk.Metadata {
+	return sdk.Metadata{
+		MetadataTable:      tableID,
+		MetadataDataset:    s.sourceConfig.Config.DatasetID,
+		MetadataProject:    s.sourceConfig.Config.ProjectID,
+		MetadataCollection: tableID,
+	}
+}
+
+// convertValue converts the value read from BigQuery to a stable representation based on the field type
+func (s *Source) convertValue(ctx context.Context, field *bigquery.FieldSchema, r bigquery.Value) (bigquery.Value, error) {
+	if field.Repeated {
+		return s.convertRepeated(ctx, field, r)
+	}
+
+	switch field.Type {
+	case bigquery.RecordFieldType:
+		if values, ok := r.([]bigquery.Value); ok {
+			return s.convertRecord(ctx, field.Schema, values)
+		}
+	case bigquery.NumericFieldType:
+		// NUMERIC has a scale of 9 digits
+		if rat, ok := r.(*big.Rat); ok {
+			return bigquery.NumericString(rat), nil
+		}
+	case bigquery.BigNumericFieldType:
+		// BIGNUMERIC has a scale of 38 digits
+		if rat, ok := r.(*big.Rat); ok {
+			return bigquery.BigNumericString(rat), nil
+		}
+	case bigquery.JSONFieldType:
+		if raw, ok := r.(string); ok && !s.sourceConfig.Config.JSONAsString {
+			var value interface{}
+			if err := json.Unmarshal([]byte(raw), &value); err != nil {
+				sdk.Logger(ctx).Warn().Str("err", err.Error()).Str("column", field.Name).Msg("Malformed JSON value, keeping raw string")
+				return raw, nil
+			}
+			return value, nil
+		}
+	case bigquery.BytesFieldType:
+		if value, ok := r.([]byte); ok {
+			if s.sourceConfig.Config.BytesEncoding == googlebigquery.BytesEncodingHex {
+				return hex.EncodeToString(value), nil
+			}
+			return base64.StdEncoding.EncodeToString(value), nil
+		}
+	case bigquery.TimestampFieldType:
+		var dateLocal time.Time
+		switch value := r.(type) {
+		case nil:
+			return nil, nil
+		case time.Time:
+			dateLocal = value
+		case string:
+			var err error
+			dateLocal, err = parseTimestamp(value)
+			if err != nil {
+				return nil, fmt.Errorf("error while converting to time format: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("unexpected timestamp value of type %T", r)
+		}
+		location := s.sourceConfig.Config.TimestampLocation
+		if location == nil {
+			location = time.UTC
+		}
+		dateLocal = dateLocal.In(location)
+
+		switch s.sourceConfig.Config.TimestampFormat {
+		case googlebigquery.TimestampFormatRFC3339:
+			return dateLocal.Format(time.RFC3339Nano), nil
+		case googlebigquery.TimestampFormatUnix:
+			return dateLocal.UnixMilli(), nil
+		case "":
+			return dateLocal.Format(googlebigquery.DefaultTimestampLayout), nil
+		default:
+			return dateLocal.Format(s.sourceConfig.Config.TimestampFormat), nil
+		}
+	case bigquery.GeographyFieldType:
+		// GEOGRAPHY values are read as WKT, eg. POINT(-122.35 47.62)
+		switch value := r.(type) {
+		case nil:
+			return nil, nil
+		case string:
+			return value, nil
+		default:
+			return nil, fmt.Errorf("unexpected geography value of type %T", r)
+		}
+	case bigquery.DateFieldType:
+		// civil.Date is formatted as YYYY-MM-DD
+		if date, ok := r.(civil.Date); ok {
+			return date.String(), nil
+		}
+	case bigquery.TimeFieldType:
+		if civilTime, ok := r.(civil.Time); ok {
+			return formatCivilTime(civilTime), nil
+		}
+	case bigquery.DateTimeFieldType:
+		if dateTime, ok := r.(civil.DateTime); ok {
+			return fmt.Sprintf("%s %s", dateTime.Date.String(), formatCivilTime(dateTime.Time)), nil
+		}
+	}
+	return r, nil
+}
+
+// convertRecord builds a nested structure out of the values of a RECORD column keyed by the field names
+func (s *Source) convertRecord(ctx context.Context, schema bigquery.Schema, values []bigquery.Value) (map[string]interface{}, error) {
+	if len(values) != len(schema) {
+		return nil, fmt.Errorf("record has %d values but schema has %d fields", len(values), len(schema))
+	}
+
+	record := make(map[string]interface{}, len(schema))
+	for i, field := range schema {
+		value, err := s.convertValue(ctx, field, values[i])
+		if err != nil {
+			return nil, fmt.Errorf("error while converting field %q: %w", field.Name, err)
+		}
+		record[field.Name] = value
+	}
+	return record, nil
+}
+
+// convertRepeated converts every element of a REPEATED column. BigQuery has no NULL arrays so a
+// missing value is returned as an empty array.
+func (s *Source) convertRepeated(ctx context.Context, field *bigquery.FieldSchema, r bigquery.Value) ([]interface{}, error) {
+	values, ok := r.([]bigquery.Value)
+	if r != nil && !ok {
+		return nil, fmt.Errorf("expected array for repeated column %q, got %T", field.Name, r)
+	}
+
+	element := *field
+	element.Repeated = false
+	list := make([]interface{}, 0, len(values))
+	for i, value := range values {
+		converted, err := s.convertValue(ctx, &element, value)
+		if err != nil {
+			return nil, fmt.Errorf("error while converting element %d: %w", i, err)
+		}
+		list = append(list, converted)
+	}
+	return list, nil
+}
+
+// timestampLayouts are the layouts tried when a TIMESTAMP value is received as string
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	"2006-01-02 15:04:05.999999999 MST",
+}
+
+// parseTimestamp parses a TIMESTAMP value received as string
+func parseTimestamp(value string) (time.Time, error) {
+	var err error
+	for _, layout := range timestampLayouts {
+		var timestamp time.Time
+		timestamp, err = time.Parse(layout, value)
+		if err == nil {
+			return timestamp, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// formatCivilTime formats the time as HH:MM:SS.ffffff. BigQuery stores microsecond precision so
+// the fraction is always written with six digits to keep offsets comparable.
+func formatCivilTime(t civil.Time) string {
+	return fmt.Sprintf("%02d:%02d:%02d.%06d", t.Hour, t.Minute, t.Second, t.Nanosecond/1000)
+}
+
+// encodeKey encodes the key built from the columns of the row with the configured key format. Rows
+// without key columns get an empty key.
+func (s *Source) encodeKey(data sdk.StructuredData, columns []string) ([]byte, error) {
+	if s.sourceConfig.Config.KeyFormat == googlebigquery.KeyFormatGob {
+		return gobKey(data, columns)
+	}
+	return jsonKey(data, columns)
+}
+
+// jsonKey encodes the value of a single column as JSON, and the values of composite keys as object
+// keyed by column. Object keys are sorted, so the encoding is deterministic.
+func jsonKey(data sdk.StructuredData, columns []string) ([]byte, error) {
+	switch len(columns) {
+	case 0:
+		return json.Marshal("")
+	case 1:
+		return json.Marshal(data[columns[0]])
+	}
+	key := make(map[string]interface{}, len(columns))
+	for _, column := range columns {
+		key[column] = data[column]
+	}
+	return json.Marshal(key)
+}
+
+// keyColumn is a column of a composite key encoded with gob
+type keyColumn struct {
+	Name  string
+	Value string
+}
+
+// gobKey encodes the key with gob. A single column is encoded as its value formatted as string.
+// Composite keys are encoded as the ordered list of their columns, so the encoding is deterministic
+// and rows only differing in one of the columns get different keys.
+func gobKey(data sdk.StructuredData, columns []string) ([]byte, error) {
+	var key interface{} = ""
+	switch {
+	case len(columns) == 1:
+		key = fmt.Sprintf("%v", data[columns[0]])
+	case len(columns) > 1:
+		composite := make([]keyColumn, 0, len(columns))
+		for _, column := range columns {
+			composite = append(composite, keyColumn{Name: column, Value: fmt.Sprintf("%v", data[column])})
+		}
+		key = composite
+	}
+
+	buffer := &bytes.Buffer{}
+	if err := gob.NewEncoder(buffer).Encode(key); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// recordKey returns the encoded primary key of the row
+func (s *Source) recordKey(data sdk.StructuredData) ([]byte, error) {
+	return s.encodeKey(data, s.sourceConfig.Config.PrimaryKeyColNames)
+}
+
+// outputKey returns the encoded key of the record. It is built from the configured key columns,
+// otherwise it is the encoded primary key. The data still needs to hold the excluded columns.
+func (s *Source) outputKey(data sdk.StructuredData, primaryKey []byte) ([]byte, error) {
+	if len(s.sourceConfig.Config.KeyColumns) == 0 {
+		return primaryKey, nil
+	}
+	return s.encodeKey(data, s.sourceConfig.Config.KeyColumns)
+}
+
+// getType returns the SQL type the offset of a column of the field type is cast to. Casting from
+// the string representation keeps NUMERIC values exact.
+func getType(fieldType bigquery.FieldType) string {
+	switch fieldType {
+	case bigquery.IntegerFieldType:
+		return "INT64"
+	case bigquery.FloatFieldType:
+		return "FLOAT64"
+	case bigquery.BooleanFieldType:
+		return "BOOL"
+	case bigquery.NumericFieldType:
+		return "NUMERIC"
+	case bigquery.BigNumericFieldType:
+		return "BIGNUMERIC"
+	case bigquery.TimeFieldType:
+		return "TIME"
+	case bigquery.DateFieldType:
+		return "DATE"
+	case bigquery.DateTimeFieldType:
+		return "DATETIME"
+	case bigquery.TimestampFieldType:
+		return "TIMESTAMP"
+
+	default:
+		return "STRING"
+	}
+}
+
+// writePosition prevents race condition happening while using map inside goroutine. The returned
+// position holds the offsets of all the tables so a restart resumes each table independently. The
+// offset is stored under positionKey, which differs from the table ID for read streams.
+func (s *Source) writePosition(tableID, positionKey, offset string, snapshot bool) (recPosition []byte, err error) {
+	s.position.lock.Lock()
+	defer s.position.lock.Unlock()
+	s.position.positions[positionKey] = offset
+	s.position.mode = PositionModeCDC
+	if snapshot {
+		s.position.mode = PositionModeSnapshot
+	}
+	return s.marshalPosition(tableID)
+}
+
+// marshalPosition returns the position holding the offsets of all the tables. The position lock
+// needs to be held.
+func (s *Source) marshalPosition(tableID string) ([]byte, error) {
+	var snapshotsDone []string
+	for doneTableID := range s.position.snapshotsDone {
+		snapshotsDone = append(snapshotsDone, doneTableID)
+	}
+	sort.Strings(snapshotsDone)
+	return json.Marshal(&Position{
+		Version:       PositionVersion,
+		Mode:          s.position.mode,
+		Table:         tableID,
+		Offsets:       s.position.positions,
+		SnapshotsDone: snapshotsDone,
+	})
+}
+
+type rowIter struct {
+	it *bigquery.RowIterator
+}
+type rowIterator interface {
+	Next(dst interface{}) error
+	Schema() bigquery.Schema
+}
+
+func (iterator rowIter) Schema() bigquery.Schema {
+	return iterator.it.Schema
+}
+
+func (iterator rowIter) Next(dst interface{}) error {
+	return iterator.it.Next(dst)
+}
+
+// getRowIterator sync data for bigquery using bigquery client jobs
+func (s *Source) getRowIterator(ctx context.Context, offset, cursor string, tableID string, partition string, firstSync bool, skip int) (it rowIterator, err error) {
+	query, params, err := s.rowQuery(ctx, offset, cursor, tableID, partition, firstSync, skip)
 	if err != nil {
+		return nil, err
+	}
+	if s.materializedSnapshot(tableID) {
+		return s.materialize(ctx, tableID, query, params...)
+	}
+	return s.query(ctx, query, params...)
+}
+
+// rowQuery returns the query reading the next page of rows of the table after the offset. The page is
+// enlarged by the skip rows equal to the offset which were already read. Rows ordered by orderBy
+// are read after the cursor, the order columns of the last row read.
+func (s *Source) rowQuery(ctx context.Context, offset, cursor string, tableID string, partition string, firstSync bool, skip int) (query string, params []bigquery.QueryParameter, err error) {
+	// check for config `IncrementColNames`. User can provide the column name for each table which
+	// would be used as orderBy as well as incremental or offset value. The primary key is used when
+	// no incrementing column is provided. Rows are ordered by `OrderBy` instead when configured.
+
+	filter := s.filterCondition(ctx, tableID)
+
+	columnNames := s.incrementColNames(tableID)
+	if len(columnNames) == 0 {
+		return "", nil, fmt.Errorf("no incrementing or primary key column to order table %s by", tableID)
+	}
+	descending := s.sourceConfig.Config.IncrementOrder == googlebigquery.IncrementOrderDesc
+	orderBy := s.orderByClause(tableID, columnNames)
+
+	// tables without offset are read from their first row, eg. empty tables whose snapshot is done
+	offsetUsed := !firstSync && len(offset) > 0
+	requiredPartitions, err := s.requiredPartitionCondition(ctx, tableID, columnNames, offsetUsed)
+	if err != nil {
+		return "", nil, err
+	}
+	end, endParams, err := s.endCondition(ctx, tableID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// rows are paginated by the last value read (keyset pagination), so every query only reads
+	// the rows after the previous page
+	var condition string
+	if !offsetUsed {
+		condition = s.nullOffsetCondition(tableID, columnNames)
+	} else {
+		condition, params, err = keysetCondition("offset", columnNames, offset, s.inclusiveOffset(tableID), descending)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	var after string
+	if s.customOrder() && len(cursor) > 0 {
+		var cursorParams []bigquery.QueryParameter
+		after, cursorParams, err = keysetCondition("cursor", s.orderColumns(tableID), cursor, false, false)
+		if err != nil {
+			return "", nil, err
+		}
+		params = append(params, cursorParams...)
+	}
+	query = "SELECT " + s.selectClause(tableID) + s.pseudoColumns(ctx, tableID) + " FROM " + s.fromClause(tableID) + s.sampleClause(ctx, tableID)
+	if where := whereClause(condition, after, end, partition, requiredPartitions, filter); len(where) > 0 {
+		query += " " + where
+	}
+	query += " ORDER BY " + orderBy + s.limitClause(tableID, firstSync, skip)
+	return query, append(params, endParams...), nil
+}
+
+// keysetCondition returns the condition selecting the rows after the offset, or from the offset on
+// when inclusive. Rows read descending are after the offset when they are smaller. BigQuery can't
+// compare tuples, so composite columns are compared lexicographically, eg. for (a, b):
+// (a > @offset0 OR (a = @offset0 AND b > @offset1)). The query parameters are named after name.
+// Column names are quoted, so reserved words and special characters can be used.
+func keysetCondition(name string, columnNames []string, offset string, inclusive, descending bool) (string, []bigquery.QueryParameter, error) {
+	after := " > "
+	if descending {
+		after = " < "
+	}
+	last := after
+	if inclusive {
+		last = strings.TrimSuffix(after, " ") + "= "
+	}
+	if len(columnNames) == 1 {
+		value, param := offsetParameter(name, offset)
+		return "`" + columnNames[0] + "`" + last + value, []bigquery.QueryParameter{param}, nil
+	}
+
+	offsets := splitOffsets(offset)
+	if len(offsets) != len(columnNames) {
+		return "", nil, fmt.Errorf("offset %q doesn't match the columns %v", offset, columnNames)
+	}
+	var params []bigquery.QueryParameter
+	var values []string
+	for i := range columnNames {
+		value, param := offsetParameter(fmt.Sprintf("%s%d", name, i), offsets[i])
+		values = append(values, value)
+		params = append(params, param)
+	}
+
+	var alternatives []string
+	for i := range columnNames {
+		var terms []string
+		for j := 0; j < i; j++ {
+			terms = append(terms, "`"+columnNames[j]+"` = "+values[j])
+		}
+		comparison := after
+		if i == len(columnNames)-1 {
+			comparison = last
+		}
+		terms = append(terms, "`"+columnNames[i]+"`"+comparison+values[i])
+		if len(terms) == 1 {
+			alternatives = append(alternatives, terms[0])
+		} else {
+			alternatives = append(alternatives, "("+strings.Join(terms, " AND ")+")")
+		}
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")", params, nil
+}
+
+// filterCondition returns the condition selecting the configured partitions and the user provided
+// filter, which is appended to the conditions as is
+func (s *Source) filterCondition(ctx context.Context, tableID string) string {
+	var conditions []string
+	if partitions := s.partitionCondition(ctx, tableID); len(partitions) > 0 {
+		conditions = append(conditions, partitions)
+	}
+	if len(s.sourceConfig.Config.Filter) > 0 {
+		conditions = append(conditions, "("+s.sourceConfig.Config.Filter+")")
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+// partitionCondition returns the condition selecting the rows of the configured partitions. The
+// partition field is compared to constant ranges, so BigQuery only scans the selected partitions.
+// External tables have no partition pseudo columns, all their rows are read then.
+func (s *Source) partitionCondition(ctx context.Context, tableID string) string {
+	partitions := s.sourceConfig.Config.Partitions
+	if len(partitions) == 0 {
+		return ""
+	}
+	field := s.sourceConfig.Config.PartitionField
+	if len(field) == 0 {
+		field = googlebigquery.DefaultPartitionField
+	}
+	if pseudoColumn(field) && s.externalTable(ctx, tableID) {
+		s.warnExternal(ctx, tableID, googlebigquery.ConfigPartitions, "reading all the rows of the table")
+		return ""
+	}
+
+	ranges := make([]string, 0, len(partitions))
+	for _, partition := range partitions {
+		ranges = append(ranges, fmt.Sprintf("(%s >= '%s' AND %s < '%s')", field, partition.Start, field, partition.End))
+	}
+	return "(" + strings.Join(ranges, " OR ") + ")"
+}
+
+// batchSize returns the number of rows fetched by each query
+func (s *Source) batchSize() int {
+	if s.sourceConfig.Config.BatchSize > 0 {
+		return s.sourceConfig.Config.BatchSize
+	}
+	return googlebigquery.CounterLimit
+}
+
+// bufferSize returns the capacity of the records channel
+func (s *Source) bufferSize() int {
+	if s.sourceConfig.Config.BufferSize > 0 {
+		return s.sourceConfig.Config.BufferSize
+	}
+	return googlebigquery.BufferSize
+}
+
+// storageSnapshot reports if the rows are read in a single query streamed with the storage API
+func (s *Source) storageSnapshot(firstSync bool) bool {
+	return firstSync && s.sourceConfig.Config.ReadMode == googlebigquery.ReadModeStorage
+}
+
+// limitClause returns the LIMIT of the query, one row more than the page holds to tell if another page
+// follows. Snapshots read with the storage API or from a temporary table are not limited.
+func (s *Source) limitClause(tableID string, firstSync bool, skip int) string {
+	if s.storageSnapshot(firstSync) || s.materializedSnapshot(tableID) {
+		return ""
+	}
+	return " LIMIT " + strconv.Itoa(s.batchSize()+skip+1)
+}
+
+// selectClause returns the columns to query. The incrementing, primary key and order columns are
+// always selected since offsets, keys and cursors are built from them.
+func (s *Source) selectClause(tableID string) string {
+	columns := s.sourceConfig.Config.Columns
+	if len(columns) == 0 {
+		return "*"
+	}
+
+	required := append(append([]string{}, s.incrementColNames(tableID)...), s.sourceConfig.Config.PrimaryKeyColNames...)
+	required = append(required, s.sourceConfig.Config.KeyColumns...)
+	required = append(required, s.sourceConfig.Config.OrderBy...)
+	for _, column := range required {
+		if !containsString(columns, column) {
+			columns = append(columns, column)
+		}
+	}
+
+	quoted := make([]string, 0, len(columns))
+	for _, column := range columns {
+		quoted = append(quoted, "`"+column+"`")
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func containsString(list []string, value string) bool {
+	for _, entry := range list {
+		if entry == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(list []int, value int) bool {
+	for _, entry := range list {
+		if entry == value {
+			return true
+		}
+	}
+	return false
+}
+
+// fromClause returns the fully qualified table or the user provided query wrapped as subquery
+func (s *Source) fromClause(tableID string) string {
+	if len(s.sourceConfig.Config.Query) > 0 {
+		return "(" + s.sourceConfig.Config.Query + ")"
+	}
+	return "`" + s.sourceConfig.Config.ProjectID + "." + s.sourceConfig.Config.DatasetID + "." + tableID + "`"
+}
+
+// sampleClause returns the TABLESAMPLE clause reading the configured percentage of the data blocks of
+// the table. External tables can't be sampled, all their rows are read then.
+func (s *Source) sampleClause(ctx context.Context, tableID string) string {
+	percent := s.sourceConfig.Config.SamplePercent
+	if percent <= 0 {
+		return ""
+	}
+	if s.externalTable(ctx, tableID) {
+		s.warnExternal(ctx, tableID, googlebigquery.ConfigSamplePercent, "all rows are read")
+		return ""
+	}
+	return " TABLESAMPLE SYSTEM (" + strconv.FormatFloat(percent, 'f', -1, 64) + " PERCENT)"
+}
+
+// whereClause joins the non empty conditions with AND. Returns empty string when there is no condition
+func whereClause(conditions ...string) string {
+	var nonEmpty []string
+	for _, condition := range conditions {
+		if len(condition) > 0 {
+			nonEmpty = append(nonEmpty, condition)
+		}
+	}
+	if len(nonEmpty) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(nonEmpty, " AND ")
+}
+
+// nextWait is how long Next waits for a record before returning sdk.ErrBackoffRetry
+const nextWait = 100 * time.Millisecond
+
+// Next returns the next record from the buffer. When the buffer is empty it waits up to nextWait
+// for a record and returns sdk.ErrBackoffRetry if none arrived, so Conduit backs off before
+// calling Read again instead of spinning while the tables are idle. Once the goroutines reading the
+// tables stopped without error, eg. after a dry run, sdk.ErrBackoffRetry is returned right away.
+func (s *Source) Next(ctx context.Context) (sdk.Record, error) {
+	if s.tomb == nil {
+		return sdk.Record{}, errors.New("source isn't opened, call Open before Read")
+	}
+	select {
+	case r := <-s.records:
+		return r, nil
+	default:
+	}
+
+	timer := time.NewTimer(nextWait)
+	defer timer.Stop()
+	select {
+	case <-s.tomb.Dead():
+		if err := s.tomb.Err(); err != nil {
+			return sdk.Record{}, err
+		}
+		return sdk.Record{}, sdk.ErrBackoffRetry
+	case r := <-s.records:
+		return r, nil
+	case <-ctx.Done():
+		return sdk.Record{}, ctx.Err()
+	case <-timer.C:
+		return sdk.Record{}, sdk.ErrBackoffRetry
+	}
+}
+
+// fetchPos unmarshal position. Positions written by older versions of the connector are still
+// decoded: a map of offsets keyed by table ID, or a bare offset used for all the configured tables.
+// Returns false when the position can't be decoded, the tables are read from their first row then.
+func fetchPos(s *Source, pos sdk.Position) bool {
+	s.position.lock = new(sync.Mutex)
+	s.position.lock.Lock()
+	defer s.position.lock.Unlock()
+	s.position.positions = make(map[string]string)
+	s.position.mode = ""
+	s.position.snapshotsDone = make(map[string]bool)
+	s.position.legacy = false
+
+	position, err := decodePosition(pos, s.sourceConfig.Config.TableIDs)
+	if err != nil || position.Offsets == nil {
+		return false
+	}
+	s.position.positions = position.Offsets
+	s.position.mode = position.Mode
+	// positions without version hold the untyped offsets of earlier versions
+	s.position.legacy = position.Version == 0
+	for _, tableID := range position.SnapshotsDone {
+		s.position.snapshotsDone[tableID] = true
+	}
+	if len(position.SnapshotsDone) == 0 && position.Mode != PositionModeSnapshot {
+		// positions of older versions don't list the snapshots done, they were all done once polling
+		for tableID := range position.Offsets {
+			s.position.snapshotsDone[tableID] = true
+		}
+	}
+	return true
+}
+
+// decodePosition returns the offsets, mode and finished snapshots held by the position
+func decodePosition(pos sdk.Position, tableIDs []string) (Position, error) {
+	// a legacy map holds string values, so it fails to decode into the version of the position
+	var position Position
+	if err := json.Unmarshal(pos, &position); err == nil && position.Version > 0 {
+		if position.Version > PositionVersion {
+			return Position{}, fmt.Errorf("unsupported position version %d", position.Version)
+		}
+		return position, nil
+	}
+
+	var offsets map[string]string
+	if err := json.Unmarshal(pos, &offsets); err == nil {
+		return Position{Offsets: offsets}, nil
+	}
+
+	var offset string
+	if err := json.Unmarshal(pos, &offset); err != nil {
+		return Position{}, err
+	}
+	if len(offset) == 0 {
+		return Position{}, nil
+	}
+	offsets = make(map[string]string)
+	for _, tableID := range tableIDs {
+		offsets[tableID] = offset
+	}
+	return Position{Offsets: offsets}, nil
+}
+
+// runIterator reads the tables and polls them till the source is stopped or ctx is canceled. Open
+// runs it with the context of the tomb, which is canceled once the source is stopped, so the
+// running queries are canceled too.
+func (s *Source) runIterator(ctx context.Context) (err error) {
+	// Snapshot sync. Start were we left last
+	stats := s.startPoll()
+	err = s.runCDCIterator(ctx)
+	if err != nil && !s.rateLimited(ctx, err) {
 		sdk.Logger(ctx).Trace().Str("err", err.Error()).Msg("error found while reading google row.")
 		return err
 	}
+	s.logPoll(ctx, stats)
+	if err := s.waitAfterEnd(ctx); err != nil {
+		return err
+	}
+	if err := s.waitAfterMaxRows(ctx); err != nil {
+		return err
+	}
+	s.heartbeat(ctx)
 
+	emitted := atomic.LoadUint64(&s.emitted)
 	for {
 		select {
 		case <-s.tomb.Dying():
 			return s.tomb.Err()
+		case <-ctx.Done():
+			return s.waitStopped(ctx)
 		case <-s.ticker.C:
+			// the ticker keeps the base polling period, ticks are skipped while backing off
+			if !s.backoff.tick() {
+				continue
+			}
 			sdk.Logger(ctx).Trace().Msg("ticker started ")
-			err = s.ReadGoogleRow(ctx)
+			stats := s.startPoll()
+			err = s.runCDCIterator(ctx)
 			if err != nil {
+				if s.rateLimited(ctx, err) {
+					s.logPoll(ctx, stats)
+					continue
+				}
 				sdk.Logger(ctx).Trace().Msg(fmt.Sprintf("error found %v", err))
 				return
 			}
+			s.logPoll(ctx, stats)
+			if err := s.waitAfterEnd(ctx); err != nil {
+				return err
+			}
+			if err := s.waitAfterMaxRows(ctx); err != nil {
+				return err
+			}
+			current := atomic.LoadUint64(&s.emitted)
+			s.backoff.polled(current > emitted)
+			// heartbeats don't reset the backoff
+			s.heartbeat(ctx)
+			emitted = atomic.LoadUint64(&s.emitted)
+		}
+	}
+}
+
+// waitStopped waits till the source is stopped or ctx is canceled and returns why. The context of
+// the tomb is canceled once it is dying, the reason it was killed with is returned then.
+func (s *Source) waitStopped(ctx context.Context) error {
+	select {
+	case <-s.tomb.Dying():
+	case <-ctx.Done():
+		if s.tomb.Alive() {
+			return ctx.Err()
+		}
+	}
+	return s.tomb.Err()
+}
+
+// rateLimited reports if the poll failed because the project is throttled. Polling is then paused
+// longer, the tables are read from their positions on the next poll.
+func (s *Source) rateLimited(ctx context.Context, err error) bool {
+	if !errors.Is(err, ErrRateLimited) {
+		return false
+	}
+	s.backoff.throttled()
+	sdk.Logger(ctx).Warn().Str("err", err.Error()).Dur("pause", s.backoff.interval).
+		Msg("BigQuery rate limit or quota exceeded, pausing polling")
+	return true
+}
+
+// pollBackoff computes the period between polls. The period doubles after every poll without rows,
+// up to max, and is reset to base once rows are read.
+type pollBackoff struct {
+	base     time.Duration
+	max      time.Duration
+	interval time.Duration // interval is the current period between polls
+	elapsed  time.Duration // elapsed is the time passed since the last poll, counted in ticks of base
+}
+
+const (
+	// minThrottledInterval and maxThrottledInterval bound the period between polls rejected by
+	// rate limits or quotas
+	minThrottledInterval = time.Minute
+	maxThrottledInterval = time.Hour
+)
+
+func newPollBackoff(base, max time.Duration) pollBackoff {
+	return pollBackoff{base: base, max: max, interval: base}
+}
+
+// tick counts a tick of the base period and reports if it is time to poll
+func (b *pollBackoff) tick() bool {
+	b.elapsed += b.base
+	if b.elapsed < b.interval {
+		return false
+	}
+	b.elapsed = 0
+	return true
+}
+
+// throttled doubles the period after a poll rejected by rate limits or quotas, beyond max up to
+// maxThrottledInterval, so a throttled project isn't queried again before its quota recovers.
+func (b *pollBackoff) throttled() {
+	b.interval *= 2
+	if b.interval < minThrottledInterval {
+		b.interval = minThrottledInterval
+	}
+	if b.interval > maxThrottledInterval {
+		b.interval = maxThrottledInterval
+	}
+}
+
+// polled updates the period after a poll. rows reports if the poll read any rows.
+func (b *pollBackoff) polled(rows bool) {
+	if rows || b.max <= b.base {
+		b.interval = b.base
+		return
+	}
+	b.interval *= 2
+	if b.interval > b.max {
+		b.interval = b.max
+	}
+}
+
+// getTables returns the tables to sync. If no table ID is configured all the tables of the dataset
+// matching the include and exclude regex are returned.
+func (s *Source) getTables(ctx context.Context) ([]string, error) {
+	config := s.sourceConfig.Config
+	if len(config.Query) > 0 {
+		// the custom query is synced as a single table
+		return []string{googlebigquery.QueryTableID}, nil
+	}
+	if len(config.TableIDs) > 0 {
+		return config.TableIDs, nil
+	}
+
+	client, _ := s.readClient()
+	tableIDs, err := client.Tables(ctx, s)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing tables of dataset %s: %w", config.DatasetID, err)
+	}
+
+	var tables []string
+	for _, tableID := range tableIDs {
+		if config.TableIncludeRegex != nil && !config.TableIncludeRegex.MatchString(tableID) {
+			continue
 		}
+		if config.TableExcludeRegex != nil && config.TableExcludeRegex.MatchString(tableID) {
+			continue
+		}
+		if materializedTable(tableID) {
+			// temporary tables of snapshots are only read while the snapshot is
+			continue
+		}
+		tables = append(tables, tableID)
+	}
+	return tables, nil
+}
+
+// runCDCIterator reads all the tables once. Each table is read in its own goroutine and
+// the function returns once all of them are done. At most MaxConcurrentReads tables and read
+// streams are read at the same time, the rest wait for a slot to free up. Tables are listed again
+// on every call so tables created in the dataset after start are picked up.
+func (s *Source) runCDCIterator(ctx context.Context) error {
+	tables, err := s.getTables(ctx)
+	if err != nil {
+		sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("error while getting tables")
+		return classifyError(err)
+	}
+
+	maxConcurrentReads := s.sourceConfig.Config.MaxConcurrentReads
+	if maxConcurrentReads <= 0 {
+		maxConcurrentReads = googlebigquery.MaxConcurrentReads
+	}
+	slots := make(chan struct{}, maxConcurrentReads)
+	s.readSlots = slots
+
+	var wg sync.WaitGroup
+	// rateLimitErr is the first ErrRateLimited of a table. It doesn't kill the tomb, so the iterator
+	// keeps running and the tables are polled again after a pause.
+	var rateLimitErr error
+	var rateLimitOnce sync.Once
+dispatch:
+	for _, tableID := range tables {
+		tableID := tableID
+		select {
+		case slots <- struct{}{}:
+		case <-s.tomb.Dying():
+			break dispatch
+		}
+
+		wg.Add(1)
+		s.tomb.Go(func() error {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			err := s.ReadGoogleRow(ctx, tableID)
+			if errors.Is(err, ErrRateLimited) {
+				rateLimitOnce.Do(func() { rateLimitErr = err })
+				return nil
+			}
+			return err
+		})
+	}
+	wg.Wait()
+
+	// tomb is killed with the error of the first table which failed
+	select {
+	case <-s.tomb.Dying():
+		return s.tomb.Err()
+	default:
 	}
+	return rateLimitErr
 }
@@ -0,0 +1,57 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googlesource
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+// heartbeat emits a heartbeat record once no record was emitted for the heartbeat interval. It carries
+// the current position, so the position acked by Conduit advances while the tables are idle.
+func (s *Source) heartbeat(ctx context.Context) {
+	interval := s.sourceConfig.Config.HeartbeatInterval
+	if interval <= 0 {
+		return
+	}
+	lastEmitted := time.Unix(0, atomic.LoadInt64(&s.lastEmitted))
+	if s.clock().Sub(lastEmitted) < interval {
+		return
+	}
+
+	position, err := s.heartbeatPosition()
+	if err != nil {
+		sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("Error marshalling position")
+		return
+	}
+	metadata := sdk.Metadata{
+		MetadataDataset:   s.sourceConfig.Config.DatasetID,
+		MetadataProject:   s.sourceConfig.Config.ProjectID,
+		MetadataHeartbeat: "true",
+	}
+	if s.send(ctx, sdk.Util.Source.NewRecordCreate(position, metadata, nil, nil)) {
+		sdk.Logger(ctx).Debug().Dur("interval", interval).Msg("no rows read, heartbeat emitted")
+	}
+}
+
+// heartbeatPosition returns the position holding the current offsets of all the tables
+func (s *Source) heartbeatPosition() (sdk.Position, error) {
+	s.position.lock.Lock()
+	defer s.position.lock.Unlock()
+	return s.marshalPosition("")
+}
@@ -0,0 +1,63 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googlesource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/bigquery"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	googlebigquery "github.com/neha-Gupta1/conduit-connector-bigquery"
+)
+
+// ErrJobTimeout is returned when a query job doesn't finish within the configured job timeout
+var ErrJobTimeout = errors.New("query job timed out")
+
+// queryJob is a running query job
+type queryJob interface {
+	ID() string
+	Wait(ctx context.Context) (*bigquery.JobStatus, error)
+	Cancel(ctx context.Context) error
+}
+
+// jobContext returns the context query jobs are started and waited for with. It is canceled once
+// the job timeout passed.
+func (s *Source) jobContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.sourceConfig.Config.JobTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.sourceConfig.Config.JobTimeout)
+}
+
+// jobTimedOut reports if the job context was canceled by the job timeout rather than by ctx
+func jobTimedOut(ctx, jobCtx context.Context) bool {
+	return ctx.Err() == nil && errors.Is(jobCtx.Err(), context.DeadlineExceeded)
+}
+
+// waitJob waits for the job to finish within the job timeout. Canceling the context only stops
+// waiting, so a job still running then is canceled in BigQuery too.
+func (s *Source) waitJob(ctx, jobCtx context.Context, job queryJob) (*bigquery.JobStatus, error) {
+	status, err := job.Wait(jobCtx)
+	if err == nil || !jobTimedOut(ctx, jobCtx) {
+		return status, err
+	}
+	if err := job.Cancel(ctx); err != nil {
+		sdk.Logger(ctx).Warn().Str("err", err.Error()).Str("jobID", job.ID()).Msg("Error while canceling the timed out job")
+	}
+	return nil, fmt.Errorf("%w: job %s didn't finish within %s, raise %s or narrow the query",
+		ErrJobTimeout, job.ID(), s.sourceConfig.Config.JobTimeout, googlebigquery.ConfigJobTimeout)
+}
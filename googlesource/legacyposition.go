@@ -0,0 +1,103 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googlesource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	googlebigquery "github.com/neha-Gupta1/conduit-connector-bigquery"
+)
+
+// ErrLegacyPosition is returned when the offset of a position written by an earlier version can't be resumed
+var ErrLegacyPosition = errors.New("position of an earlier version can't be resumed")
+
+// typedOffsetRegex matches offsets prefixed with the SQL type they are cast to, eg. INT64 5
+var typedOffsetRegex = regexp.MustCompile(`^[A-Z][A-Z0-9]* `)
+
+// migratePosition converts the offsets of a position written by an earlier version into typed offsets.
+// Earlier versions stored the SQL literal of the incrementing column, eg. '2022-01-01 10:00:00 +0000 UTC'
+// or 5, its type is resolved from the schema of the table. Tables without incrementing column were read
+// with OFFSET in no particular order, the row counter they stored can't be converted to a primary key,
+// so an error is returned for it. Offsets which are typed already are kept.
+func (s *Source) migratePosition(ctx context.Context, client tableMetadataClient) error {
+	s.position.lock.Lock()
+	legacy := s.position.legacy
+	offsets := make(map[string]string, len(s.position.positions))
+	for tableID, offset := range s.position.positions {
+		offsets[tableID] = offset
+	}
+	s.position.lock.Unlock()
+	if !legacy {
+		return nil
+	}
+
+	for tableID, offset := range offsets {
+		if len(offset) == 0 || typedOffset(offset) {
+			continue
+		}
+		migrated, err := s.migrateOffset(ctx, client, tableID, offset)
+		if err != nil {
+			return err
+		}
+		s.setPosition(tableID, migrated)
+		sdk.Logger(ctx).Info().Str("tableID", tableID).Str("legacyOffset", offset).Str("offset", migrated).
+			Msg("offset of an earlier version migrated")
+	}
+	return nil
+}
+
+// migrateOffset converts the SQL literal of the incrementing column of the table into a typed offset
+func (s *Source) migrateOffset(ctx context.Context, client tableMetadataClient, tableID, offset string) (string, error) {
+	if !s.incrementConfigured(tableID) {
+		return "", fmt.Errorf("%w: offset %s of table %s counts the rows read by OFFSET without %s, reset the position to read the table again",
+			ErrLegacyPosition, offset, tableID, googlebigquery.ConfigIncrementalColName)
+	}
+	if columnNames := s.incrementColNames(tableID); len(columnNames) > 1 {
+		return "", fmt.Errorf("%w: offset %s of table %s holds a single column, the table is incremented by %s",
+			ErrLegacyPosition, offset, tableID, strings.Join(columnNames, ", "))
+	}
+	field, err := s.incrementField(ctx, client, tableID)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrLegacyPosition, err)
+	}
+
+	literal := offset
+	if len(literal) >= 2 && strings.HasPrefix(literal, "'") && strings.HasSuffix(literal, "'") {
+		literal = literal[1 : len(literal)-1]
+	}
+	value, err := parsePosition(field, literal)
+	if err != nil {
+		return "", fmt.Errorf("%w: offset %s of table %s isn't a value of column %s: %w", ErrLegacyPosition, offset, tableID, field.Name, err)
+	}
+	return s.positionOffset(ctx, field, value)
+}
+
+// incrementConfigured reports if an incrementing column is configured for the table, opposed to
+// tables paginated by their primary key
+func (s *Source) incrementConfigured(tableID string) bool {
+	_, ok := s.sourceConfig.Config.TableIncrementColNames[tableID]
+	return ok || len(s.sourceConfig.Config.IncrementColNames) > 0
+}
+
+// typedOffset reports if the offset is prefixed with its SQL type, or holds the typed offsets of
+// composite columns
+func typedOffset(offset string) bool {
+	return typedOffsetRegex.MatchString(offset) || strings.HasPrefix(offset, "[")
+}
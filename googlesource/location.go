@@ -0,0 +1,71 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googlesource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	googlebigquery "github.com/neha-Gupta1/conduit-connector-bigquery"
+)
+
+// ErrLocationMismatch is returned when the configured location isn't the one of the dataset. Query
+// jobs run in another location than the dataset fail with "dataset not found in location".
+var ErrLocationMismatch = errors.New("dataset location mismatch")
+
+// datasetLocator fetches the location of a dataset
+type datasetLocator interface {
+	DatasetLocation(ctx context.Context, s *Source, datasetID string) (string, error)
+}
+
+// DatasetLocation returns the location of the dataset from its metadata
+func (bq bqClientStruct) DatasetLocation(ctx context.Context, s *Source, datasetID string) (string, error) {
+	md, err := bq.client.Dataset(datasetID).Metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	return md.Location, nil
+}
+
+// resolveLocation sets the location the query jobs run in to the one of the dataset when it is blank,
+// and checks a configured location against it. Locations are compared case insensitively, BigQuery
+// accepts both eg. US and us. A configured location is kept when the metadata can't be fetched.
+func (s *Source) resolveLocation(ctx context.Context, client datasetLocator) error {
+	config := s.sourceConfig.Config
+	location, err := client.DatasetLocation(ctx, s, config.DatasetID)
+	if err != nil {
+		if len(config.Location) > 0 {
+			sdk.Logger(ctx).Warn().Str("err", err.Error()).Str("location", config.Location).
+				Msg("Error while fetching the location of the dataset, the configured location is used")
+			return nil
+		}
+		return fmt.Errorf("error while detecting the location of dataset %s.%s, set %s: %w",
+			config.ProjectID, config.DatasetID, googlebigquery.ConfigLocation, err)
+	}
+
+	if len(config.Location) == 0 {
+		s.sourceConfig.Config.Location = location
+		sdk.Logger(ctx).Info().Str("location", location).Msg("location of the dataset detected")
+		return nil
+	}
+	if !strings.EqualFold(config.Location, location) {
+		return fmt.Errorf("%w: dataset %s.%s is in %s, but %s is %s", ErrLocationMismatch,
+			config.ProjectID, config.DatasetID, location, googlebigquery.ConfigLocation, config.Location)
+	}
+	return nil
+}
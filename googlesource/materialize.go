@@ -0,0 +1,126 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googlesource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	googlebigquery "github.com/neha-Gupta1/conduit-connector-bigquery"
+)
+
+const (
+	// materializedTablePrefix is the prefix of the temporary tables snapshots are written to. Tables
+	// with it are skipped when the tables of the dataset are listed.
+	materializedTablePrefix = "conduit_snapshot_"
+	// materializedTableExpiration is how long a temporary table is kept when the connector stops
+	// without dropping it
+	materializedTableExpiration = 24 * time.Hour
+)
+
+// materializingClient writes query results to tables and drops them
+type materializingClient interface {
+	// Materialize runs the query writing its results to the table dst of the dataset, replacing its
+	// rows, and returns an iterator reading the table
+	Materialize(ctx context.Context, s *Source, dst string, query string, params ...bigquery.QueryParameter) (rowIterator, error)
+	DropTable(ctx context.Context, s *Source, tableID string) error
+}
+
+func (bq bqClientStruct) Materialize(ctx context.Context, s *Source, dst string, query string, params ...bigquery.QueryParameter) (rowIterator, error) {
+	table := bq.client.Dataset(s.sourceConfig.Config.DatasetID).Table(dst)
+	q := s.newQuery(bq.client, query, params)
+	q.Dst = table
+	q.CreateDisposition = bigquery.CreateIfNeeded
+	q.WriteDisposition = bigquery.WriteTruncate
+	if _, err := s.runJob(ctx, q); err != nil {
+		return nil, err
+	}
+
+	update := bigquery.TableMetadataToUpdate{ExpirationTime: time.Now().Add(materializedTableExpiration)}
+	if _, err := table.Update(ctx, update, ""); err != nil {
+		sdk.Logger(ctx).Warn().Str("err", err.Error()).Str("table", dst).Msg("Error while setting the expiration of the temporary table")
+	}
+	// reading a table isn't billed, opposed to querying it
+	return rowIter{it: table.Read(ctx)}, nil
+}
+
+func (bq bqClientStruct) DropTable(ctx context.Context, s *Source, tableID string) error {
+	return bq.client.Dataset(s.sourceConfig.Config.DatasetID).Table(tableID).Delete(ctx)
+}
+
+// materializedSnapshot reports if the snapshot of the table is read from a temporary table. The
+// rows left are written to it with a single query, instead of one query per page.
+func (s *Source) materializedSnapshot(tableID string) bool {
+	return s.sourceConfig.Config.MaterializeSnapshot && s.sourceConfig.Config.Mode != googlebigquery.ModeCDC &&
+		!s.snapshotDone(tableID)
+}
+
+// materializedTableID returns the ID of the temporary table the snapshot of the table is written to.
+// The suffix is unique to every start, so sources reading the same table don't share it.
+func (s *Source) materializedTableID(tableID string) string {
+	return materializedTablePrefix + tableID + s.materializeSuffix
+}
+
+// materialize writes the results of the query reading the snapshot of the table to its temporary
+// table and returns an iterator reading them
+func (s *Source) materialize(ctx context.Context, tableID, query string, params ...bigquery.QueryParameter) (rowIterator, error) {
+	client, _ := s.readClient()
+	materializer, ok := client.(materializingClient)
+	if !ok {
+		return nil, errors.New("BigQuery client can't write query results to tables")
+	}
+
+	dst := s.materializedTableID(tableID)
+	// the table is dropped on teardown, also when the query failed after creating it
+	s.materialized.Store(dst, true)
+	it, err := materializer.Materialize(ctx, s, dst, query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("error while writing the snapshot of table %s to %s: %w", tableID, dst, classifyError(err))
+	}
+	sdk.Logger(ctx).Info().Str("tableID", tableID).Str("table", dst).Msg("snapshot written to temporary table")
+	return countingIterator{rowIterator: it, s: s}, nil
+}
+
+// dropMaterialized drops the temporary table. Tables which can't be dropped expire.
+func (s *Source) dropMaterialized(ctx context.Context, dst string) {
+	s.materialized.Delete(dst)
+	client, _ := s.readClient()
+	materializer, ok := client.(materializingClient)
+	if !ok {
+		return
+	}
+	if err := materializer.DropTable(ctx, s, dst); err != nil && !notFound(err) {
+		sdk.Logger(ctx).Warn().Str("err", err.Error()).Str("table", dst).
+			Dur("expiration", materializedTableExpiration).Msg("Error while dropping the temporary table, it expires instead")
+	}
+}
+
+// dropMaterializedTables drops the temporary tables of the snapshots which weren't read till the end
+func (s *Source) dropMaterializedTables(ctx context.Context) {
+	s.materialized.Range(func(dst, _ interface{}) bool {
+		s.dropMaterialized(ctx, dst.(string))
+		return true
+	})
+}
+
+// materializedTable reports if the table is a temporary table of a snapshot
+func materializedTable(tableID string) bool {
+	return strings.HasPrefix(tableID, materializedTablePrefix)
+}
@@ -0,0 +1,49 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googlesource
+
+import (
+	"context"
+	"sync/atomic"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+// countRecord counts a record about to be emitted against maxRows. Returns false once maxRows records
+// were counted, the tables are read concurrently so the count is shared by all of them.
+func (s *Source) countRecord() bool {
+	maxRows := s.sourceConfig.Config.MaxRows
+	if maxRows <= 0 {
+		return true
+	}
+	return atomic.AddUint64(&s.counted, 1) <= uint64(maxRows)
+}
+
+// maxRowsReached reports if maxRows records were emitted
+func (s *Source) maxRowsReached() bool {
+	maxRows := s.sourceConfig.Config.MaxRows
+	return maxRows > 0 && atomic.LoadUint64(&s.counted) >= uint64(maxRows)
+}
+
+// waitAfterMaxRows stops polling once maxRows records were emitted. The iterator waits for the source
+// to stop then, the records left in the buffer are still read.
+func (s *Source) waitAfterMaxRows(ctx context.Context) error {
+	if !s.maxRowsReached() {
+		return nil
+	}
+	sdk.Logger(ctx).Info().Int("maxRows", s.sourceConfig.Config.MaxRows).
+		Msg("maximum number of rows emitted, polling stopped")
+	return s.waitStopped(ctx)
+}
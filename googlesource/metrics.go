@@ -0,0 +1,161 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googlesource
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+// RowsRead returns the number of rows returned by the queries since the source was opened
+func (s *Source) RowsRead() uint64 {
+	return atomic.LoadUint64(&s.rowsRead)
+}
+
+// BytesScanned returns the number of bytes processed by the queries since the source was opened.
+// Queries are billed by the bytes they process.
+func (s *Source) BytesScanned() uint64 {
+	return atomic.LoadUint64(&s.bytesScanned)
+}
+
+// addBytesScanned adds the bytes processed by a query
+func (s *Source) addBytesScanned(bytes int64) {
+	if bytes > 0 {
+		atomic.AddUint64(&s.bytesScanned, uint64(bytes))
+	}
+}
+
+// countingIterator counts the rows returned by the iterator
+type countingIterator struct {
+	rowIterator
+	s *Source
+}
+
+func (it countingIterator) Next(dst interface{}) error {
+	err := it.rowIterator.Next(dst)
+	if err == nil {
+		atomic.AddUint64(&it.s.rowsRead, 1)
+	}
+	return err
+}
+
+// pollStats holds the counters when a poll started
+type pollStats struct {
+	rowsRead     uint64
+	bytesScanned uint64
+}
+
+// startPoll returns the counters at the start of a poll
+func (s *Source) startPoll() pollStats {
+	return pollStats{rowsRead: s.RowsRead(), bytesScanned: s.BytesScanned()}
+}
+
+// logPoll logs the rows read and bytes scanned by the poll and since the source was opened. The lag
+// is updated and logged when tables are incremented by time.
+func (s *Source) logPoll(ctx context.Context, start pollStats) {
+	rowsRead, bytesScanned := s.RowsRead(), s.BytesScanned()
+	event := sdk.Logger(ctx).Info().
+		Uint64("rowsRead", rowsRead-start.rowsRead).
+		Uint64("bytesScanned", bytesScanned-start.bytesScanned).
+		Uint64("totalRowsRead", rowsRead).
+		Uint64("totalBytesScanned", bytesScanned)
+	if lag, ok := s.lag.update(s.clock()); ok {
+		event = event.Dur("lag", lag)
+	}
+	event.Msg("poll done")
+}
+
+// Lag returns how far the sync is behind, measured after the last poll as the time passed since the
+// greatest incrementing column value read of the table which is the furthest behind. Only tables
+// incremented by a TIMESTAMP or DATETIME column are measured, false is returned while there is none.
+func (s *Source) Lag() (time.Duration, bool) {
+	return s.lag.get()
+}
+
+// clock returns the current time
+func (s *Source) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+// lagGauge tracks the greatest incrementing time read of each table
+type lagGauge struct {
+	lock   sync.Mutex
+	latest map[string]time.Time // latest holds the greatest incrementing time read keyed by table ID
+	lag    time.Duration
+	valid  bool
+}
+
+// rowTime returns the time of a TIMESTAMP or DATETIME value read from BigQuery. DATETIME values are
+// taken as UTC.
+func rowTime(field *bigquery.FieldSchema, value bigquery.Value) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, field.Type == bigquery.TimestampFieldType
+	case civil.DateTime:
+		return v.In(time.UTC), true
+	}
+	return time.Time{}, false
+}
+
+// observe records the incrementing column value of a row read from the table. Only TIMESTAMP and
+// DATETIME values are recorded.
+func (g *lagGauge) observe(tableID string, field *bigquery.FieldSchema, value bigquery.Value) {
+	t, ok := rowTime(field, value)
+	if !ok {
+		return
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.latest == nil {
+		g.latest = make(map[string]time.Time)
+	}
+	if t.After(g.latest[tableID]) {
+		g.latest[tableID] = t
+	}
+}
+
+// update computes the lag of the table which is the furthest behind at now
+func (g *lagGauge) update(now time.Time) (time.Duration, bool) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if len(g.latest) == 0 {
+		return 0, false
+	}
+	var oldest time.Time
+	for _, latest := range g.latest {
+		if oldest.IsZero() || latest.Before(oldest) {
+			oldest = latest
+		}
+	}
+	g.lag = now.Sub(oldest)
+	g.valid = true
+	return g.lag, true
+}
+
+func (g *lagGauge) get() (time.Duration, bool) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.lag, g.valid
+}
@@ -0,0 +1,67 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googlesource
+
+import (
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	googlebigquery "github.com/neha-Gupta1/conduit-connector-bigquery"
+)
+
+// dropNulls leaves the top-level columns holding NULL out of the payload when nullHandling is omit.
+// It is called once the key is read from the row, so NULL key columns are still encoded as null.
+func (s *Source) dropNulls(data sdk.StructuredData) {
+	if s.sourceConfig.Config.NullHandling != googlebigquery.NullHandlingOmit {
+		return
+	}
+	for column, value := range data {
+		if value == nil {
+			delete(data, column)
+		}
+	}
+}
+
+// nullOffset returns the first of the incrementing columns holding NULL in the row. NULL isn't
+// ordered against the other values, so it can't be used as offset.
+func nullOffset(schema bigquery.Schema, row []bigquery.Value, offsetIndexes []int) (string, bool) {
+	for _, i := range offsetIndexes {
+		if row[i] == nil {
+			return schema[i].Name, true
+		}
+	}
+	return "", false
+}
+
+// markNullOffset remembers that rows with NULL incrementing columns were read from the table
+func (s *Source) markNullOffset(tableID string) {
+	s.nullOffsets.Store(tableID, struct{}{})
+}
+
+// nullOffsetCondition returns the condition leaving out the rows with NULL incrementing columns once
+// such rows were read from the table. Queries without offset return them first when ordered ascending,
+// so a page only holding NULL rows would be read again and again, as it doesn't advance the offset.
+// Queries with an offset never return them, as NULL isn't greater nor smaller than the offset.
+func (s *Source) nullOffsetCondition(tableID string, columnNames []string) string {
+	if _, ok := s.nullOffsets.Load(tableID); !ok {
+		return ""
+	}
+	conditions := make([]string, 0, len(columnNames))
+	for _, column := range columnNames {
+		conditions = append(conditions, "`"+column+"` IS NOT NULL")
+	}
+	return strings.Join(conditions, " AND ")
+}
@@ -0,0 +1,153 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googlesource
+
+import (
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	googlebigquery "github.com/neha-Gupta1/conduit-connector-bigquery"
+)
+
+// customOrder reports if the rows are ordered by the configured orderBy columns instead of the
+// incrementing columns. The last row read isn't the greatest one then, so the pages are paginated by
+// the order columns and the offset only advances once the rows after it were read to the end.
+func (s *Source) customOrder() bool {
+	return len(s.sourceConfig.Config.OrderBy) > 0
+}
+
+// orderColumns returns the columns the pages of rows ordered by orderBy are paginated by. The
+// incrementing and primary key columns follow the orderBy columns, so every row has its own place.
+func (s *Source) orderColumns(tableID string) []string {
+	var columns []string
+	for _, column := range append(append(append([]string{}, s.sourceConfig.Config.OrderBy...),
+		s.incrementColNames(tableID)...), s.sourceConfig.Config.PrimaryKeyColNames...) {
+		if !containsString(columns, column) {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// orderByClause returns the quoted columns the rows of the table are ordered by
+func (s *Source) orderByClause(tableID string, columnNames []string) string {
+	if s.customOrder() {
+		columnNames = s.orderColumns(tableID)
+	}
+	quoted := make([]string, 0, len(columnNames))
+	for _, column := range columnNames {
+		quoted = append(quoted, "`"+column+"`")
+	}
+	if s.sourceConfig.Config.IncrementOrder == googlebigquery.IncrementOrderDesc && !s.customOrder() {
+		return strings.Join(quoted, " DESC, ") + " DESC"
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// orderCursorPositionKey is the key the order columns of the last row read are stored under in the
+// position, while the rows after the offset are read
+func orderCursorPositionKey(positionKey string) string {
+	return positionKey + "#orderBy"
+}
+
+// greatestPositionKey is the key the greatest offset of the rows read so far is stored under in the
+// position, while the rows after the offset are read
+func greatestPositionKey(positionKey string) string {
+	return positionKey + "#greatest"
+}
+
+// clearOrderCursor removes the cursor and greatest offset of the rows read after the offset once
+// they were read to the end
+func (s *Source) clearOrderCursor(positionKey string) {
+	s.position.lock.Lock()
+	defer s.position.lock.Unlock()
+	delete(s.position.positions, orderCursorPositionKey(positionKey))
+	delete(s.position.positions, greatestPositionKey(positionKey))
+}
+
+// orderCursor returns the offset of the order columns of the row, the next page starts after it.
+// Rows can't be compared with NULL, so they can't be paginated by columns holding NULL.
+func orderCursor(tableID string, schema bigquery.Schema, row, converted []bigquery.Value, indexes []int) (string, error) {
+	offsets := make([]string, len(indexes))
+	for j, i := range indexes {
+		if row[i] == nil {
+			return "", fmt.Errorf("order column %s of table %s is NULL, rows can't be paginated by it", schema[i].Name, tableID)
+		}
+		offsets[j] = formatOffset(schema[i], row[i], converted[i])
+	}
+	return joinOffsets(offsets), nil
+}
+
+// greatestOffset tracks the greatest offset of the rows read by a query which isn't ordered by the
+// incrementing columns, and the keys of the rows holding it
+type greatestOffset struct {
+	values []bigquery.Value
+	offset string
+	keys   map[string]struct{}
+}
+
+// observe remembers the offset of the row if it is the greatest one read so far. Rows read descending
+// advance to smaller offsets.
+func (g *greatestOffset) observe(values []bigquery.Value, offset string, key []byte, descending bool) {
+	greater := g.values == nil || rowBefore(g.values, values)
+	if descending {
+		greater = g.values == nil || rowBefore(values, g.values)
+	}
+	if greater && offset != g.offset {
+		g.values = values
+		g.offset = offset
+		g.keys = make(map[string]struct{})
+	}
+	if offset == g.offset {
+		g.keys[string(key)] = struct{}{}
+	}
+}
+
+// rowBefore reports if the values of the incrementing columns a are smaller than b, comparing the
+// columns lexicographically
+func rowBefore(a, b []bigquery.Value) bool {
+	for i := range a {
+		if positionBefore(a[i], b[i]) {
+			return true
+		}
+		if positionBefore(b[i], a[i]) {
+			return false
+		}
+	}
+	return false
+}
+
+// restore sets the greatest offset to the one stored in the position when the rows after the offset
+// are read again after a restart. The keys of the rows holding it are not known anymore.
+func (g *greatestOffset) restore(schema bigquery.Schema, indexes []int, offset string) error {
+	offsets := splitOffsets(offset)
+	if len(offsets) != len(indexes) {
+		return fmt.Errorf("offset %q doesn't match the incrementing columns", offset)
+	}
+	values := make([]bigquery.Value, len(indexes))
+	for j, i := range indexes {
+		_, value := parseOffset(offsets[j])
+		parsed, err := parsePosition(schema[i], value)
+		if err != nil {
+			return err
+		}
+		values[j] = parsed
+	}
+	g.values = values
+	g.offset = offset
+	g.keys = make(map[string]struct{})
+	return nil
+}
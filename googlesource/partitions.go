@@ -0,0 +1,137 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googlesource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	googlebigquery "github.com/neha-Gupta1/conduit-connector-bigquery"
+)
+
+// ErrPartitionFilterRequired is returned when a table only accepts queries filtering its partitions
+// and no such filter can be built from the configuration
+var ErrPartitionFilterRequired = errors.New("table requires a partition filter")
+
+const (
+	// partitionTimeColumn is the name the _PARTITIONTIME pseudo column is written to the payload as
+	partitionTimeColumn = "partition_time"
+	// partitionDateColumn is the name the _PARTITIONDATE pseudo column is written to the payload as
+	partitionDateColumn = "partition_date"
+)
+
+// partitioning is how a table is partitioned
+type partitioning struct {
+	// required is set for tables created with require_partition_filter
+	required bool
+	// field is the column the table is partitioned by, _PARTITIONTIME for ingestion time partitioning
+	field string
+	// byTime is set for tables partitioned by time, opposed to integer ranges
+	byTime bool
+	// hourly is set for tables partitioned by hour, their lookback filter compares times instead of dates
+	hourly bool
+	// daily is set for tables partitioned by day, only ingestion time partitioned ones have _PARTITIONDATE
+	daily bool
+	// external is set for external tables, which aren't partitioned by BigQuery
+	external bool
+}
+
+// tablePartitioning returns how the table is partitioned. The metadata is fetched once per table.
+// Tables are treated as not requiring a partition filter when it can't be fetched, BigQuery rejects
+// their queries then.
+func (s *Source) tablePartitioning(ctx context.Context, tableID string) partitioning {
+	if cached, ok := s.partitionings.Load(tableID); ok {
+		return cached.(partitioning)
+	}
+
+	var info partitioning
+	client, _ := s.readClient()
+	metadataClient, ok := client.(tableMetadataClient)
+	if !ok || tableID == googlebigquery.QueryTableID {
+		return info
+	}
+	md, err := metadataClient.TableMetadata(ctx, s, tableID)
+	if err != nil {
+		sdk.Logger(ctx).Warn().Str("err", err.Error()).Str("tableID", tableID).Msg("Error while fetching the partitioning of the table")
+		return info
+	}
+
+	info.required = md.RequirePartitionFilter
+	info.external = externalMetadata(md)
+	switch {
+	case md.TimePartitioning != nil:
+		info.field = md.TimePartitioning.Field
+		if len(info.field) == 0 {
+			info.field = googlebigquery.DefaultPartitionField
+		}
+		info.byTime = true
+		info.hourly = md.TimePartitioning.Type == bigquery.HourPartitioningType
+		info.daily = md.TimePartitioning.Type == bigquery.DayPartitioningType || len(md.TimePartitioning.Type) == 0
+	case md.RangePartitioning != nil:
+		info.field = md.RangePartitioning.Field
+	}
+	s.partitionings.Store(tableID, info)
+	return info
+}
+
+// requiredPartitionCondition returns the partition filter of tables requiring one. The configured
+// partitions and the keyset condition of an offset on the partition field already filter the
+// partitions, otherwise the partitions within the lookback window are read.
+func (s *Source) requiredPartitionCondition(ctx context.Context, tableID string, columnNames []string, offsetUsed bool) (string, error) {
+	info := s.tablePartitioning(ctx, tableID)
+	if !info.required || len(s.sourceConfig.Config.Partitions) > 0 {
+		return "", nil
+	}
+	if offsetUsed && len(columnNames) > 0 && columnNames[0] == info.field {
+		return "", nil
+	}
+
+	lookback := s.sourceConfig.Config.PartitionLookback
+	if lookback > 0 && info.byTime {
+		layout := "2006-01-02"
+		if info.hourly {
+			layout = "2006-01-02 15:04:05"
+		}
+		field := info.field
+		if field != googlebigquery.DefaultPartitionField {
+			field = "`" + field + "`"
+		}
+		return fmt.Sprintf("%s >= '%s'", field, s.clock().Add(-lookback).UTC().Format(layout)), nil
+	}
+	return "", fmt.Errorf("%w: %s is partitioned by %s, set %s or %s to select the partitions to read",
+		ErrPartitionFilterRequired, tableID, info.field, googlebigquery.ConfigPartitions, googlebigquery.ConfigPartitionLookback)
+}
+
+// pseudoColumns returns the partition pseudo columns selected in addition to the columns of the table
+// when includePseudoColumns is set. SELECT * leaves them out, and only ingestion time partitioned tables
+// have them. They are aliased, as BigQuery rejects result columns named like them.
+func (s *Source) pseudoColumns(ctx context.Context, tableID string) string {
+	if !s.sourceConfig.Config.IncludePseudoColumns {
+		return ""
+	}
+	info := s.tablePartitioning(ctx, tableID)
+	if info.external || info.field != googlebigquery.DefaultPartitionField {
+		return ""
+	}
+	columns := []string{googlebigquery.DefaultPartitionField + " AS " + partitionTimeColumn}
+	if info.daily {
+		columns = append(columns, "_PARTITIONDATE AS "+partitionDateColumn)
+	}
+	return ", " + strings.Join(columns, ", ")
+}
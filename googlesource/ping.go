@@ -0,0 +1,104 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googlesource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"cloud.google.com/go/bigquery"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	googlebigquery "github.com/neha-Gupta1/conduit-connector-bigquery"
+	"google.golang.org/api/googleapi"
+)
+
+// connectionChecker runs the requests checking the connection to BigQuery
+type connectionChecker interface {
+	TestQuery(ctx context.Context, location string) error
+	DatasetAccess(ctx context.Context, datasetID string) error
+}
+
+// TestQuery runs SELECT 1 and reads its row
+func (bq bqClientStruct) TestQuery(ctx context.Context, location string) error {
+	q := bq.client.Query("SELECT 1")
+	q.Location = location
+	it, err := q.Read(ctx)
+	if err != nil {
+		return err
+	}
+	var row []bigquery.Value
+	return it.Next(&row)
+}
+
+// DatasetAccess fetches the metadata of the dataset, which fails without access to it
+func (bq bqClientStruct) DatasetAccess(ctx context.Context, datasetID string) error {
+	_, err := bq.client.Dataset(datasetID).Metadata(ctx)
+	return err
+}
+
+// Ping checks the credentials and the access to the dataset of a configured source without reading
+// any table. It runs a test query and fetches the metadata of the dataset, so auth and permission
+// problems are reported before the pipeline is started.
+func (s *Source) Ping(ctx context.Context) error {
+	if s.clientType == nil {
+		return errors.New("source isn't configured, call Configure before Ping")
+	}
+	ctx = googlebigquery.WithLogLevel(ctx, s.sourceConfig.Config.LogLevel)
+	client, err := s.clientType.Client()
+	if err != nil {
+		sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("error found while creating connection. ")
+		return fmt.Errorf("error while creating bigquery client: %w", err)
+	}
+	bqClient := bqClientStruct{client: client}
+	defer bqClient.Close()
+	return s.ping(ctx, bqClient)
+}
+
+// ping checks the connection with the client
+func (s *Source) ping(ctx context.Context, checker connectionChecker) error {
+	config := s.sourceConfig.Config
+	if err := checker.TestQuery(ctx, config.Location); err != nil {
+		sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("error while running test query")
+		return fmt.Errorf("error while running a test query in project %s: %w", config.ProjectID, pingError(err))
+	}
+	if err := checker.DatasetAccess(ctx, config.DatasetID); err != nil {
+		sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("error while accessing dataset")
+		return fmt.Errorf("error while accessing dataset %s.%s: %w", config.ProjectID, config.DatasetID, pingError(err))
+	}
+	sdk.Logger(ctx).Info().Str("projectID", config.ProjectID).Str("datasetID", config.DatasetID).
+		Msg("connection to BigQuery checked")
+	return nil
+}
+
+// pingError explains the errors of rejected credentials and missing permissions
+func pingError(err error) error {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	switch apiErr.Code {
+	case http.StatusUnauthorized:
+		return fmt.Errorf("credentials rejected, check %s or the application default credentials: %w",
+			googlebigquery.ConfigServiceAccountJSON, err)
+	case http.StatusForbidden:
+		return fmt.Errorf("permission denied, check the roles granted to the service account: %w", err)
+	case http.StatusNotFound:
+		return fmt.Errorf("not found, check %s, %s and %s: %w",
+			googlebigquery.ConfigProjectID, googlebigquery.ConfigDatasetID, googlebigquery.ConfigLocation, err)
+	}
+	return err
+}
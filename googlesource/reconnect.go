@@ -0,0 +1,95 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googlesource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+// maxConnectionFailures is the number of queries in a row failing to reach BigQuery after which the
+// client is considered dead and recreated
+const maxConnectionFailures = 3
+
+// connectionError reports if the request failed to reach BigQuery, eg. because the connection was reset
+func connectionError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// readClient returns the client tables are read with and its generation, which changes every time
+// the client is recreated
+func (s *Source) readClient() (bqClient, uint64) {
+	s.clientLock.RLock()
+	defer s.clientLock.RUnlock()
+	return s.bqReadClient, s.clientGeneration
+}
+
+// newReadClient creates a new client to read the tables with
+func (s *Source) newReadClient() (bqClient, error) {
+	if s.connect != nil {
+		return s.connect()
+	}
+	client, err := s.clientType.Client()
+	if err != nil {
+		return nil, err
+	}
+	return bqClientStruct{client: client}, nil
+}
+
+// connectionFailed counts a query failing with a connection error and recreates the client once
+// the queries failed maxConnectionFailures times in a row
+func (s *Source) connectionFailed(ctx context.Context, generation uint64) {
+	if atomic.AddInt32(&s.connectionFailures, 1) < maxConnectionFailures {
+		return
+	}
+	if err := s.reconnect(ctx, generation); err != nil {
+		sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("Error while recreating BigQuery client")
+	}
+}
+
+// reconnect replaces the client of the generation with a new one. The tables read concurrently fail
+// on the same dead client, so the client is only replaced once per generation.
+func (s *Source) reconnect(ctx context.Context, generation uint64) error {
+	s.clientLock.Lock()
+	defer s.clientLock.Unlock()
+	if s.clientClosed || s.clientGeneration != generation {
+		return nil
+	}
+
+	client, err := s.newReadClient()
+	if err != nil {
+		return fmt.Errorf("error while creating bigquery client: %w", err)
+	}
+	if s.bqReadClient != nil {
+		if err := s.bqReadClient.Close(); err != nil {
+			sdk.Logger(ctx).Warn().Str("err", err.Error()).Msg("got error while closing dead BigQuery client")
+		}
+	}
+	s.bqReadClient = client
+	s.clientGeneration++
+	atomic.StoreInt32(&s.connectionFailures, 0)
+	sdk.Logger(ctx).Warn().Int("failures", maxConnectionFailures).Msg("recreated BigQuery client after repeated connection errors")
+	return nil
+}
@@ -0,0 +1,174 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googlesource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	googlebigquery "github.com/neha-Gupta1/conduit-connector-bigquery"
+	"google.golang.org/api/googleapi"
+)
+
+// retryableReasons are the error reasons of BigQuery failures which go away when retried
+var retryableReasons = map[string]bool{
+	"backendError":      true,
+	"internalError":     true,
+	"jobBackendError":   true,
+	"jobInternalError":  true,
+	"rateLimitExceeded": true,
+}
+
+// ErrRateLimited is returned when BigQuery rejected a query because the rate limit or quota of the
+// project is exceeded. Polling is paused longer till the quota recovers.
+var ErrRateLimited = errors.New("BigQuery rate limit or quota exceeded")
+
+// ErrMaxBytesBilled is returned when BigQuery rejected a query because it would bill more bytes than
+// allowed by maxBytesBilled
+var ErrMaxBytesBilled = errors.New("query exceeds the maximum bytes billed")
+
+// rateLimitedReasons are the error reasons of BigQuery rejecting requests of a throttled project
+var rateLimitedReasons = map[string]bool{
+	"quotaExceeded":     true,
+	"rateLimitExceeded": true,
+}
+
+// rateLimitError is the error of a query rejected by throttling. It is ErrRateLimited and wraps the
+// error returned by BigQuery.
+type rateLimitError struct {
+	err error
+}
+
+func (e rateLimitError) Error() string {
+	return ErrRateLimited.Error() + ": " + e.err.Error()
+}
+
+func (e rateLimitError) Unwrap() error {
+	return e.err
+}
+
+func (e rateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// classifyError returns ErrRateLimited wrapping the error when the project is throttled and
+// ErrMaxBytesBilled when the query would bill too many bytes
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if hasReason(err, bytesBilledLimitExceeded) {
+		return fmt.Errorf("%w, raise %s or narrow the query: %w", ErrMaxBytesBilled, googlebigquery.ConfigMaxBytesBilled, err)
+	}
+	var bqErr *bigquery.Error
+	if errors.As(err, &bqErr) && rateLimitedReasons[bqErr.Reason] {
+		return rateLimitError{err: err}
+	}
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		if apiErr.Code == http.StatusTooManyRequests {
+			return rateLimitError{err: err}
+		}
+		for _, item := range apiErr.Errors {
+			if rateLimitedReasons[item.Reason] {
+				return rateLimitError{err: err}
+			}
+		}
+	}
+	return err
+}
+
+// bytesBilledLimitExceeded is the error reason of queries billing more bytes than allowed
+const bytesBilledLimitExceeded = "bytesBilledLimitExceeded"
+
+// hasReason reports if BigQuery failed the request with the reason
+func hasReason(err error, reason string) bool {
+	var bqErr *bigquery.Error
+	if errors.As(err, &bqErr) && bqErr.Reason == reason {
+		return true
+	}
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		for _, item := range apiErr.Errors {
+			if item.Reason == reason {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// retryable reports if the error is a transient BigQuery error worth retrying
+func retryable(err error) bool {
+	var bqErr *bigquery.Error
+	if errors.As(err, &bqErr) {
+		return retryableReasons[bqErr.Reason]
+	}
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, item := range apiErr.Errors {
+		if retryableReasons[item.Reason] {
+			return true
+		}
+	}
+	switch apiErr.Code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// query runs the query and retries it with exponential backoff while it fails with a transient or
+// connection error. The client is recreated when queries keep failing to reach BigQuery.
+// Returns ErrRateLimited when the query is still throttled after the retries.
+func (s *Source) query(ctx context.Context, query string, params ...bigquery.QueryParameter) (rowIterator, error) {
+	delay := s.sourceConfig.Config.RetryDelay
+	for attempt := 0; ; attempt++ {
+		client, generation := s.readClient()
+		it, err := client.Query(ctx, s, query, params...)
+		if err == nil {
+			atomic.StoreInt32(&s.connectionFailures, 0)
+			return countingIterator{rowIterator: it, s: s}, nil
+		}
+		lostConnection := connectionError(err)
+		if lostConnection {
+			s.connectionFailed(ctx, generation)
+		}
+		if attempt >= s.sourceConfig.Config.MaxRetries || !(retryable(err) || lostConnection) {
+			return nil, classifyError(err)
+		}
+
+		// the delay is randomized by up to half, so tables failing together don't retry together
+		wait := delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+		sdk.Logger(ctx).Warn().Str("err", err.Error()).Int("attempt", attempt+1).Dur("delay", wait).
+			Msg("transient error while running query, retrying")
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		delay *= 2
+	}
+}
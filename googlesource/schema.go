@@ -0,0 +1,150 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googlesource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+// ErrColumnMissing is returned when an incrementing or primary key column is not part of the rows read,
+// eg. because it was dropped from the table
+var ErrColumnMissing = errors.New("column missing")
+
+// tableSchema is the last schema read from a table together with its JSON representation
+type tableSchema struct {
+	schema bigquery.Schema
+	json   string
+}
+
+// schemaMetadata returns the JSON representation of the schema of the columns in the payload of the
+// records read from the table. The JSON is cached per table and only encoded again when the schema changed.
+func (s *Source) schemaMetadata(ctx context.Context, tableID string, schema bigquery.Schema) string {
+	previous, ok := s.schemas.Load(tableID)
+	if ok && reflect.DeepEqual(previous.(*tableSchema).schema, schema) {
+		return previous.(*tableSchema).json
+	}
+
+	encoded, err := s.payloadSchema(schema).ToJSONFields()
+	if err != nil {
+		sdk.Logger(ctx).Error().Str("err", err.Error()).Str("tableID", tableID).Msg("Error while encoding the table schema")
+		return ""
+	}
+	if ok {
+		added, dropped, changed := schemaDiff(previous.(*tableSchema).schema, schema)
+		sdk.Logger(ctx).Info().Str("tableID", tableID).Strs("added", added).Strs("dropped", dropped).
+			Strs("changed", changed).Msg("schema of the table changed")
+	}
+	s.schemas.Store(tableID, &tableSchema{schema: schema, json: string(encoded)})
+	return string(encoded)
+}
+
+// payloadSchema returns a copy of the schema without the change function and excluded columns
+func (s *Source) payloadSchema(schema bigquery.Schema) bigquery.Schema {
+	fields := make(bigquery.Schema, 0, len(schema))
+	for _, field := range schema {
+		if field.Name == changeTypeColumn || field.Name == changeTimestampColumn {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	for _, column := range s.sourceConfig.Config.ExcludeColumns {
+		fields = removeField(fields, strings.Split(column, "."))
+	}
+	return fields
+}
+
+// removeField returns a copy of the schema without the field at the path. The schema itself is not
+// modified as it is shared with the iterator.
+func removeField(schema bigquery.Schema, path []string) bigquery.Schema {
+	fields := make(bigquery.Schema, 0, len(schema))
+	for _, field := range schema {
+		if field.Name != path[0] {
+			fields = append(fields, field)
+			continue
+		}
+		if len(path) == 1 {
+			continue
+		}
+		nested := *field
+		nested.Schema = removeField(field.Schema, path[1:])
+		fields = append(fields, &nested)
+	}
+	return fields
+}
+
+// schemaDiff returns the names of the columns added, dropped and changed from the previous to the current schema
+func schemaDiff(previous, current bigquery.Schema) (added, dropped, changed []string) {
+	fields := make(map[string]*bigquery.FieldSchema, len(previous))
+	for _, field := range previous {
+		fields[field.Name] = field
+	}
+	for _, field := range current {
+		before, ok := fields[field.Name]
+		switch {
+		case !ok:
+			added = append(added, field.Name)
+		case !reflect.DeepEqual(before, field):
+			changed = append(changed, field.Name)
+		}
+		delete(fields, field.Name)
+	}
+	for _, field := range previous {
+		if _, ok := fields[field.Name]; ok {
+			dropped = append(dropped, field.Name)
+		}
+	}
+	return added, dropped, changed
+}
+
+// resolveColumns returns the positions of the incrementing columns in the schema and checks the
+// primary key columns are part of it. Columns are looked up by name, so they can move between polls.
+func (s *Source) resolveColumns(tableID string, schema bigquery.Schema, incrementColNames []string, userDefinedOffset, userDefinedKey bool) ([]int, error) {
+	if userDefinedKey {
+		_, err := columnIndexes(tableID, schema, s.sourceConfig.Config.PrimaryKeyColNames)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !userDefinedOffset {
+		return nil, nil
+	}
+	return columnIndexes(tableID, schema, incrementColNames)
+}
+
+// columnIndexes returns the positions of the columns in the schema
+func columnIndexes(tableID string, schema bigquery.Schema, columns []string) ([]int, error) {
+	indexes := make([]int, 0, len(columns))
+	for _, column := range columns {
+		index := -1
+		for i, field := range schema {
+			if field.Name == column {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("%w: %s of table %s", ErrColumnMissing, column, tableID)
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, nil
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/rand"
 	"sync"
 	"time"
 
@@ -27,18 +28,68 @@ import (
 	"gopkg.in/tomb.v2"
 )
 
+// errIteratorStopped is the reason the goroutines reading the tables are stopped with
+var errIteratorStopped = errors.New("iterator is stopped")
+
 type Source struct {
 	sdk.UnimplementedSource
-	bqReadClient bqClient
+	// emitted counts the records sent to the records channel, rowsRead the rows returned by queries
+	// and bytesScanned the bytes processed by them. Kept first for 64-bit atomic alignment
+	emitted      uint64
+	rowsRead     uint64
+	bytesScanned uint64
+	// counted counts the records emitted against maxRows, including the one which exceeded it
+	counted uint64
+	// lastEmitted is the time the last record was sent to the records channel, in Unix nanoseconds
+	lastEmitted int64
+	// bqReadClient is shared by the goroutines reading the tables and recreated when it's dead, so
+	// it's accessed through readClient. clientLock guards it together with clientGeneration and clientClosed
+	bqReadClient       bqClient
+	clientLock         sync.RWMutex
+	clientGeneration   uint64
+	clientClosed       bool
+	connectionFailures int32
+	// connect creates the client tables are read with. The client of clientType is used when nil,
+	// tests set it to a mock
+	connect      func() (bqClient, error)
 	sourceConfig googlebigquery.SourceConfig
-	// for all the function running in goroutine we needed the ctx value. To provide the current
-	// ctx value ctx was required in struct.
-	ctx            context.Context
-	records        chan sdk.Record
-	position       position
-	ticker         *time.Ticker
-	tomb           *tomb.Tomb
-	iteratorClosed bool
+	records      chan sdk.Record
+	position     position
+	ticker       *time.Ticker
+	backoff      pollBackoff
+	tomb         *tomb.Tomb
+	// readSlots bounds the tables and read streams queried at the same time to MaxConcurrentReads
+	readSlots chan struct{}
+	// iteratorClosed is closed by StopIterator, the goroutines reading the tables stop once it is
+	iteratorClosed chan struct{}
+	seenKeys       keyCache
+	knownKeys      keySet
+	// lag tracks how far the tables incremented by time are behind
+	lag lagGauge
+	// now returns the current time. time.Now is used when nil, tests set it to control the clock
+	now func() time.Time
+	// schemas holds the last schema read from every table, keyed by table ID
+	schemas sync.Map
+	// partitionings holds how the tables are partitioned, keyed by table ID
+	partitionings sync.Map
+	// nullOffsets holds the tables rows with NULL incrementing columns were read from
+	nullOffsets sync.Map
+	// seeded holds the tables whose watermark was queried when the snapshot is skipped
+	seeded sync.Map
+	// changeFunctions holds the change function tables fell back to, keyed by table ID
+	changeFunctions sync.Map
+	// boundaries holds the rows read with the current offset, keyed by position key
+	boundaries sync.Map
+	// endOffsets holds the offset of the end position, keyed by table ID
+	endOffsets sync.Map
+	// tablesEnded holds the tables read up to the end position
+	tablesEnded sync.Map
+	// externalWarnings holds the options warned about as unsupported by external tables, keyed by table ID and option
+	externalWarnings sync.Map
+	// materialized holds the temporary tables snapshots were written to, they are dropped once read
+	materialized sync.Map
+	// materializeSuffix makes the temporary tables of this start unique
+	materializeSuffix string
 	// interface to provide BigQuery client. In testing this will be used to mock the client
 	clientType clientFactory
 }
@@ -47,13 +98,79 @@ type Source struct {
 // Ref issue- https://github.com/neha-Gupta1/conduit-connector-bigquery/issues/26
 type position struct {
 	lock      *sync.Mutex
-	positions string
+	positions map[string]string // positions holds the offset of each table keyed by table ID
+	mode      string            // mode is the phase of the sync the last record was read in
+	// snapshotsDone holds the tables whose snapshot was read till the end, even without any row
+	snapshotsDone map[string]bool
+	// legacy is set when the offsets were read from a position of an earlier version, they are migrated on open
+	legacy bool
+}
+
+// keyCache remembers the keys of the last rows read, so a row read again during CDC is emitted as
+// update. The oldest keys are evicted once the cache is full.
+type keyCache struct {
+	lock  sync.Mutex
+	keys  map[string]struct{}
+	order []string // order holds the keys in insertion order, next is the oldest once the cache is full
+	next  int
+}
+
+// seen adds the key of the table to the cache and reports if it was already in it
+func (c *keyCache) seen(tableID string, key []byte, size int) bool {
+	if size <= 0 {
+		return false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry := tableID + "\x00" + string(key)
+	if _, ok := c.keys[entry]; ok {
+		return true
+	}
+	if c.keys == nil {
+		c.keys = make(map[string]struct{}, size)
+	}
+	if len(c.order) < size {
+		c.order = append(c.order, entry)
+	} else {
+		delete(c.keys, c.order[c.next])
+		c.order[c.next] = entry
+		c.next = (c.next + 1) % size
+	}
+	c.keys[entry] = struct{}{}
+	return false
+}
+
+const (
+	// PositionVersion is the version of the position format written to the records
+	PositionVersion = 1
+	// PositionModeSnapshot is the mode of positions written while the tables are snapshot
+	PositionModeSnapshot = "snapshot"
+	// PositionModeCDC is the mode of positions written while polling for changes
+	PositionModeCDC = "cdc"
+)
+
+// Position is the position written to every record. It holds the offsets of all the tables so a
+// restart resumes each table independently.
+type Position struct {
+	Version int               `json:"version"` // Version is the version of the position format
+	Mode    string            `json:"mode"`    // Mode is the phase of the sync, snapshot or cdc
+	Table   string            `json:"table"`   // Table is the table whose offset advanced with the record
+	Offsets map[string]string `json:"offsets"` // Offsets holds the offset of each table keyed by table ID
+	// SnapshotsDone lists the tables whose snapshot was read till the end. Empty tables have no offset,
+	// so they are only told apart from tables never read by it.
+	SnapshotsDone []string `json:"snapshotsDone,omitempty"`
 }
 
 func NewSource() sdk.Source {
 	return &Source{}
 }
 
+// Parameters returns a map of named sdk.Parameters that describe how to configure the Source.
+func (s *Source) Parameters() map[string]sdk.Parameter {
+	return googlebigquery.SourceParameters()
+}
+
 func (s *Source) Configure(ctx context.Context, cfg map[string]string) error {
 	sdk.Logger(ctx).Trace().Msg("Configuring a Source Connector.")
 	sourceConfig, err := googlebigquery.ParseSourceConfig(cfg)
@@ -63,31 +180,58 @@ func (s *Source) Configure(ctx context.Context, cfg map[string]string) error {
 	}
 
 	s.sourceConfig = sourceConfig
-	s.clientType = &client{ctx: ctx, projectID: s.sourceConfig.Config.ProjectID, opts: []option.ClientOption{option.WithCredentialsJSON([]byte(s.sourceConfig.Config.ServiceAccount))}}
+	ctx = googlebigquery.WithLogLevel(ctx, s.sourceConfig.Config.LogLevel)
+	opts, err := s.clientOptions(ctx)
+	if err != nil {
+		sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("invalid credentials provided")
+		return err
+	}
+	s.clientType = &client{
+		ctx:         ctx,
+		projectID:   s.sourceConfig.Config.ProjectID,
+		opts:        opts,
+		storageRead: s.sourceConfig.Config.ReadMode == googlebigquery.ReadModeStorage,
+	}
 	return nil
 }
 
+// clientOptions returns the options used to create the BigQuery client
+func (s *Source) clientOptions(ctx context.Context) ([]option.ClientOption, error) {
+	return googlebigquery.ClientOptions(ctx, s.sourceConfig.Config)
+}
+
 func (s *Source) Open(ctx context.Context, pos sdk.Position) (err error) {
-	s.ctx = ctx
-	fetchPos(s, pos)
+	if s.clientType == nil {
+		return errors.New("source isn't configured, call Configure before Open")
+	}
+	// the goroutines reading the tables are handed ctx, so they honor the configured log level
+	ctx = s.logContext(ctx)
+	if !fetchPos(s, pos) {
+		sdk.Logger(ctx).Info().Msg("Could not get position. Will start with offset 0")
+	}
+	s.materializeSuffix = fmt.Sprintf("_%08x", rand.Uint32())
+	// the first heartbeat is emitted an interval after start
+	s.lastEmitted = s.clock().UnixNano()
 
 	pollingTime := googlebigquery.PollingTime
 
 	// s.records is a buffered channel that contains records
 	//  coming from all the tables which user wants to sync.
-	s.records = make(chan sdk.Record, 100)
-	s.iteratorClosed = false
+	s.records = make(chan sdk.Record, s.bufferSize())
+	s.iteratorClosed = make(chan struct{})
 
 	if len(s.sourceConfig.Config.PollingTime) > 0 {
 		pollingTime, err = time.ParseDuration(s.sourceConfig.Config.PollingTime)
 		if err != nil {
 			sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("error found while getting time.")
-			return errors.New("invalid polling time duration provided")
+			return fmt.Errorf("%w: %q", googlebigquery.ErrInvalidPollingTime, s.sourceConfig.Config.PollingTime)
 		}
 	}
 
 	s.ticker = time.NewTicker(pollingTime)
-	s.tomb = &tomb.Tomb{}
+	s.backoff = newPollBackoff(pollingTime, s.sourceConfig.Config.MaxPollingTime)
+	// the tomb is only created once its goroutines are started, a tomb without goroutines never dies
+	s.tomb = nil
 	client, err := s.clientType.Client()
 	if err != nil {
 		sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("error found while creating connection. ")
@@ -95,19 +239,61 @@ func (s *Source) Open(ctx context.Context, pos sdk.Position) (err error) {
 		return clientErr
 	}
 	bqClient := bqClientStruct{client: client}
+	s.clientLock.Lock()
 	s.bqReadClient = bqClient
+	s.clientClosed = false
+	s.clientLock.Unlock()
 
-	s.tomb.Go(s.runIterator)
+	if err := s.resolveLocation(ctx, bqClient); err != nil {
+		sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("invalid location provided")
+		return err
+	}
+	if err := s.validateTables(ctx, bqClient); err != nil {
+		sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("invalid tables provided")
+		return err
+	}
+	if err := s.migratePosition(ctx, bqClient); err != nil {
+		sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("invalid position provided")
+		return err
+	}
+	if err := s.seedStartPosition(ctx, bqClient); err != nil {
+		sdk.Logger(ctx).Error().Str("err", err.Error()).Msg("invalid start or end position provided")
+		return err
+	}
+
+	// the context of the goroutines is canceled once the source is stopped
+	s.tomb = &tomb.Tomb{}
+	tombCtx := s.tomb.Context(ctx)
+	if s.sourceConfig.Config.DryRun {
+		s.tomb.Go(func() error {
+			return s.runDryRun(tombCtx)
+		})
+		return nil
+	}
+
+	s.tomb.Go(func() error {
+		return s.runIterator(tombCtx)
+	})
 	sdk.Logger(ctx).Trace().Msg("end of function: open")
 	return nil
 }
 
 func (s *Source) Read(ctx context.Context) (sdk.Record, error) {
+	if ctx.Err() != nil {
+		// reading is stopped, the records buffered till now are still returned
+		return s.drain(ctx)
+	}
+	readCtx := ctx
+	ctx = s.logContext(ctx)
 	sdk.Logger(ctx).Trace().Msg("Stated read function")
 	var response sdk.Record
 
-	response, err := s.Next(s.ctx)
+	response, err := s.Next(readCtx)
 	if err != nil {
+		if readCtx.Err() != nil {
+			// reading was stopped while waiting for a record
+			return s.drain(readCtx)
+		}
 		sdk.Logger(ctx).Trace().Str("err", err.Error()).Msg("Error from endpoint.")
 		return sdk.Record{}, err
 	}
@@ -115,38 +301,90 @@ func (s *Source) Read(ctx context.Context) (sdk.Record, error) {
 }
 
 func (s *Source) Ack(ctx context.Context, position sdk.Position) error {
-	sdk.Logger(ctx).Debug().Str("position", string(position)).Msg("got ack")
+	sdk.Logger(s.logContext(ctx)).Debug().Str("position", string(position)).Msg("got ack")
 	return nil
 }
 
+// logContext returns ctx logging with the configured log level
+func (s *Source) logContext(ctx context.Context) context.Context {
+	return googlebigquery.WithLogLevel(ctx, s.sourceConfig.Config.LogLevel)
+}
+
+// drain stops the goroutines reading the tables and returns the records they buffered one by one.
+// The context error is returned once the buffer is empty, which ends reading.
+func (s *Source) drain(ctx context.Context) (sdk.Record, error) {
+	if s.tomb != nil {
+		s.tomb.Kill(errIteratorStopped)
+		<-s.tomb.Dead()
+	}
+	select {
+	case record, ok := <-s.records:
+		if ok {
+			return record, nil
+		}
+	default:
+	}
+	return sdk.Record{}, ctx.Err()
+}
+
 func (s *Source) Teardown(ctx context.Context) error {
-	s.iteratorClosed = true
+	s.stopReading()
 
+	// the records channel is only closed once the goroutines sending to it stopped
+	stopped := true
+	if s.tomb != nil {
+		select {
+		case <-s.tomb.Dead():
+		case <-ctx.Done():
+			stopped = false
+		}
+	}
+	// the temporary tables are dropped with the context of the teardown, the one of open is canceled
+	s.dropMaterializedTables(ctx)
+	err := s.closeClient()
 	if s.records != nil {
-		close(s.records)
+		// Read hands out the buffered records once its context is canceled, Teardown is only called
+		// once Read isn't called anymore
+		if unread := len(s.records); unread > 0 {
+			// positions are only persisted once acked, so the records are read again after a restart
+			sdk.Logger(s.logContext(ctx)).Warn().Int("records", unread).Msg("buffered records weren't read before teardown")
+		}
+		if stopped {
+			close(s.records)
+		}
 	}
-	err := s.StopIterator()
+
 	if err != nil {
-		sdk.Logger(s.ctx).Error().Str("err", err.Error()).Msg("got error while closing BigQuery client")
+		sdk.Logger(s.logContext(ctx)).Error().Str("err", err.Error()).Msg("got error while closing BigQuery client")
 		return err
 	}
 	return nil
 }
 
+// StopIterator stops the goroutines reading the tables and closes the BigQuery client
 func (s *Source) StopIterator() error {
-	s.iteratorClosed = true
-	if s.bqReadClient != nil {
-		err := s.bqReadClient.Close()
-		if err != nil {
-			sdk.Logger(s.ctx).Error().Str("err", err.Error()).Msg("got error while closing BigQuery client")
-			return err
-		}
-	}
+	s.stopReading()
+	return s.closeClient()
+}
+
+// stopReading stops the goroutines reading the tables
+func (s *Source) stopReading() {
+	s.closeIterator()
 	if s.ticker != nil {
 		s.ticker.Stop()
 	}
 	if s.tomb != nil {
-		s.tomb.Kill(errors.New("iterator is stopped"))
+		s.tomb.Kill(errIteratorStopped)
+	}
+}
+
+// closeClient closes the BigQuery client, the tables can't be read anymore afterwards
+func (s *Source) closeClient() error {
+	s.clientLock.Lock()
+	defer s.clientLock.Unlock()
+	s.clientClosed = true
+	if s.bqReadClient != nil {
+		return s.bqReadClient.Close()
 	}
 	return nil
 }
@@ -15,16 +15,36 @@
 package googlesource
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
 	sdk "github.com/conduitio/conduit-connector-sdk"
 	googlebigquery "github.com/neha-Gupta1/conduit-connector-bigquery"
+	"github.com/rs/zerolog"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
 	"gopkg.in/tomb.v2"
 )
 
@@ -164,6 +184,24 @@ func TestInvalidCreds(t *testing.T) {
 	}
 }
 
+func TestOpenNotConfigured(t *testing.T) {
+	src := Source{}
+	ctx := context.Background()
+	// configuring fails without project, so the source can't be opened
+	if err := src.Configure(ctx, map[string]string{googlebigquery.ConfigServiceAccount: "invalid"}); err == nil {
+		t.Fatalf("expected configure error")
+	}
+	if err := src.Open(ctx, sdk.Position{}); err == nil {
+		t.Errorf("expected error for source which isn't configured")
+	}
+	if _, err := src.Read(ctx); err == nil {
+		t.Errorf("expected read error for source which isn't opened")
+	}
+	if err := src.Teardown(ctx); err != nil {
+		t.Errorf("expected no error on teardown, got %v", err)
+	}
+}
+
 func TestNewSource(t *testing.T) {
 	NewSource()
 }
@@ -188,6 +226,439 @@ func TestNextContextDone(t *testing.T) {
 	}
 }
 
+func TestReadBackoffRetryWhenEmpty(t *testing.T) {
+	s := Source{tomb: &tomb.Tomb{}, records: make(chan sdk.Record, 1)}
+
+	start := time.Now()
+	_, err := s.Read(context.Background())
+	if !errors.Is(err, sdk.ErrBackoffRetry) {
+		t.Fatalf("expected ErrBackoffRetry on empty buffer, got %v", err)
+	}
+	// Read waits for a record instead of returning right away
+	if elapsed := time.Since(start); elapsed < nextWait {
+		t.Errorf("expected Read to wait %v for a record, returned after %v", nextWait, elapsed)
+	}
+
+	go func() {
+		time.Sleep(nextWait / 4)
+		s.records <- sdk.Record{Position: sdk.Position("1")}
+	}()
+	record, err := s.Read(context.Background())
+	if err != nil {
+		t.Fatalf("expected record produced while waiting, got %v", err)
+	}
+	if string(record.Position) != "1" {
+		t.Errorf("expected record with position 1, got %q", record.Position)
+	}
+}
+
+func TestReadDrainsBufferedRecords(t *testing.T) {
+	s := Source{tomb: &tomb.Tomb{}, records: make(chan sdk.Record, 3)}
+	// a producer blocked on the full buffer has to stop once the source stops
+	s.tomb.Go(func() error {
+		for i := 0; ; i++ {
+			record := sdk.Record{Payload: sdk.Change{After: sdk.StructuredData{"id": i}}}
+			if !s.emit(context.Background(), record) {
+				return nil
+			}
+		}
+	})
+	for len(s.records) < cap(s.records) {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 3; i++ {
+		record, err := s.Read(ctx)
+		if err != nil {
+			t.Fatalf("expected buffered record %d, got err: %v", i, err)
+		}
+		if got := record.Payload.After.(sdk.StructuredData)["id"]; got != i {
+			t.Errorf("expected record %d, got %v", i, got)
+		}
+	}
+	_, err := s.Read(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context canceled once drained, got %v", err)
+	}
+
+	err = s.Teardown(context.Background())
+	if err != nil {
+		t.Errorf("expected no error on teardown, got %v", err)
+	}
+	if _, ok := <-s.records; ok {
+		t.Errorf("expected records channel to be closed")
+	}
+}
+
+func TestTeardownDoesntWaitForUnreadRecords(t *testing.T) {
+	var logs bytes.Buffer
+	s := Source{tomb: &tomb.Tomb{}, records: make(chan sdk.Record, 3)}
+	s.tomb.Go(func() error {
+		for i := 0; ; i++ {
+			record := sdk.Record{Payload: sdk.Change{After: sdk.StructuredData{"id": i}}}
+			if !s.emit(context.Background(), record) {
+				return nil
+			}
+		}
+	})
+	for len(s.records) < cap(s.records) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Read isn't called anymore once Teardown is called, nothing reads the buffered records
+	done := make(chan error)
+	go func() {
+		done <- s.Teardown(zerolog.New(&logs).WithContext(context.Background()))
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected no error on teardown, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected teardown to return without the buffered records being read")
+	}
+	if !strings.Contains(logs.String(), `"records":3`) {
+		t.Errorf("expected the unread records to be logged, got %s", logs.String())
+	}
+}
+
+func TestTeardownKeepsRecordsOfRunningProducer(t *testing.T) {
+	s := Source{tomb: &tomb.Tomb{}, records: make(chan sdk.Record, 1)}
+	block := make(chan struct{})
+	s.tomb.Go(func() error {
+		<-block
+		return nil
+	})
+	s.records <- sdk.Record{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := s.Teardown(ctx)
+	if err != nil {
+		t.Errorf("expected no error on teardown, got %v", err)
+	}
+	// the producer is still running so the channel can't be closed yet
+	if len(s.records) != 1 {
+		t.Errorf("expected buffered record to be kept, got %d records", len(s.records))
+	}
+	close(block)
+}
+
+// mockSlowIterator returns a row every delay, without ever reaching the end of the page
+type mockSlowIterator struct {
+	delay time.Duration
+	index int64
+}
+
+func (it *mockSlowIterator) Next(dst interface{}) error {
+	time.Sleep(it.delay)
+	it.index++
+	*dst.(*[]bigquery.Value) = []bigquery.Value{it.index}
+	return nil
+}
+
+func (it *mockSlowIterator) Schema() bigquery.Schema {
+	return bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}}
+}
+
+type mockSlowClient struct{}
+
+func (bq mockSlowClient) Query(ctx context.Context, s *Source, query string, params ...bigquery.QueryParameter) (it rowIterator, err error) {
+	return &mockSlowIterator{delay: time.Millisecond}, nil
+}
+
+func (bq mockSlowClient) Tables(ctx context.Context, s *Source) (tableIDs []string, err error) {
+	return nil, nil
+}
+
+func (bq mockSlowClient) Close() error {
+	return nil
+}
+
+func TestStopIteratorStopsReadingPage(t *testing.T) {
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.bqReadClient = mockSlowClient{}
+	src.records = make(chan sdk.Record, 10000)
+	src.iteratorClosed = make(chan struct{})
+	fetchPos(&src, sdk.Position{})
+
+	done := make(chan error)
+	go func() {
+		done <- src.ReadGoogleRow(context.Background(), "table1")
+	}()
+	for len(src.records) == 0 {
+		select {
+		case err := <-done:
+			t.Fatalf("expected read to run till stopped, got %v", err)
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	// no tomb is set, the goroutine has to observe the closed channel
+	err := src.StopIterator()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	case <-time.After(100 * time.Millisecond):
+		t.Fatalf("expected read to stop right after StopIterator")
+	}
+
+	// stopping twice doesn't close the channel again
+	err = src.StopIterator()
+	if err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestReadGoogleRowFullChannelContextCanceled(t *testing.T) {
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.bqReadClient = mockTableClient{
+		schema: bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}},
+		tables: map[string][][]bigquery.Value{
+			"table1": {{int64(1)}, {int64(2)}},
+		},
+	}
+	src.records = make(chan sdk.Record, 1)
+	src.records <- sdk.Record{}
+	src.tomb = &tomb.Tomb{}
+	ctx, cancel := context.WithCancel(context.Background())
+	fetchPos(&src, sdk.Position{})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- src.ReadGoogleRow(ctx, "table1")
+	}()
+	// the first row is blocked on the full channel by now
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil && !errors.Is(err, context.Canceled) {
+			t.Errorf("expected no error or context canceled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected read blocked on the full channel to return once the context is canceled")
+	}
+	if len(src.records) != 1 {
+		t.Errorf("expected no record added to the full channel, got %d records", len(src.records))
+	}
+}
+
+func TestClientOptionsDefaultCredentials(t *testing.T) {
+	ctx := context.Background()
+	src := Source{}
+	if opts, err := src.clientOptions(ctx); err != nil || len(opts) != 1 {
+		t.Errorf("expected only the scopes option for default credentials, got %v, err %v", opts, err)
+	}
+
+	src.sourceConfig.Config.ServiceAccount = "/path/to/key.json"
+	if opts, err := src.clientOptions(ctx); err != nil || len(opts) != 2 {
+		t.Errorf("expected credentials option, got %v, err %v", opts, err)
+	}
+}
+
+func TestClientOptionsInlineServiceAccount(t *testing.T) {
+	src := Source{}
+	src.sourceConfig.Config.ServiceAccount = ` {"type": "service_account"}`
+
+	opts, err := src.clientOptions(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// serviceAccount held the key as JSON before it accepted paths
+	if len(opts) != 2 || !reflect.DeepEqual(opts[0], option.WithCredentialsJSON([]byte(src.sourceConfig.Config.ServiceAccount))) {
+		t.Errorf("expected JSON credentials option, got %v", opts)
+	}
+
+	src.sourceConfig.Config.ServiceAccount = "/path/to/key.json"
+	opts, err = src.clientOptions(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(opts) != 2 || !reflect.DeepEqual(opts[0], option.WithCredentialsFile(src.sourceConfig.Config.ServiceAccount)) {
+		t.Errorf("expected key file credentials option, got %v", opts)
+	}
+}
+
+func TestClientOptionsPreferJSON(t *testing.T) {
+	src := Source{}
+	src.sourceConfig.Config.ServiceAccount = "/path/to/key.json"
+	src.sourceConfig.Config.ServiceAccountJSON = `{"type": "service_account"}`
+
+	opts, err := src.clientOptions(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(opts) != 2 {
+		t.Fatalf("expected a credentials and a scopes option, got %v", opts)
+	}
+	if !reflect.DeepEqual(opts[0], option.WithCredentialsJSON([]byte(src.sourceConfig.Config.ServiceAccountJSON))) {
+		t.Errorf("expected JSON credentials option, got %v", opts[0])
+	}
+}
+
+func TestClientOptionsBase64(t *testing.T) {
+	credentials := `{"type": "service_account"}`
+	src := Source{}
+	src.sourceConfig.Config.ServiceAccount = "/path/to/key.json"
+	src.sourceConfig.Config.ServiceAccountBase64 = base64.StdEncoding.EncodeToString([]byte(credentials))
+
+	opts, err := src.clientOptions(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(opts) != 2 || !reflect.DeepEqual(opts[0], option.WithCredentialsJSON([]byte(credentials))) {
+		t.Errorf("expected decoded JSON credentials option, got %v", opts)
+	}
+
+	src.sourceConfig.Config.ServiceAccountBase64 = "not base64!"
+	if _, err := src.clientOptions(context.Background()); err == nil {
+		t.Errorf("expected error for malformed base64")
+	}
+}
+
+func TestClientOptionsScopes(t *testing.T) {
+	src := Source{}
+	opts, err := src.clientOptions(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(opts, []option.ClientOption{option.WithScopes(bigquery.Scope)}) {
+		t.Errorf("expected default BigQuery scope, got %v", opts)
+	}
+
+	src.sourceConfig.Config.Scopes = []string{"scope1", "scope2"}
+	opts, err = src.clientOptions(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(opts, []option.ClientOption{option.WithScopes("scope1", "scope2")}) {
+		t.Errorf("expected configured scopes, got %v", opts)
+	}
+}
+
+func TestClientOptionsEndpoint(t *testing.T) {
+	src := Source{}
+	src.sourceConfig.Config.Endpoint = "http://localhost:9050"
+	opts, err := src.clientOptions(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := []option.ClientOption{option.WithScopes(bigquery.Scope), option.WithEndpoint("http://localhost:9050")}
+	if !reflect.DeepEqual(opts, want) {
+		t.Errorf("expected scopes and endpoint options, got %v", opts)
+	}
+
+	src.sourceConfig.Config.ServiceAccount = "/path/to/key.json"
+	src.sourceConfig.Config.Insecure = true
+	opts, err = src.clientOptions(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want = []option.ClientOption{option.WithEndpoint("http://localhost:9050"), option.WithoutAuthentication()}
+	if !reflect.DeepEqual(opts, want) {
+		t.Errorf("expected endpoint without authentication, got %v", opts)
+	}
+}
+
+func TestNewQueryLabels(t *testing.T) {
+	client, err := bigquery.NewClient(context.Background(), "project", option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer client.Close()
+
+	src := Source{}
+	src.sourceConfig.Config.Location = "US"
+	src.sourceConfig.Config.QueryLabels = map[string]string{"team": "data-eng", "pipeline": "orders"}
+	params := []bigquery.QueryParameter{{Name: "offset", Value: "5"}}
+
+	q := src.newQuery(client, "SELECT 1", params)
+	if !reflect.DeepEqual(q.Labels, src.sourceConfig.Config.QueryLabels) {
+		t.Errorf("expected labels %v, got %v", src.sourceConfig.Config.QueryLabels, q.Labels)
+	}
+	if q.Location != "US" || !reflect.DeepEqual(q.Parameters, params) {
+		t.Errorf("expected location and parameters to be set, got %v and %v", q.Location, q.Parameters)
+	}
+}
+
+func TestNewQueryMaxBytesBilled(t *testing.T) {
+	client, err := bigquery.NewClient(context.Background(), "project", option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer client.Close()
+
+	src := Source{}
+	if q := src.newQuery(client, "SELECT 1", nil); q.MaxBytesBilled != 0 {
+		t.Errorf("expected no limit by default, got %v", q.MaxBytesBilled)
+	}
+
+	src.sourceConfig.Config.MaxBytesBilled = 1 << 30
+	if q := src.newQuery(client, "SELECT 1", nil); q.MaxBytesBilled != 1<<30 {
+		t.Errorf("expected limit of %v bytes, got %v", 1<<30, q.MaxBytesBilled)
+	}
+}
+
+func TestNewQueryPriority(t *testing.T) {
+	client, err := bigquery.NewClient(context.Background(), "project", option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer client.Close()
+
+	src := Source{}
+	if q := src.newQuery(client, "SELECT 1", nil); q.Priority != bigquery.InteractivePriority {
+		t.Errorf("expected interactive priority by default, got %v", q.Priority)
+	}
+
+	src.sourceConfig.Config.QueryPriority = googlebigquery.QueryPriorityBatch
+	if q := src.newQuery(client, "SELECT 1", nil); q.Priority != bigquery.BatchPriority {
+		t.Errorf("expected batch priority, got %v", q.Priority)
+	}
+}
+func TestNewQueryCache(t *testing.T) {
+	client, err := bigquery.NewClient(context.Background(), "project", option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer client.Close()
+
+	src := Source{}
+	src.sourceConfig.Config.UseQueryCache = true
+	if q := src.newQuery(client, "SELECT 1", nil); q.DisableQueryCache {
+		t.Errorf("expected query cache to be used")
+	}
+
+	src.sourceConfig.Config.UseQueryCache = false
+	if q := src.newQuery(client, "SELECT 1", nil); !q.DisableQueryCache {
+		t.Errorf("expected query cache to be disabled")
+	}
+}
+
+func TestClientOptionsImpersonationWithoutBaseCredentials(t *testing.T) {
+	src := Source{}
+	src.sourceConfig.Config.ServiceAccount = "/path/does/not/exist.json"
+	src.sourceConfig.Config.ImpersonateServiceAccount = "target@project.iam.gserviceaccount.com"
+
+	if _, err := src.clientOptions(context.Background()); err == nil {
+		t.Errorf("expected error when base credentials can't be resolved")
+	}
+}
+
 type mockClient struct {
 }
 
@@ -195,6 +666,21 @@ func (client *mockClient) Client() (*bigquery.Client, error) {
 	return nil, fmt.Errorf("mock error")
 }
 
+func TestOpenBufferSize(t *testing.T) {
+	src := Source{}
+	src.sourceConfig.Config.BufferSize = 250
+	// the records channel is created before the client
+	src.clientType = &mockClient{}
+	err := src.Open(context.Background(), nil)
+	if err == nil {
+		t.Errorf("expected mock error, got nil")
+	}
+	if cap(src.records) != 250 {
+		t.Errorf("expected records channel with capacity 250, got %d", cap(src.records))
+	}
+	src.ticker.Stop()
+}
+
 func TestInvalid(t *testing.T) {
 	googlebigquery.PollingTime = time.Second * 1
 
@@ -258,18 +744,25 @@ func TestInvalidOrderByName(t *testing.T) {
 		googlebigquery.ConfigPrimaryKeyColName:  "post_abbr",
 	}
 
+	// without a table ID all the tables of the dataset are synced
 	ctx := context.Background()
 	err = src.Configure(ctx, cfg)
-	if err == nil {
-		t.Log("expected error. got null")
-		t.Errorf("some other error found: %v", err)
+	if err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if len(src.sourceConfig.Config.TableIDs) != 0 {
+		t.Errorf("expected no table IDs, got %v", src.sourceConfig.Config.TableIDs)
 	}
 }
 
 type mockBQClientStruct struct {
 }
 
-func (bq mockBQClientStruct) Query(s *Source, query string) (it rowIterator, err error) {
+func (bq mockBQClientStruct) Query(ctx context.Context, s *Source, query string, params ...bigquery.QueryParameter) (it rowIterator, err error) {
+	return nil, fmt.Errorf("mock error")
+}
+
+func (bq mockBQClientStruct) Tables(ctx context.Context, s *Source) (tableIDs []string, err error) {
 	return nil, fmt.Errorf("mock error")
 }
 
@@ -295,10 +788,4892 @@ func TestInvalidCloseBQ(t *testing.T) {
 		t.Errorf("expected no error, got %v", err)
 	}
 	src.bqReadClient = mockBQClientStruct{}
-	src.ctx = ctx
 
 	err = src.Teardown(ctx)
 	if err == nil {
 		t.Errorf("mock error expected, got %v", err)
 	}
 }
+
+// runCDCIteratorInTomb runs the iterator inside the tomb like runIterator does and waits for all the
+// goroutines to finish
+func runCDCIteratorInTomb(src *Source) error {
+	src.tomb.Go(func() error {
+		return src.runCDCIterator(src.tomb.Context(context.Background()))
+	})
+	return src.tomb.Wait()
+}
+
+// startIterator runs runIterator in the tomb with the context of the tomb like Open does
+func startIterator(src *Source) {
+	ctx := src.tomb.Context(context.Background())
+	src.tomb.Go(func() error {
+		return src.runIterator(ctx)
+	})
+}
+
+type mockRowIterator struct {
+	rows   [][]bigquery.Value
+	schema bigquery.Schema
+	index  int
+}
+
+func (it *mockRowIterator) Next(dst interface{}) error {
+	if it.index >= len(it.rows) {
+		return iterator.Done
+	}
+	*dst.(*[]bigquery.Value) = it.rows[it.index]
+	it.index++
+	return nil
+}
+
+func (it *mockRowIterator) Schema() bigquery.Schema {
+	return it.schema
+}
+
+// mockTableClient returns the rows of the table referenced in the query. If queries or params
+// are set the received queries and parameters are recorded
+type mockTableClient struct {
+	tables  map[string][][]bigquery.Value
+	schema  bigquery.Schema
+	queries *[]string
+	params  *[]bigquery.QueryParameter
+}
+
+func (bq mockTableClient) Query(ctx context.Context, s *Source, query string, params ...bigquery.QueryParameter) (it rowIterator, err error) {
+	if bq.queries != nil {
+		*bq.queries = append(*bq.queries, query)
+	}
+	if bq.params != nil {
+		*bq.params = append(*bq.params, params...)
+	}
+	for tableID, rows := range bq.tables {
+		if strings.Contains(query, "."+tableID+"`") {
+			schema, rows := project(query, bq.schema, rows)
+			return &mockRowIterator{rows: rows, schema: schema}, nil
+		}
+	}
+	return nil, fmt.Errorf("table not found in query %s", query)
+}
+
+// project keeps only the columns selected in the query
+func project(query string, schema bigquery.Schema, rows [][]bigquery.Value) (bigquery.Schema, [][]bigquery.Value) {
+	selected := query[len("SELECT "):strings.Index(query, " FROM ")]
+	// the schema of tables read with their pseudo columns holds them
+	if selected == "*" || strings.HasPrefix(selected, "*, ") {
+		return schema, rows
+	}
+
+	var indexes []int
+	var projectedSchema bigquery.Schema
+	for _, column := range strings.Split(selected, ", ") {
+		column = strings.Trim(column, "`")
+		for i, field := range schema {
+			if field.Name == column {
+				indexes = append(indexes, i)
+				projectedSchema = append(projectedSchema, field)
+			}
+		}
+	}
+
+	projectedRows := make([][]bigquery.Value, 0, len(rows))
+	for _, row := range rows {
+		projectedRow := make([]bigquery.Value, 0, len(indexes))
+		for _, i := range indexes {
+			projectedRow = append(projectedRow, row[i])
+		}
+		projectedRows = append(projectedRows, projectedRow)
+	}
+	return projectedSchema, projectedRows
+}
+
+func (bq mockTableClient) Tables(ctx context.Context, s *Source) (tableIDs []string, err error) {
+	for tableID := range bq.tables {
+		tableIDs = append(tableIDs, tableID)
+	}
+	sort.Strings(tableIDs)
+	return tableIDs, nil
+}
+
+func (bq mockTableClient) Close() error {
+	return nil
+}
+
+func TestRunCDCIteratorMultipleTables(t *testing.T) {
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.TableIDs = []string{"table1", "table2"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.bqReadClient = mockTableClient{
+		schema: bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}},
+		tables: map[string][][]bigquery.Value{
+			"table1": {{int64(1)}, {int64(2)}},
+			"table2": {{int64(1)}, {int64(2)}, {int64(3)}},
+		},
+	}
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	err := runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(src.records) != 5 {
+		t.Fatalf("expected 5 records, got %v", len(src.records))
+	}
+
+	var lastPosition sdk.Position
+	for len(src.records) > 0 {
+		record := <-src.records
+		lastPosition = record.Position
+
+		table := record.Metadata[MetadataTable]
+		if table != "table1" && table != "table2" {
+			t.Errorf("expected table metadata, got %v", record.Metadata)
+		}
+		if record.Metadata[MetadataCollection] != table ||
+			record.Metadata[MetadataDataset] != "dataset" ||
+			record.Metadata[MetadataProject] != "project" {
+			t.Errorf("expected collection, dataset and project metadata, got %v", record.Metadata)
+		}
+	}
+	var position Position
+	err = json.Unmarshal(lastPosition, &position)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if position.Offsets["table1"] != "INT64 2" || position.Offsets["table2"] != "INT64 3" {
+		t.Errorf("expected offsets per table, got %v", position.Offsets)
+	}
+	if position.Version != PositionVersion || position.Mode != PositionModeSnapshot {
+		t.Errorf("expected version %v and snapshot mode, got %v", PositionVersion, position)
+	}
+}
+
+func TestRunCDCIteratorSchemaMetadata(t *testing.T) {
+	user := &bigquery.FieldSchema{Name: "user", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+		{Name: "name", Type: bigquery.StringFieldType},
+		{Name: "email", Type: bigquery.StringFieldType},
+	}}
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.ExcludeColumns = []string{"user.email"}
+	src.bqReadClient = mockTableClient{
+		schema: bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType, Required: true}, user},
+		tables: map[string][][]bigquery.Value{
+			"table1": {{int64(1), []bigquery.Value{"alice", "alice@example.com"}}},
+		},
+	}
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	err := runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(src.records) != 1 {
+		t.Fatalf("expected 1 record, got %v", len(src.records))
+	}
+
+	record := <-src.records
+	schema, err := bigquery.SchemaFromJSON([]byte(record.Metadata[MetadataSchema]))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := bigquery.Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType, Required: true},
+		{Name: "user", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{{Name: "name", Type: bigquery.StringFieldType}}},
+	}
+	if !reflect.DeepEqual(schema, expected) {
+		t.Errorf("expected schema %v, got %v", expected, schema)
+	}
+	if len(user.Schema) != 2 {
+		t.Errorf("expected the queried schema to keep the excluded field, got %v", user.Schema)
+	}
+
+	// a column added to the table shows up in the metadata of the next records
+	changed := bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}, {Name: "name", Type: bigquery.StringFieldType}}
+	schema, err = bigquery.SchemaFromJSON([]byte(src.schemaMetadata(context.Background(), "table1", changed)))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(schema, changed) {
+		t.Errorf("expected schema %v, got %v", changed, schema)
+	}
+}
+
+func TestFetchPos(t *testing.T) {
+	testCases := []struct {
+		name     string
+		position string
+		offsets  map[string]string
+		mode     string
+	}{
+		{
+			name:     "versioned position",
+			position: `{"version":1,"mode":"cdc","offsets":{"table1":"INT64 2","table2":"DATE 2022-01-02"}}`,
+			offsets:  map[string]string{"table1": "INT64 2", "table2": "DATE 2022-01-02"},
+			mode:     PositionModeCDC,
+		},
+		{
+			name:     "legacy offsets per table",
+			position: `{"table1":"INT64 2","version":"INT64 3"}`,
+			offsets:  map[string]string{"table1": "INT64 2", "version": "INT64 3"},
+		},
+		{
+			name:     "legacy bare offset",
+			position: `"46"`,
+			offsets:  map[string]string{"table1": "46", "table2": "46"},
+		},
+		{
+			name:     "empty position",
+			position: ``,
+			offsets:  map[string]string{},
+		},
+		{
+			name:     "unsupported version",
+			position: `{"version":2,"mode":"cdc","offsets":{"table1":"INT64 2"}}`,
+			offsets:  map[string]string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		src := Source{}
+		src.sourceConfig.Config.TableIDs = []string{"table1", "table2"}
+		fetchPos(&src, sdk.Position(tc.position))
+
+		if !reflect.DeepEqual(src.position.positions, tc.offsets) {
+			t.Errorf("%s: expected offsets %v, got %v", tc.name, tc.offsets, src.position.positions)
+		}
+		if src.position.mode != tc.mode {
+			t.Errorf("%s: expected mode %q, got %q", tc.name, tc.mode, src.position.mode)
+		}
+	}
+}
+
+func TestMigratePosition(t *testing.T) {
+	schema := bigquery.Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType},
+		{Name: "created_at", Type: bigquery.TimestampFieldType},
+	}
+	testCases := []struct {
+		name      string
+		position  string
+		increment string
+		offset    string
+		condition string
+	}{
+		{
+			name:      "timestamp literal",
+			position:  `"'2022-01-01 10:00:00 +0000 UTC'"`,
+			increment: "created_at",
+			offset:    "TIMESTAMP 2022-01-01 10:00:00+00:00",
+			condition: "WHERE `created_at` >= CAST(@offset AS TIMESTAMP)",
+		},
+		{
+			name:      "timestamp literal without zone offset",
+			position:  `"'2022-01-01 10:00:00 UTC'"`,
+			increment: "created_at",
+			offset:    "TIMESTAMP 2022-01-01 10:00:00+00:00",
+			condition: "WHERE `created_at` >= CAST(@offset AS TIMESTAMP)",
+		},
+		{
+			name:      "integer literal",
+			position:  `"5"`,
+			increment: "id",
+			offset:    "INT64 5",
+			condition: "WHERE `id` > CAST(@offset AS INT64)",
+		},
+		{
+			name:      "typed offsets per table",
+			position:  `{"table1":"INT64 2"}`,
+			increment: "id",
+			offset:    "INT64 2",
+			condition: "WHERE `id` > CAST(@offset AS INT64)",
+		},
+	}
+
+	for _, tc := range testCases {
+		var queries []string
+		var params []bigquery.QueryParameter
+		src := Source{}
+		src.sourceConfig.Config.ProjectID = "project"
+		src.sourceConfig.Config.DatasetID = "dataset"
+		src.sourceConfig.Config.TableIDs = []string{"table1"}
+		src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+		src.sourceConfig.Config.IncrementColNames = []string{tc.increment}
+		src.bqReadClient = mockTableClient{tables: map[string][][]bigquery.Value{"table1": nil}, schema: schema, queries: &queries, params: &params}
+		src.records = make(chan sdk.Record, 10)
+		fetchPos(&src, sdk.Position(tc.position))
+
+		if err := src.migratePosition(context.Background(), mockMetadataClient{tables: map[string]bool{"table1": true}, schema: schema}); err != nil {
+			t.Fatalf("%s: expected no error, got %v", tc.name, err)
+		}
+		if offset := src.getPosition("table1"); offset != tc.offset {
+			t.Errorf("%s: expected offset %q, got %q", tc.name, tc.offset, offset)
+		}
+
+		// the row query casts the migrated value to the type of the column
+		if err := src.ReadGoogleRow(context.Background(), "table1"); err != nil {
+			t.Fatalf("%s: expected no error, got %v", tc.name, err)
+		}
+		if len(queries) != 1 || !strings.Contains(queries[0], tc.condition) {
+			t.Errorf("%s: expected query with %q, got %q", tc.name, tc.condition, queries)
+		}
+		_, value := parseOffset(tc.offset)
+		if len(params) != 1 || params[0].Value != value {
+			t.Errorf("%s: expected offset parameter %q, got %v", tc.name, value, params)
+		}
+	}
+
+	// tables without incrementing column were read with OFFSET, the position counts the rows read
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	fetchPos(&src, sdk.Position(`"46"`))
+	err := src.migratePosition(context.Background(), mockMetadataClient{tables: map[string]bool{"table1": true}, schema: schema})
+	if !errors.Is(err, ErrLegacyPosition) {
+		t.Errorf("expected ErrLegacyPosition for a row counter, got %v", err)
+	}
+
+	// versioned positions are typed already
+	fetchPos(&src, sdk.Position(`{"version":1,"mode":"cdc","offsets":{"table1":"46"}}`))
+	if err := src.migratePosition(context.Background(), mockMetadataClient{}); err != nil {
+		t.Errorf("expected versioned position to be kept, got %v", err)
+	}
+}
+
+func TestGetTablesDiscoversDataset(t *testing.T) {
+	src := Source{}
+	src.bqReadClient = mockTableClient{
+		tables: map[string][][]bigquery.Value{
+			"orders":     nil,
+			"orders_tmp": nil,
+			"customers":  nil,
+			"events":     nil,
+		},
+	}
+
+	tables, err := src.getTables(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(tables, []string{"customers", "events", "orders", "orders_tmp"}) {
+		t.Errorf("expected all tables of dataset, got %v", tables)
+	}
+
+	src.sourceConfig.Config.TableIncludeRegex = regexp.MustCompile("^(orders|customers)")
+	src.sourceConfig.Config.TableExcludeRegex = regexp.MustCompile("_tmp$")
+	tables, err = src.getTables(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(tables, []string{"customers", "orders"}) {
+		t.Errorf("expected filtered tables, got %v", tables)
+	}
+
+	src.sourceConfig.Config.TableIDs = []string{"events"}
+	tables, err = src.getTables(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(tables, []string{"events"}) {
+		t.Errorf("expected configured tables, got %v", tables)
+	}
+}
+
+// mockListErrorClient fails listing the tables of the dataset
+type mockListErrorClient struct {
+	mockTableClient
+}
+
+func (bq mockListErrorClient) Tables(ctx context.Context, s *Source) (tableIDs []string, err error) {
+	return nil, errors.New("dataset listing failed")
+}
+
+func TestRunIteratorWithoutTableIDs(t *testing.T) {
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.bqReadClient = mockListErrorClient{}
+	src.records = make(chan sdk.Record, 10)
+	src.ticker = time.NewTicker(5 * time.Millisecond)
+	defer src.ticker.Stop()
+	src.backoff = newPollBackoff(5*time.Millisecond, 0)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	// the tables of the dataset are listed without table ID, its error is returned instead of panicking
+	err := src.runIterator(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "error while listing tables of dataset dataset: dataset listing failed") {
+		t.Errorf("expected listing error, got %v", err)
+	}
+}
+
+func TestGetRowIteratorSamplePercent(t *testing.T) {
+	var queries []string
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.SamplePercent = 12.5
+	src.bqReadClient = mockQueryClient{queries: &queries}
+
+	if _, err := src.getRowIterator(context.Background(), "", "", "table1", "", true, 0); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := src.getRowIterator(context.Background(), "INT64 5", "", "table1", "", false, 0); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := []string{
+		"SELECT * FROM `project.dataset.table1` TABLESAMPLE SYSTEM (12.5 PERCENT) ORDER BY `id` LIMIT 501",
+		"SELECT * FROM `project.dataset.table1` TABLESAMPLE SYSTEM (12.5 PERCENT) WHERE `id` > CAST(@offset AS INT64) ORDER BY `id` LIMIT 501",
+	}
+	if !reflect.DeepEqual(queries, want) {
+		t.Errorf("expected queries %q, got %q", want, queries)
+	}
+}
+
+func TestGetRowIteratorIncrementColumnPerTable(t *testing.T) {
+	var queries []string
+	src := Source{}
+	src.sourceConfig.Config.IncrementColNames = []string{"updated_at"}
+	src.sourceConfig.Config.TableIncrementColNames = map[string][]string{"table2": {"id"}}
+	src.bqReadClient = mockQueryClient{queries: &queries}
+
+	_, err := src.getRowIterator(context.Background(), "STRING 2022-01-01", "", "table1", "", false, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	_, err = src.getRowIterator(context.Background(), "INT64 5", "", "table2", "", false, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if !strings.Contains(queries[0], "WHERE `updated_at` > CAST(@offset AS STRING) ORDER BY `updated_at`") {
+		t.Errorf("expected default increment column in query, got %v", queries[0])
+	}
+	if !strings.Contains(queries[1], "WHERE `id` > CAST(@offset AS INT64) ORDER BY `id`") {
+		t.Errorf("expected table increment column in query, got %v", queries[1])
+	}
+}
+
+// mockQueryClient records the queries it receives and returns no rows
+type mockQueryClient struct {
+	queries *[]string
+}
+
+func (bq mockQueryClient) Query(ctx context.Context, s *Source, query string, params ...bigquery.QueryParameter) (it rowIterator, err error) {
+	*bq.queries = append(*bq.queries, query)
+	return &mockRowIterator{}, nil
+}
+
+func (bq mockQueryClient) Tables(ctx context.Context, s *Source) (tableIDs []string, err error) {
+	return nil, nil
+}
+
+func (bq mockQueryClient) Close() error {
+	return nil
+}
+
+// mockConcurrencyClient tracks how many queries run at the same time
+type mockConcurrencyClient struct {
+	active    *int32
+	maxActive *int32
+	queried   *int32
+}
+
+func (bq mockConcurrencyClient) Query(ctx context.Context, s *Source, query string, params ...bigquery.QueryParameter) (it rowIterator, err error) {
+	active := atomic.AddInt32(bq.active, 1)
+	defer atomic.AddInt32(bq.active, -1)
+	for {
+		maxActive := atomic.LoadInt32(bq.maxActive)
+		if active <= maxActive || atomic.CompareAndSwapInt32(bq.maxActive, maxActive, active) {
+			break
+		}
+	}
+	atomic.AddInt32(bq.queried, 1)
+	time.Sleep(20 * time.Millisecond)
+	return &mockRowIterator{}, nil
+}
+
+func (bq mockConcurrencyClient) Tables(ctx context.Context, s *Source) (tableIDs []string, err error) {
+	return nil, nil
+}
+
+func (bq mockConcurrencyClient) Close() error {
+	return nil
+}
+
+func TestRunCDCIteratorMaxConcurrentReads(t *testing.T) {
+	var active, maxActive, queried int32
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1", "table2", "table3", "table4", "table5", "table6"}
+	src.sourceConfig.Config.MaxConcurrentReads = 2
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.bqReadClient = mockConcurrencyClient{active: &active, maxActive: &maxActive, queried: &queried}
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	err := runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if queried != 6 {
+		t.Errorf("expected all 6 tables to be queried, got %v", queried)
+	}
+	if maxActive > 2 {
+		t.Errorf("expected at most 2 concurrent queries, got %v", maxActive)
+	}
+}
+
+func TestRunCDCIteratorPropagatesError(t *testing.T) {
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1", "table2", "table3"}
+	src.sourceConfig.Config.MaxConcurrentReads = 1
+	src.bqReadClient = mockBQClientStruct{}
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	err := runCDCIteratorInTomb(&src)
+	if err == nil {
+		t.Errorf("expected mock error, got nil")
+	}
+}
+
+func TestReadGoogleRowOperation(t *testing.T) {
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.bqReadClient = mockTableClient{
+		schema: bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}},
+		tables: map[string][][]bigquery.Value{
+			"table1": {{int64(1)}, {int64(2)}},
+		},
+	}
+	src.records = make(chan sdk.Record, 10)
+	fetchPos(&src, sdk.Position{})
+
+	// first batch is the snapshot
+	src.tomb = &tomb.Tomb{}
+	err := runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(src.records) != 2 {
+		t.Fatalf("expected 2 records, got %v", len(src.records))
+	}
+	for len(src.records) > 0 {
+		if record := <-src.records; record.Operation != sdk.OperationSnapshot {
+			t.Errorf("expected snapshot operation, got %v", record.Operation)
+		}
+	}
+
+	// rows found on later ticks are created rows
+	src.tomb = &tomb.Tomb{}
+	err = runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(src.records) == 0 {
+		t.Fatalf("expected records on second tick")
+	}
+	for len(src.records) > 0 {
+		if record := <-src.records; record.Operation != sdk.OperationCreate {
+			t.Errorf("expected create operation, got %v", record.Operation)
+		}
+	}
+}
+
+func TestReadGoogleRowSchemaChange(t *testing.T) {
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.IncrementColNames = []string{"id"}
+	src.bqReadClient = mockTableClient{
+		schema: bigquery.Schema{
+			{Name: "id", Type: bigquery.IntegerFieldType},
+			{Name: "name", Type: bigquery.StringFieldType},
+		},
+		tables: map[string][][]bigquery.Value{
+			"table1": {{int64(1), "a"}, {int64(2), "b"}},
+		},
+	}
+	src.records = make(chan sdk.Record, 10)
+	fetchPos(&src, sdk.Position{})
+
+	src.tomb = &tomb.Tomb{}
+	err := runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(src.records) != 2 {
+		t.Fatalf("expected 2 records, got %v", len(src.records))
+	}
+	for len(src.records) > 0 {
+		<-src.records
+	}
+
+	// a column added in front of the table moves the incrementing column
+	src.bqReadClient = mockTableClient{
+		schema: bigquery.Schema{
+			{Name: "email", Type: bigquery.StringFieldType},
+			{Name: "id", Type: bigquery.IntegerFieldType},
+			{Name: "name", Type: bigquery.StringFieldType},
+		},
+		tables: map[string][][]bigquery.Value{
+			"table1": {{"c@example.com", int64(3), "c"}},
+		},
+	}
+	src.tomb = &tomb.Tomb{}
+	err = runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(src.records) != 1 {
+		t.Fatalf("expected 1 record, got %v", len(src.records))
+	}
+	record := <-src.records
+	after := record.Payload.After.(sdk.StructuredData)
+	if after["id"] != int64(3) || after["email"] != "c@example.com" {
+		t.Errorf("expected row 3 with added column, got %v", after)
+	}
+	if !strings.Contains(record.Metadata[MetadataSchema], `"email"`) {
+		t.Errorf("expected added column in schema metadata, got %v", record.Metadata[MetadataSchema])
+	}
+	if offset := src.getPosition("table1"); offset != "INT64 3" {
+		t.Errorf("expected offset INT64 3, got %v", offset)
+	}
+
+	// rows can't be synced once the incrementing column was dropped
+	src.bqReadClient = mockTableClient{
+		schema: bigquery.Schema{{Name: "name", Type: bigquery.StringFieldType}},
+		tables: map[string][][]bigquery.Value{
+			"table1": {{"d"}},
+		},
+	}
+	src.tomb = &tomb.Tomb{}
+	err = runCDCIteratorInTomb(&src)
+	if !errors.Is(err, ErrColumnMissing) {
+		t.Errorf("expected column missing error, got %v", err)
+	}
+	if len(src.records) != 0 {
+		t.Errorf("expected no records, got %v", len(src.records))
+	}
+}
+
+func TestSchemaDiff(t *testing.T) {
+	previous := bigquery.Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType},
+		{Name: "name", Type: bigquery.StringFieldType},
+		{Name: "age", Type: bigquery.IntegerFieldType},
+	}
+	current := bigquery.Schema{
+		{Name: "email", Type: bigquery.StringFieldType},
+		{Name: "id", Type: bigquery.IntegerFieldType},
+		{Name: "age", Type: bigquery.FloatFieldType},
+	}
+
+	added, dropped, changed := schemaDiff(previous, current)
+	if !reflect.DeepEqual(added, []string{"email"}) {
+		t.Errorf("expected email to be added, got %v", added)
+	}
+	if !reflect.DeepEqual(dropped, []string{"name"}) {
+		t.Errorf("expected name to be dropped, got %v", dropped)
+	}
+	if !reflect.DeepEqual(changed, []string{"age"}) {
+		t.Errorf("expected age to be changed, got %v", changed)
+	}
+}
+
+func TestReadGoogleRowDate(t *testing.T) {
+	var queries []string
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.IncrementColNames = []string{"created_on"}
+	src.bqReadClient = mockTableClient{
+		schema: bigquery.Schema{
+			{Name: "id", Type: bigquery.IntegerFieldType},
+			{Name: "created_on", Type: bigquery.DateFieldType},
+		},
+		tables: map[string][][]bigquery.Value{
+			"table1": {
+				{int64(1), civil.Date{Year: 2022, Month: time.January, Day: 2}},
+				{int64(2), civil.Date{Year: 2022, Month: time.December, Day: 31}},
+			},
+		},
+		queries: &queries,
+	}
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	err := runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := []string{"2022-01-02", "2022-12-31"}
+	for _, date := range want {
+		record := <-src.records
+		payload := record.Payload.After.(sdk.StructuredData)
+		if payload["created_on"] != date {
+			t.Errorf("expected date %v, got %v", date, payload["created_on"])
+		}
+	}
+	if src.getPosition("table1") != "DATE 2022-12-31" {
+		t.Errorf("expected quoted date offset, got %v", src.getPosition("table1"))
+	}
+
+	// next poll compares against the date literal
+	src.tomb = &tomb.Tomb{}
+	err = runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(queries[len(queries)-1], "WHERE `created_on` >= CAST(@offset AS DATE)") {
+		t.Errorf("expected date comparison in query, got %v", queries[len(queries)-1])
+	}
+}
+
+func TestConvertValueDateTime(t *testing.T) {
+	field := &bigquery.FieldSchema{Name: "updated_at", Type: bigquery.DateTimeFieldType}
+	testCases := []struct {
+		value civil.DateTime
+		want  string
+	}{
+		{
+			value: civil.DateTime{
+				Date: civil.Date{Year: 2022, Month: time.March, Day: 4},
+				Time: civil.Time{Hour: 5, Minute: 6, Second: 7},
+			},
+			want: "2022-03-04 05:06:07.000000",
+		},
+		{
+			value: civil.DateTime{
+				Date: civil.Date{Year: 2022, Month: time.March, Day: 4},
+				Time: civil.Time{Hour: 5, Minute: 6, Second: 7, Nanosecond: 123456789},
+			},
+			want: "2022-03-04 05:06:07.123456",
+		},
+		{
+			value: civil.DateTime{
+				Date: civil.Date{Year: 2022, Month: time.March, Day: 4},
+				Time: civil.Time{Hour: 23, Minute: 59, Second: 59, Nanosecond: 1000},
+			},
+			want: "2022-03-04 23:59:59.000001",
+		},
+	}
+
+	for _, tc := range testCases {
+		got, err := (&Source{}).convertValue(context.Background(), field, tc.value)
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+		if got != tc.want {
+			t.Errorf("expected %v, got %v", tc.want, got)
+		}
+	}
+
+	offset := formatOffset(field, testCases[1].value, "2022-03-04 05:06:07.123456")
+	if offset != "DATETIME 2022-03-04 05:06:07.123456" {
+		t.Errorf("expected datetime offset, got %v", offset)
+	}
+}
+
+func TestReadGoogleRowTimeAcrossPolls(t *testing.T) {
+	var queries []string
+	schema := bigquery.Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType},
+		{Name: "updated_time", Type: bigquery.TimeFieldType},
+	}
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.IncrementColNames = []string{"updated_time"}
+	src.bqReadClient = mockTableClient{
+		schema: schema,
+		tables: map[string][][]bigquery.Value{
+			"table1": {
+				{int64(1), civil.Time{Hour: 9, Minute: 30}},
+				{int64(2), civil.Time{Hour: 10, Minute: 15, Second: 1, Nanosecond: 500000000}},
+			},
+		},
+		queries: &queries,
+	}
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	err := runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := []string{"09:30:00.000000", "10:15:01.500000"}
+	for _, value := range want {
+		record := <-src.records
+		payload := record.Payload.After.(sdk.StructuredData)
+		if payload["updated_time"] != value {
+			t.Errorf("expected time %v, got %v", value, payload["updated_time"])
+		}
+	}
+
+	// second poll picks up only the newer row
+	src.bqReadClient = mockTableClient{
+		schema: schema,
+		tables: map[string][][]bigquery.Value{
+			"table1": {
+				{int64(3), civil.Time{Hour: 11}},
+			},
+		},
+		queries: &queries,
+	}
+	src.tomb = &tomb.Tomb{}
+	err = runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if !strings.Contains(queries[len(queries)-1], "WHERE `updated_time` >= CAST(@offset AS TIME)") {
+		t.Errorf("expected time comparison in query, got %v", queries[len(queries)-1])
+	}
+	record := <-src.records
+	payload := record.Payload.After.(sdk.StructuredData)
+	if payload["updated_time"] != "11:00:00.000000" {
+		t.Errorf("expected time 11:00:00.000000, got %v", payload["updated_time"])
+	}
+	if src.getPosition("table1") != "TIME 11:00:00.000000" {
+		t.Errorf("expected quoted time offset, got %v", src.getPosition("table1"))
+	}
+}
+
+func TestConvertValueBytes(t *testing.T) {
+	field := &bigquery.FieldSchema{Name: "payload", Type: bigquery.BytesFieldType}
+	value := []byte{0x00, 0x01, 0xfe, 0xff, 'b', 'q'}
+
+	src := Source{}
+	src.sourceConfig.Config.BytesEncoding = googlebigquery.BytesEncodingBase64
+	got, err := src.convertValue(context.Background(), field, value)
+	if err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if got != "AAH+/2Jx" {
+		t.Errorf("expected base64 value AAH+/2Jx, got %v", got)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(got.(string))
+	if err != nil || !bytes.Equal(decoded, value) {
+		t.Errorf("expected base64 to round trip, got %v, %v", decoded, err)
+	}
+
+	src.sourceConfig.Config.BytesEncoding = googlebigquery.BytesEncodingHex
+	got, err = src.convertValue(context.Background(), field, value)
+	if err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if got != "0001feff6271" {
+		t.Errorf("expected hex value 0001feff6271, got %v", got)
+	}
+	decoded, err = hex.DecodeString(got.(string))
+	if err != nil || !bytes.Equal(decoded, value) {
+		t.Errorf("expected hex to round trip, got %v, %v", decoded, err)
+	}
+}
+
+func TestReadGoogleRowNestedRecord(t *testing.T) {
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.bqReadClient = mockTableClient{
+		schema: bigquery.Schema{
+			{Name: "id", Type: bigquery.IntegerFieldType},
+			{Name: "user", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+				{Name: "name", Type: bigquery.StringFieldType},
+				{Name: "address", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+					{Name: "city", Type: bigquery.StringFieldType},
+					{Name: "since", Type: bigquery.DateFieldType},
+				}},
+			}},
+		},
+		tables: map[string][][]bigquery.Value{
+			"table1": {
+				{int64(1), []bigquery.Value{"neha", []bigquery.Value{"pune", civil.Date{Year: 2020, Month: time.May, Day: 1}}}},
+				{int64(2), nil},
+			},
+		},
+	}
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	err := runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	record := <-src.records
+	payload := record.Payload.After.(sdk.StructuredData)
+	want := map[string]interface{}{
+		"name": "neha",
+		"address": map[string]interface{}{
+			"city":  "pune",
+			"since": "2020-05-01",
+		},
+	}
+	if !reflect.DeepEqual(payload["user"], want) {
+		t.Errorf("expected nested record %v, got %v", want, payload["user"])
+	}
+
+	record = <-src.records
+	payload = record.Payload.After.(sdk.StructuredData)
+	if payload["user"] != nil {
+		t.Errorf("expected null record, got %v", payload["user"])
+	}
+}
+
+func TestConvertRecordMismatchedSchema(t *testing.T) {
+	field := &bigquery.FieldSchema{Name: "user", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+		{Name: "name", Type: bigquery.StringFieldType},
+		{Name: "age", Type: bigquery.IntegerFieldType},
+	}}
+	_, err := (&Source{}).convertValue(context.Background(), field, []bigquery.Value{"neha"})
+	if err == nil {
+		t.Errorf("expected error for mismatched record values")
+	}
+}
+
+func TestReadGoogleRowRepeated(t *testing.T) {
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.bqReadClient = mockTableClient{
+		schema: bigquery.Schema{
+			{Name: "id", Type: bigquery.IntegerFieldType},
+			{Name: "scores", Type: bigquery.IntegerFieldType, Repeated: true},
+			{Name: "visits", Type: bigquery.RecordFieldType, Repeated: true, Schema: bigquery.Schema{
+				{Name: "page", Type: bigquery.StringFieldType},
+				{Name: "on", Type: bigquery.DateFieldType},
+			}},
+		},
+		tables: map[string][][]bigquery.Value{
+			"table1": {
+				{
+					int64(1),
+					[]bigquery.Value{int64(10), int64(20)},
+					[]bigquery.Value{
+						[]bigquery.Value{"home", civil.Date{Year: 2022, Month: time.June, Day: 1}},
+						[]bigquery.Value{"about", civil.Date{Year: 2022, Month: time.June, Day: 2}},
+					},
+				},
+				{int64(2), []bigquery.Value{}, nil},
+			},
+		},
+	}
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	err := runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	record := <-src.records
+	payload := record.Payload.After.(sdk.StructuredData)
+	wantScores := []interface{}{int64(10), int64(20)}
+	if !reflect.DeepEqual(payload["scores"], wantScores) {
+		t.Errorf("expected scores %v, got %v", wantScores, payload["scores"])
+	}
+	wantVisits := []interface{}{
+		map[string]interface{}{"page": "home", "on": "2022-06-01"},
+		map[string]interface{}{"page": "about", "on": "2022-06-02"},
+	}
+	if !reflect.DeepEqual(payload["visits"], wantVisits) {
+		t.Errorf("expected visits %v, got %v", wantVisits, payload["visits"])
+	}
+
+	// empty and null arrays are both returned as empty arrays
+	record = <-src.records
+	payload = record.Payload.After.(sdk.StructuredData)
+	if !reflect.DeepEqual(payload["scores"], []interface{}{}) {
+		t.Errorf("expected empty scores, got %#v", payload["scores"])
+	}
+	if !reflect.DeepEqual(payload["visits"], []interface{}{}) {
+		t.Errorf("expected empty visits, got %#v", payload["visits"])
+	}
+}
+
+func TestConvertValueJSON(t *testing.T) {
+	field := &bigquery.FieldSchema{Name: "attributes", Type: bigquery.JSONFieldType}
+	raw := `{"color":"red","sizes":[1,2],"nested":{"ok":true}}`
+
+	src := Source{}
+	got, err := src.convertValue(context.Background(), field, raw)
+	if err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	want := map[string]interface{}{
+		"color":  "red",
+		"sizes":  []interface{}{float64(1), float64(2)},
+		"nested": map[string]interface{}{"ok": true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	// malformed JSON falls back to the raw string
+	got, err = src.convertValue(context.Background(), field, `{"color":`)
+	if err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if got != `{"color":` {
+		t.Errorf("expected raw string for malformed JSON, got %v", got)
+	}
+
+	src.sourceConfig.Config.JSONAsString = true
+	got, err = src.convertValue(context.Background(), field, raw)
+	if err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if got != raw {
+		t.Errorf("expected raw string, got %v", got)
+	}
+}
+
+func TestReadGoogleRowNumericOffset(t *testing.T) {
+	var queries []string
+	amount, _ := new(big.Rat).SetString("12345678901234567890.123456789")
+	ratio, _ := new(big.Rat).SetString("0.1")
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"amount"}
+	src.sourceConfig.Config.IncrementColNames = []string{"amount"}
+	src.bqReadClient = mockTableClient{
+		schema: bigquery.Schema{
+			{Name: "amount", Type: bigquery.NumericFieldType},
+			{Name: "ratio", Type: bigquery.BigNumericFieldType},
+		},
+		tables: map[string][][]bigquery.Value{
+			"table1": {
+				{amount, ratio},
+			},
+		},
+		queries: &queries,
+	}
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	err := runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	record := <-src.records
+	payload := record.Payload.After.(sdk.StructuredData)
+	if payload["amount"] != "12345678901234567890.123456789" {
+		t.Errorf("expected exact numeric value, got %v", payload["amount"])
+	}
+	if payload["ratio"] != "0.10000000000000000000000000000000000000" {
+		t.Errorf("expected exact bignumeric value, got %v", payload["ratio"])
+	}
+	if src.getPosition("table1") != "NUMERIC 12345678901234567890.123456789" {
+		t.Errorf("expected exact numeric offset, got %v", src.getPosition("table1"))
+	}
+
+	src.tomb = &tomb.Tomb{}
+	err = runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(queries[len(queries)-1], "WHERE `amount` > CAST(@offset AS NUMERIC)") {
+		t.Errorf("expected numeric comparison in query, got %v", queries[len(queries)-1])
+	}
+}
+
+func TestReadGoogleRowTimestampFormat(t *testing.T) {
+	updatedAt := time.Date(2022, time.March, 4, 5, 6, 7, 123456000, time.UTC)
+	testCases := []struct {
+		format string
+		want   interface{}
+	}{
+		{format: "", want: "2022-03-04 05:06:07.123456 UTC"},
+		{format: googlebigquery.TimestampFormatRFC3339, want: "2022-03-04T05:06:07.123456Z"},
+		{format: googlebigquery.TimestampFormatUnix, want: int64(1646370367123)},
+		{format: "02/01/2006 15:04", want: "04/03/2022 05:06"},
+	}
+
+	for _, tc := range testCases {
+		var queries []string
+		src := Source{}
+		src.sourceConfig.Config.TableIDs = []string{"table1"}
+		src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+		src.sourceConfig.Config.IncrementColNames = []string{"updated_at"}
+		src.sourceConfig.Config.TimestampFormat = tc.format
+		src.bqReadClient = mockTableClient{
+			schema: bigquery.Schema{
+				{Name: "id", Type: bigquery.IntegerFieldType},
+				{Name: "updated_at", Type: bigquery.TimestampFieldType},
+			},
+			tables: map[string][][]bigquery.Value{
+				"table1": {{int64(1), updatedAt}},
+			},
+			queries: &queries,
+		}
+		src.records = make(chan sdk.Record, 10)
+		src.tomb = &tomb.Tomb{}
+		fetchPos(&src, sdk.Position{})
+
+		err := runCDCIteratorInTomb(&src)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		record := <-src.records
+		payload := record.Payload.After.(sdk.StructuredData)
+		if payload["updated_at"] != tc.want {
+			t.Errorf("format %q: expected %v, got %v", tc.format, tc.want, payload["updated_at"])
+		}
+
+		// the offset does not depend on the output format
+		src.tomb = &tomb.Tomb{}
+		err = runCDCIteratorInTomb(&src)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if src.getPosition("table1") != "TIMESTAMP 2022-03-04 05:06:07.123456+00:00" {
+			t.Errorf("format %q: expected timestamp offset, got %v", tc.format, src.getPosition("table1"))
+		}
+		// the rows equal to the offset are read again as updated_at isn't unique
+		if !strings.Contains(queries[len(queries)-1], "WHERE `updated_at` >= CAST(@offset AS TIMESTAMP)") {
+			t.Errorf("format %q: expected timestamp comparison in query, got %v", tc.format, queries[len(queries)-1])
+		}
+	}
+}
+
+func TestConvertValueTimestampLocation(t *testing.T) {
+	field := &bigquery.FieldSchema{Name: "updated_at", Type: bigquery.TimestampFieldType}
+	updatedAt := time.Date(2022, time.July, 4, 15, 0, 0, 0, time.UTC)
+
+	src := Source{}
+	src.sourceConfig.Config.TimestampFormat = googlebigquery.TimestampFormatRFC3339
+	got, err := src.convertValue(context.Background(), field, updatedAt)
+	if err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if got != "2022-07-04T15:00:00Z" {
+		t.Errorf("expected UTC timestamp by default, got %v", got)
+	}
+
+	location, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	src.sourceConfig.Config.TimestampLocation = location
+	got, err = src.convertValue(context.Background(), field, updatedAt)
+	if err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if got != "2022-07-04T11:00:00-04:00" {
+		t.Errorf("expected New York timestamp, got %v", got)
+	}
+}
+
+func TestConvertValueTimestampPrecision(t *testing.T) {
+	field := &bigquery.FieldSchema{Name: "updated_at", Type: bigquery.TimestampFieldType}
+	src := Source{}
+	src.sourceConfig.Config.TimestampFormat = googlebigquery.TimestampFormatRFC3339
+
+	testCases := []struct {
+		value bigquery.Value
+		want  interface{}
+	}{
+		{value: time.Date(2022, time.March, 4, 5, 6, 7, 0, time.UTC), want: "2022-03-04T05:06:07Z"},
+		{value: time.Date(2022, time.March, 4, 5, 6, 7, 123456000, time.UTC), want: "2022-03-04T05:06:07.123456Z"},
+		{value: time.Date(2022, time.March, 4, 5, 6, 7, 100000000, time.FixedZone("IST", 19800)), want: "2022-03-03T23:36:07.1Z"},
+		{value: "2022-03-04 05:06:07 +0000 UTC", want: "2022-03-04T05:06:07Z"},
+		{value: "2022-03-04T05:06:07.654321Z", want: "2022-03-04T05:06:07.654321Z"},
+		{value: nil, want: nil},
+	}
+	for _, tc := range testCases {
+		got, err := src.convertValue(context.Background(), field, tc.value)
+		if err != nil {
+			t.Errorf("value %v: expected no error, got %v", tc.value, err)
+		}
+		if got != tc.want {
+			t.Errorf("value %v: expected %v, got %v", tc.value, tc.want, got)
+		}
+	}
+
+	_, err := src.convertValue(context.Background(), field, "yesterday")
+	if err == nil {
+		t.Errorf("expected error for malformed timestamp string")
+	}
+}
+
+func TestGetRowIteratorFilter(t *testing.T) {
+	var queries []string
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.TableIncrementColNames = map[string][]string{"orders": {"id"}}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"user_id"}
+	src.sourceConfig.Config.Filter = "region = 'us' OR region = 'ca'"
+	src.bqReadClient = mockQueryClient{queries: &queries}
+
+	want := []string{
+		"SELECT * FROM `project.dataset.orders` WHERE (region = 'us' OR region = 'ca') ORDER BY `id` LIMIT 501",
+		"SELECT * FROM `project.dataset.orders` WHERE `id` >= CAST(@offset AS INT64) AND (region = 'us' OR region = 'ca') ORDER BY `id` LIMIT 501",
+		"SELECT * FROM `project.dataset.users` WHERE (region = 'us' OR region = 'ca') ORDER BY `user_id` LIMIT 501",
+	}
+
+	_, _ = src.getRowIterator(context.Background(), "", "", "orders", "", true, 0)
+	_, _ = src.getRowIterator(context.Background(), "INT64 10", "", "orders", "", false, 0)
+	_, _ = src.getRowIterator(context.Background(), "", "", "users", "", true, 0)
+
+	if !reflect.DeepEqual(queries, want) {
+		t.Errorf("expected queries %q, got %q", want, queries)
+	}
+}
+
+func TestGetRowIteratorPartitions(t *testing.T) {
+	var queries []string
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.IncrementColNames = []string{"id"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.Partitions = []googlebigquery.Partition{
+		{ID: "20240101", Start: "2024-01-01", End: "2024-01-02"},
+		{ID: "2024010205", Start: "2024-01-02 05:00:00", End: "2024-01-02 06:00:00"},
+	}
+	src.sourceConfig.Config.Filter = "region = 'us'"
+	src.bqReadClient = mockQueryClient{queries: &queries}
+
+	partitions := "((_PARTITIONTIME >= '2024-01-01' AND _PARTITIONTIME < '2024-01-02') OR " +
+		"(_PARTITIONTIME >= '2024-01-02 05:00:00' AND _PARTITIONTIME < '2024-01-02 06:00:00'))"
+	_, _ = src.getRowIterator(context.Background(), "", "", "events", "", true, 0)
+	_, _ = src.getRowIterator(context.Background(), "INT64 10", "", "events", "", false, 0)
+
+	// tables partitioned by a column are filtered by it
+	src.sourceConfig.Config.PartitionField = "event_date"
+	src.sourceConfig.Config.Partitions = src.sourceConfig.Config.Partitions[:1]
+	_, _ = src.getRowIterator(context.Background(), "INT64 10", "", "events", "", false, 0)
+
+	want := []string{
+		"SELECT * FROM `project.dataset.events` WHERE " + partitions + " AND (region = 'us') ORDER BY `id` LIMIT 501",
+		"SELECT * FROM `project.dataset.events` WHERE `id` > CAST(@offset AS INT64) AND " + partitions + " AND (region = 'us') ORDER BY `id` LIMIT 501",
+		"SELECT * FROM `project.dataset.events` WHERE `id` > CAST(@offset AS INT64) AND " +
+			"((event_date >= '2024-01-01' AND event_date < '2024-01-02')) AND (region = 'us') ORDER BY `id` LIMIT 501",
+	}
+	if !reflect.DeepEqual(queries, want) {
+		t.Errorf("expected queries %q, got %q", want, queries)
+	}
+}
+
+func TestReadGoogleRowPseudoColumns(t *testing.T) {
+	partitionTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		partitioning *bigquery.TimePartitioning
+		schema       bigquery.Schema
+		row          []bigquery.Value
+		wantQuery    string
+		want         sdk.StructuredData
+	}{
+		{
+			name:         "ingestion time partitioned",
+			partitioning: &bigquery.TimePartitioning{Type: bigquery.DayPartitioningType},
+			schema: bigquery.Schema{
+				{Name: "id", Type: bigquery.IntegerFieldType},
+				{Name: "partition_time", Type: bigquery.TimestampFieldType},
+				{Name: "partition_date", Type: bigquery.DateFieldType},
+			},
+			row:       []bigquery.Value{int64(1), partitionTime, civil.DateOf(partitionTime)},
+			wantQuery: "SELECT *, _PARTITIONTIME AS partition_time, _PARTITIONDATE AS partition_date FROM `project.dataset.events` ORDER BY `id` LIMIT 501",
+			want:      sdk.StructuredData{"id": int64(1), "partition_time": "2024-01-01 00:00:00 UTC", "partition_date": "2024-01-01"},
+		},
+		{
+			name:         "hourly partitioned",
+			partitioning: &bigquery.TimePartitioning{Type: bigquery.HourPartitioningType},
+			schema: bigquery.Schema{
+				{Name: "id", Type: bigquery.IntegerFieldType},
+				{Name: "partition_time", Type: bigquery.TimestampFieldType},
+			},
+			row:       []bigquery.Value{int64(1), partitionTime},
+			wantQuery: "SELECT *, _PARTITIONTIME AS partition_time FROM `project.dataset.events` ORDER BY `id` LIMIT 501",
+			want:      sdk.StructuredData{"id": int64(1), "partition_time": "2024-01-01 00:00:00 UTC"},
+		},
+		{
+			name:         "partitioned by column",
+			partitioning: &bigquery.TimePartitioning{Field: "event_date"},
+			schema:       bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}},
+			row:          []bigquery.Value{int64(1)},
+			wantQuery:    "SELECT * FROM `project.dataset.events` ORDER BY `id` LIMIT 501",
+			want:         sdk.StructuredData{"id": int64(1)},
+		},
+		{
+			name:      "unpartitioned",
+			schema:    bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}},
+			row:       []bigquery.Value{int64(1)},
+			wantQuery: "SELECT * FROM `project.dataset.events` ORDER BY `id` LIMIT 501",
+			want:      sdk.StructuredData{"id": int64(1)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var queries []string
+			src := Source{}
+			src.sourceConfig.Config.ProjectID = "project"
+			src.sourceConfig.Config.DatasetID = "dataset"
+			src.sourceConfig.Config.TableIDs = []string{"events"}
+			src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+			src.sourceConfig.Config.IncludePseudoColumns = true
+			src.bqReadClient = mockPseudoColumnsClient{
+				mockTableClient: mockTableClient{
+					schema:  tt.schema,
+					tables:  map[string][][]bigquery.Value{"events": {tt.row}},
+					queries: &queries,
+				},
+				metadata: &bigquery.TableMetadata{TimePartitioning: tt.partitioning},
+			}
+			src.records = make(chan sdk.Record, 10)
+			fetchPos(&src, sdk.Position{})
+
+			if err := src.ReadGoogleRow(context.Background(), "events"); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if len(queries) != 1 || queries[0] != tt.wantQuery {
+				t.Errorf("expected query %q, got %q", tt.wantQuery, queries)
+			}
+			if len(src.records) != 1 {
+				t.Fatalf("expected 1 record, got %d", len(src.records))
+			}
+			after := (<-src.records).Payload.After.(sdk.StructuredData)
+			if !reflect.DeepEqual(after, tt.want) {
+				t.Errorf("expected payload %v, got %v", tt.want, after)
+			}
+		})
+	}
+}
+
+// mockPseudoColumnsClient returns the rows of the table and its partitioning
+type mockPseudoColumnsClient struct {
+	mockTableClient
+	metadata *bigquery.TableMetadata
+}
+
+func (bq mockPseudoColumnsClient) TableMetadata(ctx context.Context, s *Source, tableID string) (*bigquery.TableMetadata, error) {
+	return bq.metadata, nil
+}
+
+// mockPartitionedClient records queries and returns the metadata of partitioned tables
+type mockPartitionedClient struct {
+	mockQueryClient
+	metadata map[string]*bigquery.TableMetadata
+}
+
+func (bq mockPartitionedClient) TableMetadata(ctx context.Context, s *Source, tableID string) (*bigquery.TableMetadata, error) {
+	return bq.metadata[tableID], nil
+}
+
+func TestReadGoogleRowExternalTable(t *testing.T) {
+	var queries []string
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.TableIDs = []string{"files", "events"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.CDCMode = googlebigquery.CDCModeChangeHistory
+	src.sourceConfig.Config.Partitions = []googlebigquery.Partition{{ID: "20240101", Start: "2024-01-01", End: "2024-01-02"}}
+	client := mockPartitionedClient{
+		mockQueryClient: mockQueryClient{queries: &queries},
+		metadata: map[string]*bigquery.TableMetadata{
+			"files": {
+				Type: bigquery.ExternalTable,
+				ExternalDataConfig: &bigquery.ExternalDataConfig{
+					SourceFormat: bigquery.CSV,
+					SourceURIs:   []string{"gs://bucket/files/*.csv"},
+					AutoDetect:   true,
+				},
+			},
+			"events": {
+				Schema:           bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}},
+				TimePartitioning: &bigquery.TimePartitioning{Type: bigquery.DayPartitioningType},
+			},
+		},
+	}
+	src.bqReadClient = client
+	src.records = make(chan sdk.Record, 10)
+	fetchPos(&src, sdk.Position{})
+
+	// the columns of the external table without schema can't be validated
+	if err := src.validateTables(context.Background(), client); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// the external table is polled without partition filter
+	if err := src.ReadGoogleRow(context.Background(), "files"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if src.changeHistory(context.Background(), "files") {
+		t.Errorf("expected external table to be polled")
+	}
+	if !src.changeHistory(context.Background(), "events") {
+		t.Errorf("expected native table to keep reading its change history")
+	}
+	_, _ = src.getRowIterator(context.Background(), "", "", "events", "", true, 0)
+
+	want := []string{
+		"SELECT * FROM `project.dataset.files` ORDER BY `id` LIMIT 501",
+		"SELECT * FROM `project.dataset.events` WHERE ((_PARTITIONTIME >= '2024-01-01' AND _PARTITIONTIME < '2024-01-02')) ORDER BY `id` LIMIT 501",
+	}
+	if !reflect.DeepEqual(queries, want) {
+		t.Errorf("expected queries %q, got %q", want, queries)
+	}
+	for _, option := range []string{googlebigquery.ConfigPartitions, googlebigquery.ConfigCDCMode} {
+		if _, ok := src.externalWarnings.Load("files#" + option); !ok {
+			t.Errorf("expected warning about %s for the external table", option)
+		}
+	}
+}
+
+func TestGetRowIteratorRequirePartitionFilter(t *testing.T) {
+	var queries []string
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.IncrementColNames = []string{"event_time"}
+	src.sourceConfig.Config.TableIncrementColNames = map[string][]string{"logs": {"id"}}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.bqReadClient = mockPartitionedClient{
+		mockQueryClient: mockQueryClient{queries: &queries},
+		metadata: map[string]*bigquery.TableMetadata{
+			"events": {
+				RequirePartitionFilter: true,
+				TimePartitioning:       &bigquery.TimePartitioning{Type: bigquery.DayPartitioningType, Field: "event_time"},
+			},
+			"logs": {
+				RequirePartitionFilter: true,
+				TimePartitioning:       &bigquery.TimePartitioning{Type: bigquery.HourPartitioningType},
+			},
+			"users": {},
+		},
+	}
+	src.now = func() time.Time { return time.Date(2024, 1, 10, 12, 30, 0, 0, time.UTC) }
+
+	// the first query has no watermark to filter the partitions by
+	_, err := src.getRowIterator(context.Background(), "", "", "events", "", true, 0)
+	if !errors.Is(err, ErrPartitionFilterRequired) {
+		t.Errorf("expected partition filter required error, got %v", err)
+	}
+	// the offset on the partition field filters the partitions
+	_, err = src.getRowIterator(context.Background(), "TIMESTAMP 2024-01-09 00:00:00+00:00", "", "events", "", false, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	src.sourceConfig.Config.PartitionLookback = 48 * time.Hour
+	for _, tableID := range []string{"events", "logs", "users"} {
+		if _, err = src.getRowIterator(context.Background(), "", "", tableID, "", true, 0); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	_, err = src.getRowIterator(context.Background(), "INT64 5", "", "logs", "", false, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := []string{
+		"SELECT * FROM `project.dataset.events` WHERE `event_time` >= CAST(@offset AS TIMESTAMP) ORDER BY `event_time` LIMIT 501",
+		"SELECT * FROM `project.dataset.events` WHERE `event_time` >= '2024-01-08' ORDER BY `event_time` LIMIT 501",
+		"SELECT * FROM `project.dataset.logs` WHERE _PARTITIONTIME >= '2024-01-08 12:30:00' ORDER BY `id` LIMIT 501",
+		"SELECT * FROM `project.dataset.users` ORDER BY `event_time` LIMIT 501",
+		"SELECT * FROM `project.dataset.logs` WHERE `id` > CAST(@offset AS INT64) AND _PARTITIONTIME >= '2024-01-08 12:30:00' ORDER BY `id` LIMIT 501",
+	}
+	if !reflect.DeepEqual(queries, want) {
+		t.Errorf("expected queries %q, got %q", want, queries)
+	}
+}
+
+func TestGetRowIteratorCustomQuery(t *testing.T) {
+	var queries []string
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.TableIDs = []string{"ignored"}
+	src.sourceConfig.Config.IncrementColNames = []string{"order_id"}
+	src.sourceConfig.Config.Query = "SELECT o.id AS order_id, u.name FROM `project.dataset.orders` o JOIN `project.dataset.users` u ON o.user_id = u.id"
+	src.bqReadClient = mockQueryClient{queries: &queries}
+
+	tables, err := src.getTables(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(tables, []string{googlebigquery.QueryTableID}) {
+		t.Errorf("expected query to be synced as single table, got %v", tables)
+	}
+
+	_, _ = src.getRowIterator(context.Background(), "", "", googlebigquery.QueryTableID, "", true, 0)
+	_, _ = src.getRowIterator(context.Background(), "INT64 42", "", googlebigquery.QueryTableID, "", false, 0)
+
+	want := []string{
+		"SELECT * FROM (" + src.sourceConfig.Config.Query + ") ORDER BY `order_id` LIMIT 501",
+		"SELECT * FROM (" + src.sourceConfig.Config.Query + ") WHERE `order_id` > CAST(@offset AS INT64) ORDER BY `order_id` LIMIT 501",
+	}
+	if !reflect.DeepEqual(queries, want) {
+		t.Errorf("expected queries %q, got %q", want, queries)
+	}
+}
+
+func TestReadGoogleRowColumns(t *testing.T) {
+	var queries []string
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.IncrementColNames = []string{"updated"}
+	src.sourceConfig.Config.Columns = []string{"name"}
+	src.bqReadClient = mockTableClient{
+		schema: bigquery.Schema{
+			{Name: "id", Type: bigquery.IntegerFieldType},
+			{Name: "name", Type: bigquery.StringFieldType},
+			{Name: "description", Type: bigquery.StringFieldType},
+			{Name: "updated", Type: bigquery.IntegerFieldType},
+		},
+		tables: map[string][][]bigquery.Value{
+			"table1": {{int64(1), "neha", "a very long text", int64(100)}},
+		},
+		queries: &queries,
+	}
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	err := runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if !strings.HasPrefix(queries[0], "SELECT `name`, `updated`, `id` FROM `project.dataset.table1`") {
+		t.Errorf("expected projected query, got %v", queries[0])
+	}
+
+	record := <-src.records
+	want := sdk.StructuredData{"id": int64(1), "name": "neha", "updated": int64(100)}
+	if !reflect.DeepEqual(record.Payload.After, want) {
+		t.Errorf("expected payload %v, got %v", want, record.Payload.After)
+	}
+	if src.getPosition("table1") != "INT64 100" {
+		t.Errorf("expected offset 100, got %v", src.getPosition("table1"))
+	}
+}
+
+func TestReadGoogleRowExcludeColumns(t *testing.T) {
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.ExcludeColumns = []string{"ssn", "user.email", "contacts.phone", "missing.field"}
+	src.bqReadClient = mockTableClient{
+		schema: bigquery.Schema{
+			{Name: "id", Type: bigquery.IntegerFieldType},
+			{Name: "ssn", Type: bigquery.StringFieldType},
+			{Name: "user", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+				{Name: "name", Type: bigquery.StringFieldType},
+				{Name: "email", Type: bigquery.StringFieldType},
+			}},
+			{Name: "contacts", Type: bigquery.RecordFieldType, Repeated: true, Schema: bigquery.Schema{
+				{Name: "kind", Type: bigquery.StringFieldType},
+				{Name: "phone", Type: bigquery.StringFieldType},
+			}},
+		},
+		tables: map[string][][]bigquery.Value{
+			"table1": {{
+				int64(1),
+				"123-45-6789",
+				[]bigquery.Value{"neha", "neha@example.com"},
+				[]bigquery.Value{[]bigquery.Value{"home", "555-0100"}, []bigquery.Value{"work", "555-0199"}},
+			}},
+		},
+	}
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	err := runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	record := <-src.records
+	want := sdk.StructuredData{
+		"id":   int64(1),
+		"user": map[string]interface{}{"name": "neha"},
+		"contacts": []interface{}{
+			map[string]interface{}{"kind": "home"},
+			map[string]interface{}{"kind": "work"},
+		},
+	}
+	if !reflect.DeepEqual(record.Payload.After, want) {
+		t.Errorf("expected payload %v, got %v", want, record.Payload.After)
+	}
+}
+
+func TestReadGoogleRowBatchSize(t *testing.T) {
+	var queries []string
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.IncrementColNames = []string{"id"}
+	src.sourceConfig.Config.BatchSize = 2
+	src.bqReadClient = &mockPagedClient{
+		schema: bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}},
+		pages: [][][]bigquery.Value{
+			{{int64(1)}, {int64(2)}, {int64(3)}},
+			{{int64(3)}, {int64(4)}, {int64(5)}},
+			{{int64(5)}},
+		},
+		queries: &queries,
+	}
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	err := runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// a page without the look-ahead row is the last one
+	if len(queries) != 3 {
+		t.Fatalf("expected 3 queries, got %v", queries)
+	}
+	for _, query := range queries {
+		if !strings.HasSuffix(query, "LIMIT 3") {
+			t.Errorf("expected batch size and look-ahead row as limit, got %v", query)
+		}
+	}
+	if len(src.records) != 5 {
+		t.Errorf("expected 5 records, got %v", len(src.records))
+	}
+}
+
+func TestReadGoogleRowExactPages(t *testing.T) {
+	var queries []string
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.IncrementColNames = []string{"id"}
+	src.sourceConfig.Config.BatchSize = 2
+	src.bqReadClient = &mockPagedClient{
+		schema: bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}},
+		pages: [][][]bigquery.Value{
+			{{int64(1)}, {int64(2)}, {int64(3)}},
+			{{int64(3)}, {int64(4)}, {int64(5)}},
+			{{int64(5)}, {int64(6)}},
+		},
+		queries: &queries,
+	}
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	if err := runCDCIteratorInTomb(&src); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// the last page is full, but without look-ahead row no empty page is queried after it
+	if len(queries) != 3 {
+		t.Fatalf("expected 3 queries, got %v", queries)
+	}
+	if len(src.records) != 6 {
+		t.Errorf("expected 6 records, got %v", len(src.records))
+	}
+	if offset := src.getPosition("table1"); offset != "INT64 6" {
+		t.Errorf("expected offset INT64 6, got %v", offset)
+	}
+	if !src.snapshotDone("table1") {
+		t.Errorf("expected snapshot of the table to be done")
+	}
+}
+
+func TestReadGoogleRowExactPagesInclusive(t *testing.T) {
+	var queries []string
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.IncrementColNames = []string{"seq"}
+	src.sourceConfig.Config.BatchSize = 2
+	src.bqReadClient = &mockPagedClient{
+		schema: bigquery.Schema{
+			{Name: "id", Type: bigquery.IntegerFieldType},
+			{Name: "seq", Type: bigquery.IntegerFieldType},
+		},
+		pages: [][][]bigquery.Value{
+			{{int64(1), int64(10)}, {int64(2), int64(20)}, {int64(3), int64(30)}},
+			{{int64(2), int64(20)}, {int64(3), int64(30)}, {int64(4), int64(40)}, {int64(5), int64(50)}},
+			{{int64(4), int64(40)}, {int64(5), int64(50)}, {int64(6), int64(60)}},
+		},
+		queries: &queries,
+	}
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	if err := runCDCIteratorInTomb(&src); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// the row equal to the offset is read again, it doesn't count into the page
+	want := []string{"LIMIT 3", "LIMIT 4", "LIMIT 4"}
+	if len(queries) != len(want) {
+		t.Fatalf("expected %d queries, got %v", len(want), queries)
+	}
+	for i, query := range queries {
+		if !strings.HasSuffix(query, want[i]) {
+			t.Errorf("expected query %d to end with %s, got %v", i, want[i], query)
+		}
+	}
+	if len(src.records) != 6 {
+		t.Errorf("expected 6 records, got %v", len(src.records))
+	}
+	if offset := src.getPosition("table1"); offset != "INT64 60" {
+		t.Errorf("expected offset INT64 60, got %v", offset)
+	}
+}
+
+func TestReadGoogleRowNullIncrements(t *testing.T) {
+	var queries []string
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.IncrementColNames = []string{"seq"}
+	src.sourceConfig.Config.BatchSize = 2
+	src.bqReadClient = &mockPagedClient{
+		schema: bigquery.Schema{
+			{Name: "id", Type: bigquery.IntegerFieldType},
+			{Name: "seq", Type: bigquery.IntegerFieldType},
+		},
+		pages: [][][]bigquery.Value{
+			{{int64(1), nil}, {int64(2), nil}, {int64(3), int64(5)}},
+			{{int64(3), int64(5)}, {int64(4), nil}, {int64(5), int64(7)}},
+			{{int64(3), int64(5)}, {int64(5), int64(7)}},
+		},
+		queries: &queries,
+	}
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	if err := runCDCIteratorInTomb(&src); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(src.records) != 5 {
+		t.Errorf("expected 5 records, got %v", len(src.records))
+	}
+
+	// the page of NULL rows doesn't advance the offset, the next query leaves them out instead of reading them again
+	if len(queries) != 3 {
+		t.Fatalf("expected 3 queries, got %v", queries)
+	}
+	if strings.Contains(queries[0], "IS NOT NULL") {
+		t.Errorf("expected first query to read the NULL rows, got %v", queries[0])
+	}
+	if !strings.Contains(queries[1], "WHERE `seq` IS NOT NULL ORDER BY `seq`") {
+		t.Errorf("expected NULL rows to be left out, got %v", queries[1])
+	}
+	// the NULL row between two values keeps the offset of the row before it
+	if !strings.Contains(queries[2], "WHERE `seq` >= CAST(@offset AS INT64)") {
+		t.Errorf("expected keyset condition, got %v", queries[2])
+	}
+	if offset := src.getPosition("table1"); offset != "INT64 7" {
+		t.Errorf("expected offset INT64 7, got %v", offset)
+	}
+}
+
+// mockPagedClient returns the next page on every query
+type mockPagedClient struct {
+	schema  bigquery.Schema
+	pages   [][][]bigquery.Value
+	queries *[]string
+}
+
+func (bq *mockPagedClient) Query(ctx context.Context, s *Source, query string, params ...bigquery.QueryParameter) (it rowIterator, err error) {
+	*bq.queries = append(*bq.queries, query)
+	if len(bq.pages) == 0 {
+		return &mockRowIterator{schema: bq.schema}, nil
+	}
+	page := bq.pages[0]
+	bq.pages = bq.pages[1:]
+	return &mockRowIterator{rows: page, schema: bq.schema}, nil
+}
+
+func (bq *mockPagedClient) Tables(ctx context.Context, s *Source) (tableIDs []string, err error) {
+	return nil, nil
+}
+
+func (bq *mockPagedClient) Close() error {
+	return nil
+}
+
+func TestReadGoogleRowStorageSnapshot(t *testing.T) {
+	var queries []string
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.IncrementColNames = []string{"id"}
+	src.sourceConfig.Config.BatchSize = 2
+	src.sourceConfig.Config.ReadMode = googlebigquery.ReadModeStorage
+	src.bqReadClient = &mockPagedClient{
+		schema: bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}},
+		pages: [][][]bigquery.Value{
+			{{int64(1)}, {int64(2)}, {int64(3)}, {int64(4)}, {int64(5)}},
+			{{int64(6)}},
+		},
+		queries: &queries,
+	}
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	err := runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// the snapshot is read with a single query even though it is bigger than the batch size
+	want := []string{"SELECT * FROM `project.dataset.table1` ORDER BY `id`"}
+	if !reflect.DeepEqual(queries, want) {
+		t.Errorf("expected queries %q, got %q", want, queries)
+	}
+	if len(src.records) != 5 {
+		t.Errorf("expected 5 records, got %v", len(src.records))
+	}
+	for i := 0; i < 5; i++ {
+		record := <-src.records
+		if record.Operation != sdk.OperationSnapshot {
+			t.Errorf("expected snapshot operation, got %v", record.Operation)
+		}
+	}
+
+	// changes after the snapshot are paginated
+	src.tomb = &tomb.Tomb{}
+	err = runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if queries[1] != "SELECT * FROM `project.dataset.table1` WHERE `id` > CAST(@offset AS INT64) ORDER BY `id` LIMIT 3" {
+		t.Errorf("expected paginated query, got %v", queries[1])
+	}
+}
+
+func TestReadGoogleRowKeysetPagination(t *testing.T) {
+	var queries []string
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.BatchSize = 2
+	src.bqReadClient = &mockPagedClient{
+		schema: bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}, {Name: "name", Type: bigquery.StringFieldType}},
+		pages: [][][]bigquery.Value{
+			{{int64(3), "a"}, {int64(7), "b"}, {int64(9), "c"}},
+			{{int64(9), "c"}},
+		},
+		queries: &queries,
+	}
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	err := runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// without incrementing column the pages continue after the last primary key read
+	want := []string{
+		"SELECT * FROM `project.dataset.table1` ORDER BY `id` LIMIT 3",
+		"SELECT * FROM `project.dataset.table1` WHERE `id` > CAST(@offset AS INT64) ORDER BY `id` LIMIT 3",
+	}
+	if !reflect.DeepEqual(queries, want) {
+		t.Errorf("expected queries %q, got %q", want, queries)
+	}
+	if src.getPosition("table1") != "INT64 9" {
+		t.Errorf("expected position at last primary key, got %v", src.getPosition("table1"))
+	}
+}
+
+// mockScanClient serves a table ordered by id and counts the rows BigQuery has to read to answer
+// the queries. An OFFSET needs the skipped rows to be read and discarded while a keyset condition
+// on the clustering column lets BigQuery start right after the last value.
+type mockScanClient struct {
+	rows    int
+	scanned *int
+}
+
+var (
+	limitRegex = regexp.MustCompile(`LIMIT (\d+)(?: OFFSET (\d+))?`)
+)
+
+func (bq mockScanClient) Query(ctx context.Context, s *Source, query string, params ...bigquery.QueryParameter) (it rowIterator, err error) {
+	after := offsetParam(params)
+	match := limitRegex.FindStringSubmatch(query)
+	limit, _ := strconv.Atoi(match[1])
+	skip, _ := strconv.Atoi(match[2])
+
+	start := after + skip
+	end := start + limit
+	if end > bq.rows {
+		end = bq.rows
+	}
+	*bq.scanned += end - after
+
+	var rows [][]bigquery.Value
+	for id := start + 1; id <= end; id++ {
+		rows = append(rows, []bigquery.Value{int64(id)})
+	}
+	return &mockRowIterator{rows: rows, schema: bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}}}, nil
+}
+
+func (bq mockScanClient) Tables(ctx context.Context, s *Source) (tableIDs []string, err error) {
+	return nil, nil
+}
+
+func (bq mockScanClient) Close() error {
+	return nil
+}
+
+// BenchmarkPagination compares the rows scanned by the previous OFFSET pagination with the keyset
+// pagination used now.
+func BenchmarkPagination(b *testing.B) {
+	const rows, batchSize = 10000, 500
+	tableID := "table1"
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.BatchSize = batchSize
+
+	b.Run("offset", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			scanned := 0
+			client := mockScanClient{rows: rows, scanned: &scanned}
+			for offset := 0; offset < rows; offset += batchSize {
+				query := "SELECT * FROM `project.dataset." + tableID + "` LIMIT " + strconv.Itoa(batchSize) + " OFFSET " + strconv.Itoa(offset)
+				_, _ = client.Query(context.Background(), &src, query)
+			}
+			b.ReportMetric(float64(scanned), "rows-scanned/op")
+		}
+	})
+
+	b.Run("keyset", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			scanned := 0
+			src.bqReadClient = mockScanClient{rows: rows, scanned: &scanned}
+			src.records = make(chan sdk.Record, rows)
+			src.tomb = &tomb.Tomb{}
+			fetchPos(&src, sdk.Position{})
+			if err := src.ReadGoogleRow(context.Background(), tableID); err != nil {
+				b.Fatalf("expected no error, got %v", err)
+			}
+			b.ReportMetric(float64(scanned), "rows-scanned/op")
+		}
+	})
+}
+
+// BenchmarkSnapshot reads a snapshot of 1M rows through Next, and hands the same number of records
+// over the records channel without reading them. The hand-off only takes a small share of the time
+// a row takes to be converted and positioned, so the records aren't batched onto the channel.
+func BenchmarkSnapshot(b *testing.B) {
+	const rows = 1000000
+	newSource := func() *Source {
+		src := &Source{}
+		src.sourceConfig.Config.ProjectID = "project"
+		src.sourceConfig.Config.DatasetID = "dataset"
+		src.sourceConfig.Config.TableIDs = []string{"table1"}
+		src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+		src.records = make(chan sdk.Record, src.bufferSize())
+		src.tomb = &tomb.Tomb{}
+		fetchPos(src, sdk.Position{})
+		return src
+	}
+	// drain reads the records with Next like Conduit does
+	drain := func(src *Source) chan struct{} {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for read := 0; read < rows; {
+				if _, err := src.Next(context.Background()); err == nil {
+					read++
+				}
+			}
+		}()
+		return done
+	}
+
+	b.Run("read", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			src := newSource()
+			scanned := 0
+			src.bqReadClient = mockScanClient{rows: rows, scanned: &scanned}
+			done := drain(src)
+			if err := src.ReadGoogleRow(context.Background(), "table1"); err != nil {
+				b.Fatalf("expected no error, got %v", err)
+			}
+			<-done
+		}
+	})
+
+	b.Run("handoff", func(b *testing.B) {
+		record := sdk.Util.Source.NewRecordSnapshot(sdk.Position("position"), nil, sdk.RawData("1"), sdk.StructuredData{"id": int64(1)})
+		for n := 0; n < b.N; n++ {
+			src := newSource()
+			done := drain(src)
+			for i := 0; i < rows; i++ {
+				if !src.send(context.Background(), record) {
+					b.Fatalf("expected record %d to be sent", i)
+				}
+			}
+			<-done
+		}
+	})
+}
+
+// mockStreamClient serves the rows 1 to rows of a table. Rows are assigned to the read streams
+// by id modulo the number of streams.
+type mockStreamClient struct {
+	rows    int
+	lock    *sync.Mutex
+	queries *[]string
+}
+
+var (
+	partitionRegex = regexp.MustCompile(`, (\d+)\) = (\d+)`)
+)
+
+// offsetParam returns the integer offset passed as query parameter or 0 without offset
+func offsetParam(params []bigquery.QueryParameter) int {
+	for _, param := range params {
+		if param.Name == "offset" {
+			offset, _ := strconv.Atoi(param.Value.(string))
+			return offset
+		}
+	}
+	return 0
+}
+
+func (bq mockStreamClient) Query(ctx context.Context, s *Source, query string, params ...bigquery.QueryParameter) (it rowIterator, err error) {
+	bq.lock.Lock()
+	*bq.queries = append(*bq.queries, query)
+	bq.lock.Unlock()
+	schema := bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}}
+
+	if strings.HasPrefix(query, "SELECT * FROM UNNEST(") {
+		greatest := 0
+		for _, param := range params {
+			value, _ := strconv.Atoi(param.Value.(string))
+			if value > greatest {
+				greatest = value
+			}
+		}
+		return &mockRowIterator{rows: [][]bigquery.Value{{int64(greatest)}}, schema: schema}, nil
+	}
+
+	match := partitionRegex.FindStringSubmatch(query)
+	streams, _ := strconv.Atoi(match[1])
+	stream, _ := strconv.Atoi(match[2])
+	after := offsetParam(params)
+
+	var rows [][]bigquery.Value
+	for id := after + 1; id <= bq.rows; id++ {
+		if id%streams == stream {
+			rows = append(rows, []bigquery.Value{int64(id)})
+		}
+	}
+	return &mockRowIterator{rows: rows, schema: schema}, nil
+}
+
+func (bq mockStreamClient) Tables(ctx context.Context, s *Source) (tableIDs []string, err error) {
+	return nil, nil
+}
+
+func (bq mockStreamClient) Close() error {
+	return nil
+}
+
+func TestReadGoogleRowReadStreams(t *testing.T) {
+	var queries []string
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.ReadMode = googlebigquery.ReadModeStorage
+	src.sourceConfig.Config.ReadStreams = 3
+	src.bqReadClient = mockStreamClient{rows: 10, lock: &sync.Mutex{}, queries: &queries}
+	src.records = make(chan sdk.Record, 20)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	err := runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(src.records) != 10 {
+		t.Fatalf("expected 10 records, got %v", len(src.records))
+	}
+	for i := 0; i < 10; i++ {
+		record := <-src.records
+		if record.Operation != sdk.OperationSnapshot {
+			t.Errorf("expected snapshot operation, got %v", record.Operation)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		want := fmt.Sprintf("SELECT * FROM `project.dataset.table1` WHERE MOD(ABS(FARM_FINGERPRINT(TO_JSON_STRING(`id`))), 3) = %d ORDER BY `id`", i)
+		found := false
+		for _, query := range queries {
+			found = found || query == want
+		}
+		if !found {
+			t.Errorf("expected stream query %q, got %q", want, queries)
+		}
+	}
+
+	// stream offsets are merged into the offset of the table
+	want := map[string]string{"table1": "INT64 10"}
+	if !reflect.DeepEqual(src.position.positions, want) {
+		t.Errorf("expected positions %v, got %v", want, src.position.positions)
+	}
+}
+
+func TestReadGoogleRowReadStreamsMaxConcurrentReads(t *testing.T) {
+	var active, maxActive, queried int32
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1", "table2"}
+	src.sourceConfig.Config.MaxConcurrentReads = 2
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.ReadMode = googlebigquery.ReadModeStorage
+	src.sourceConfig.Config.ReadStreams = 4
+	src.bqReadClient = mockConcurrencyClient{active: &active, maxActive: &maxActive, queried: &queried}
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	done := make(chan error)
+	go func() {
+		done <- runCDCIteratorInTomb(&src)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the streams of both tables to be read")
+	}
+	if queried != 8 {
+		t.Errorf("expected the 4 streams of both tables to be queried, got %v", queried)
+	}
+	// the streams share the read slots with the tables
+	if maxActive != 2 {
+		t.Errorf("expected 2 concurrent queries, got %v", maxActive)
+	}
+}
+
+func TestReadGoogleRowReadStreamsResume(t *testing.T) {
+	var queries []string
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.ReadMode = googlebigquery.ReadModeStorage
+	src.sourceConfig.Config.ReadStreams = 2
+	src.bqReadClient = mockStreamClient{rows: 10, lock: &sync.Mutex{}, queries: &queries}
+	src.records = make(chan sdk.Record, 20)
+	src.tomb = &tomb.Tomb{}
+
+	// restart after stream 0 read up to 4 and stream 1 finished
+	fetchPos(&src, sdk.Position(`{"version":1,"mode":"snapshot","offsets":{"table1#stream0":"INT64 4","table1#stream1":"INT64 9"}}`))
+
+	err := runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// only the remaining rows 6, 8 and 10 of stream 0 are read
+	if len(src.records) != 3 {
+		t.Fatalf("expected 3 records, got %v", len(src.records))
+	}
+	want := map[string]string{"table1": "INT64 10"}
+	if !reflect.DeepEqual(src.position.positions, want) {
+		t.Errorf("expected positions %v, got %v", want, src.position.positions)
+	}
+}
+
+func TestReadGoogleRowOffsetWithQuotes(t *testing.T) {
+	var queries []string
+	var params []bigquery.QueryParameter
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"name"}
+	src.bqReadClient = mockTableClient{
+		schema: bigquery.Schema{{Name: "name", Type: bigquery.StringFieldType}},
+		tables: map[string][][]bigquery.Value{
+			"table1": {{"o'brien"}, {"x' OR '1'='1"}},
+		},
+		queries: &queries,
+		params:  &params,
+	}
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	err := runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	src.tomb = &tomb.Tomb{}
+	err = runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// the value read from the table is only passed as parameter
+	want := "SELECT * FROM `project.dataset.table1` WHERE `name` > CAST(@offset AS STRING) ORDER BY `name` LIMIT 501"
+	if queries[len(queries)-1] != want {
+		t.Errorf("expected query %v, got %v", want, queries[len(queries)-1])
+	}
+	wantParams := []bigquery.QueryParameter{{Name: "offset", Value: "x' OR '1'='1"}}
+	if !reflect.DeepEqual(params, wantParams) {
+		t.Errorf("expected params %v, got %v", wantParams, params)
+	}
+}
+
+func TestReadGoogleRowCompositeKey(t *testing.T) {
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"order_id", "line_no"}
+	src.bqReadClient = mockTableClient{
+		schema: bigquery.Schema{
+			{Name: "order_id", Type: bigquery.IntegerFieldType},
+			{Name: "line_no", Type: bigquery.IntegerFieldType},
+			{Name: "item", Type: bigquery.StringFieldType},
+		},
+		tables: map[string][][]bigquery.Value{
+			"table1": {
+				{int64(1), int64(1), "pen"},
+				{int64(1), int64(2), "ink"},
+				{int64(2), int64(1), "pen"},
+				{int64(2), int64(2), "paper"},
+			},
+		},
+	}
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	err := runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	keys := make(map[string]bool)
+	var first sdk.Record
+	for i := 0; i < 4; i++ {
+		record := <-src.records
+		if i == 0 {
+			first = record
+		}
+		key := string(record.Key.Bytes())
+		if keys[key] {
+			t.Errorf("expected unique keys, got duplicate for record %v", record.Payload.After)
+		}
+		keys[key] = true
+	}
+
+	// the key is an object of the columns
+	want := `{"line_no":1,"order_id":1}`
+	if string(first.Key.Bytes()) != want {
+		t.Errorf("expected key %s, got %s", want, first.Key.Bytes())
+	}
+}
+
+func TestReadGoogleRowCreatedAt(t *testing.T) {
+	updatedAt := time.Date(2023, 5, 1, 12, 30, 0, 0, time.UTC)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		increment []string
+		want      time.Time
+	}{
+		{name: "timestamp incrementing column", increment: []string{"updated_at"}, want: updatedAt},
+		{name: "integer incrementing column", increment: []string{"id"}, want: now},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := Source{}
+			src.sourceConfig.Config.TableIDs = []string{"table1"}
+			src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+			src.sourceConfig.Config.IncrementColNames = tt.increment
+			src.bqReadClient = mockTableClient{
+				schema: bigquery.Schema{
+					{Name: "id", Type: bigquery.IntegerFieldType},
+					{Name: "updated_at", Type: bigquery.TimestampFieldType},
+				},
+				tables: map[string][][]bigquery.Value{"table1": {{int64(1), updatedAt}}},
+			}
+			src.now = func() time.Time { return now }
+			src.records = make(chan sdk.Record, 10)
+			fetchPos(&src, sdk.Position{})
+
+			if err := src.ReadGoogleRow(context.Background(), "table1"); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			record := <-src.records
+			createdAt, err := record.Metadata.GetCreatedAt()
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !createdAt.Equal(tt.want) {
+				t.Errorf("expected created at %v, got %v", tt.want, createdAt)
+			}
+		})
+	}
+}
+
+func TestReadGoogleRowKeyColumns(t *testing.T) {
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.KeyColumns = []string{"customer_id"}
+	src.sourceConfig.Config.ExcludeColumns = []string{"customer_id"}
+	src.bqReadClient = mockTableClient{
+		schema: bigquery.Schema{
+			{Name: "id", Type: bigquery.IntegerFieldType},
+			{Name: "customer_id", Type: bigquery.StringFieldType},
+		},
+		tables: map[string][][]bigquery.Value{
+			"table1": {{int64(1), "c1"}, {int64(2), "c1"}, {int64(3), "c2"}},
+		},
+	}
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	if err := runCDCIteratorInTomb(&src); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(src.records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(src.records))
+	}
+
+	// the key is built from the key column, also when the column is excluded from the payload
+	for _, want := range []string{"c1", "c1", "c2"} {
+		record := <-src.records
+		var key string
+		if err := json.Unmarshal(record.Key.Bytes(), &key); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if key != want {
+			t.Errorf("expected key %q, got %q", want, key)
+		}
+		if _, ok := record.Payload.After.(sdk.StructuredData)["customer_id"]; ok {
+			t.Errorf("expected excluded column to be dropped from the payload")
+		}
+	}
+}
+
+func TestReadGoogleRowGeography(t *testing.T) {
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"stores"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.bqReadClient = mockTableClient{
+		schema: bigquery.Schema{
+			{Name: "id", Type: bigquery.IntegerFieldType},
+			{Name: "location", Type: bigquery.GeographyFieldType},
+		},
+		tables: map[string][][]bigquery.Value{
+			"stores": {{int64(1), "POINT(-122.35 47.62)"}, {int64(2), nil}},
+		},
+	}
+	src.records = make(chan sdk.Record, 10)
+	fetchPos(&src, sdk.Position{})
+
+	if err := src.ReadGoogleRow(context.Background(), "stores"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(src.records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(src.records))
+	}
+	for _, want := range []interface{}{"POINT(-122.35 47.62)", nil} {
+		after := (<-src.records).Payload.After.(sdk.StructuredData)
+		if after["location"] != want {
+			t.Errorf("expected location %v, got %v", want, after["location"])
+		}
+	}
+
+	if _, err := src.convertValue(context.Background(), &bigquery.FieldSchema{Name: "location", Type: bigquery.GeographyFieldType}, 42); err == nil {
+		t.Errorf("expected error for geography value of type int")
+	}
+}
+
+func TestReadGoogleRowConversionErrors(t *testing.T) {
+	newSource := func(policy string) *Source {
+		src := &Source{}
+		src.sourceConfig.Config.TableIDs = []string{"stores"}
+		src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+		src.sourceConfig.Config.KeyColumns = []string{"score"}
+		src.sourceConfig.Config.OnConversionError = policy
+		src.bqReadClient = mockTableClient{
+			schema: bigquery.Schema{
+				{Name: "id", Type: bigquery.IntegerFieldType},
+				{Name: "location", Type: bigquery.GeographyFieldType},
+				{Name: "score", Type: bigquery.FloatFieldType},
+			},
+			tables: map[string][][]bigquery.Value{
+				// the location of the second row isn't WKT, the score of the third one can't be encoded as JSON key
+				"stores": {
+					{int64(1), "POINT(1 1)", 1.5},
+					{int64(2), int64(42), 2.5},
+					{int64(3), "POINT(3 3)", math.NaN()},
+					{int64(4), "POINT(4 4)", 4.5},
+				},
+			},
+		}
+		src.records = make(chan sdk.Record, 10)
+		fetchPos(src, sdk.Position{})
+		return src
+	}
+
+	t.Run(googlebigquery.OnConversionErrorFail, func(t *testing.T) {
+		src := newSource(googlebigquery.OnConversionErrorFail)
+		err := src.ReadGoogleRow(context.Background(), "stores")
+		if !errors.Is(err, ErrConversion) {
+			t.Fatalf("expected ErrConversion, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "column location") {
+			t.Errorf("expected error to name the column, got %v", err)
+		}
+		if len(src.records) != 1 {
+			t.Errorf("expected the record before the row, got %d records", len(src.records))
+		}
+	})
+
+	t.Run(googlebigquery.OnConversionErrorSkip, func(t *testing.T) {
+		src := newSource(googlebigquery.OnConversionErrorSkip)
+		if err := src.ReadGoogleRow(context.Background(), "stores"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(src.records) != 2 {
+			t.Fatalf("expected 2 records, got %d", len(src.records))
+		}
+		for _, want := range []int64{1, 4} {
+			record := <-src.records
+			if id := record.Payload.After.(sdk.StructuredData)["id"]; id != want {
+				t.Errorf("expected id %d, got %v", want, id)
+			}
+		}
+		if offset := src.getPosition("stores"); offset != "INT64 4" {
+			t.Errorf("expected the position past the skipped rows, got %v", offset)
+		}
+	})
+
+	t.Run(googlebigquery.OnConversionErrorDLQ, func(t *testing.T) {
+		src := newSource(googlebigquery.OnConversionErrorDLQ)
+		if err := src.ReadGoogleRow(context.Background(), "stores"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(src.records) != 4 {
+			t.Fatalf("expected 4 records, got %d", len(src.records))
+		}
+		var records []sdk.Record
+		for i := 0; i < 4; i++ {
+			records = append(records, <-src.records)
+		}
+		for _, i := range []int{0, 3} {
+			if _, ok := records[i].Metadata[MetadataConversionError]; ok {
+				t.Errorf("expected no conversion error on record %d", i)
+			}
+		}
+
+		// the value which can't be converted is formatted as string
+		if cause := records[1].Metadata[MetadataConversionError]; !strings.Contains(cause, "column location") {
+			t.Errorf("expected conversion error of column location, got %q", cause)
+		}
+		if location := records[1].Payload.After.(sdk.StructuredData)["location"]; location != "42" {
+			t.Errorf("expected location as read, got %v", location)
+		}
+		if string(records[1].Key.Bytes()) != "2.5" {
+			t.Errorf("expected key 2.5, got %s", records[1].Key.Bytes())
+		}
+
+		if cause := records[2].Metadata[MetadataConversionError]; !strings.Contains(cause, "key of table stores") {
+			t.Errorf("expected conversion error of the key, got %q", cause)
+		}
+		if records[2].Key != nil {
+			t.Errorf("expected no key, got %v", records[2].Key)
+		}
+		if id := records[2].Payload.After.(sdk.StructuredData)["id"]; id != int64(3) {
+			t.Errorf("expected id 3, got %v", id)
+		}
+	})
+}
+
+func TestReadGoogleRowNulls(t *testing.T) {
+	for _, nullHandling := range []string{googlebigquery.NullHandlingNull, googlebigquery.NullHandlingOmit} {
+		t.Run(nullHandling, func(t *testing.T) {
+			src := Source{}
+			src.sourceConfig.Config.TableIDs = []string{"table1"}
+			src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+			src.sourceConfig.Config.IncrementColNames = []string{"seq"}
+			src.sourceConfig.Config.NullHandling = nullHandling
+			src.bqReadClient = mockTableClient{
+				schema: bigquery.Schema{
+					{Name: "id", Type: bigquery.IntegerFieldType},
+					{Name: "seq", Type: bigquery.IntegerFieldType},
+					{Name: "name", Type: bigquery.StringFieldType},
+				},
+				tables: map[string][][]bigquery.Value{
+					"table1": {{int64(1), int64(10), "a"}, {nil, int64(11), "b"}, {int64(3), nil, nil}},
+				},
+			}
+			src.records = make(chan sdk.Record, 10)
+			fetchPos(&src, sdk.Position{})
+
+			if err := src.ReadGoogleRow(context.Background(), "table1"); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if len(src.records) != 3 {
+				t.Fatalf("expected 3 records, got %d", len(src.records))
+			}
+
+			// the NULL key column is encoded as null
+			<-src.records
+			if key := string((<-src.records).Key.Bytes()); key != "null" {
+				t.Errorf("expected null key, got %q", key)
+			}
+
+			after := (<-src.records).Payload.After.(sdk.StructuredData)
+			for _, column := range []string{"seq", "name"} {
+				value, ok := after[column]
+				if nullHandling == googlebigquery.NullHandlingOmit && ok {
+					t.Errorf("expected NULL column %s to be omitted, got %v", column, value)
+				}
+				if nullHandling == googlebigquery.NullHandlingNull && (!ok || value != nil) {
+					t.Errorf("expected NULL column %s to be null, got %v", column, value)
+				}
+			}
+
+			// the NULL incrementing column doesn't move the offset
+			if offset := src.getPosition("table1"); offset != "INT64 11" {
+				t.Errorf("expected offset INT64 11, got %v", offset)
+			}
+		})
+	}
+}
+
+func TestEncodeKeyFormat(t *testing.T) {
+	data := sdk.StructuredData{"order_id": int64(42), "line_no": int64(1), "customer": "c1"}
+
+	tests := []struct {
+		name    string
+		columns []string
+		want    interface{}
+	}{
+		{name: "single column", columns: []string{"order_id"}, want: float64(42)},
+		{name: "string column", columns: []string{"customer"}, want: "c1"},
+		{name: "composite key", columns: []string{"order_id", "line_no"}, want: map[string]interface{}{"order_id": float64(42), "line_no": float64(1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := Source{}
+			key, err := src.encodeKey(data, tt.columns)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !json.Valid(key) {
+				t.Fatalf("expected valid JSON, got %s", key)
+			}
+			var got interface{}
+			if err := json.Unmarshal(key, &got); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected key %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// gob keeps the keys of earlier versions, composite keys are lists of the columns in order
+	src := Source{}
+	src.sourceConfig.Config.KeyFormat = googlebigquery.KeyFormatGob
+	key, err := src.encodeKey(data, []string{"order_id", "line_no"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var composite []keyColumn
+	if err := gob.NewDecoder(bytes.NewReader(key)).Decode(&composite); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := []keyColumn{{Name: "order_id", Value: "42"}, {Name: "line_no", Value: "1"}}
+	if !reflect.DeepEqual(composite, want) {
+		t.Errorf("expected key %v, got %v", want, composite)
+	}
+}
+
+func TestReadGoogleRowCompositeIncrementColumns(t *testing.T) {
+	var queries []string
+	var params []bigquery.QueryParameter
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.IncrementColNames = []string{"updated_at", "id"}
+	src.bqReadClient = mockTableClient{
+		schema: bigquery.Schema{
+			{Name: "id", Type: bigquery.IntegerFieldType},
+			{Name: "updated_at", Type: bigquery.DateFieldType},
+		},
+		tables: map[string][][]bigquery.Value{
+			"table1": {
+				{int64(1), civil.Date{Year: 2022, Month: 1, Day: 2}},
+				{int64(2), civil.Date{Year: 2022, Month: 1, Day: 2}},
+			},
+		},
+		queries: &queries,
+		params:  &params,
+	}
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	err := runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := "SELECT * FROM `project.dataset.table1` ORDER BY `updated_at`, `id` LIMIT 501"
+	if queries[0] != want {
+		t.Errorf("expected query %v, got %v", want, queries[0])
+	}
+
+	// the offset holds the values of all the columns, so rows sharing updated_at aren't skipped
+	wantPosition := `["DATE 2022-01-02","INT64 2"]`
+	if src.position.positions["table1"] != wantPosition {
+		t.Errorf("expected position %v, got %v", wantPosition, src.position.positions["table1"])
+	}
+
+	src.tomb = &tomb.Tomb{}
+	err = runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want = "SELECT * FROM `project.dataset.table1` WHERE (`updated_at` > CAST(@offset0 AS DATE) OR " +
+		"(`updated_at` = CAST(@offset0 AS DATE) AND `id` > CAST(@offset1 AS INT64))) ORDER BY `updated_at`, `id` LIMIT 501"
+	if queries[len(queries)-1] != want {
+		t.Errorf("expected query %v, got %v", want, queries[len(queries)-1])
+	}
+	wantParams := []bigquery.QueryParameter{{Name: "offset0", Value: "2022-01-02"}, {Name: "offset1", Value: "2"}}
+	if !reflect.DeepEqual(params[len(params)-2:], wantParams) {
+		t.Errorf("expected params %v, got %v", wantParams, params[len(params)-2:])
+	}
+}
+
+func TestGetRowIteratorIncrementOrder(t *testing.T) {
+	testCases := []struct {
+		order   string
+		columns []string
+		offset  string
+		want    string
+	}{
+		{
+			order:   googlebigquery.IncrementOrderAsc,
+			columns: []string{"id"},
+			offset:  "INT64 10",
+			want:    "SELECT * FROM `project.dataset.table1` WHERE `id` > CAST(@offset AS INT64) ORDER BY `id` LIMIT 501",
+		},
+		{
+			order:   googlebigquery.IncrementOrderDesc,
+			columns: []string{"id"},
+			offset:  "INT64 10",
+			want:    "SELECT * FROM `project.dataset.table1` WHERE `id` < CAST(@offset AS INT64) ORDER BY `id` DESC LIMIT 501",
+		},
+		{
+			order:   googlebigquery.IncrementOrderDesc,
+			columns: []string{"updated_at", "id"},
+			offset:  joinOffsets([]string{"INT64 2", "INT64 10"}),
+			want: "SELECT * FROM `project.dataset.table1` WHERE (`updated_at` < CAST(@offset0 AS INT64) OR " +
+				"(`updated_at` = CAST(@offset0 AS INT64) AND `id` < CAST(@offset1 AS INT64))) ORDER BY `updated_at` DESC, `id` DESC LIMIT 501",
+		},
+		{
+			// rows equal to a non unique offset are read again
+			order:   googlebigquery.IncrementOrderDesc,
+			columns: []string{"updated_at"},
+			offset:  "INT64 2",
+			want:    "SELECT * FROM `project.dataset.table1` WHERE `updated_at` <= CAST(@offset AS INT64) ORDER BY `updated_at` DESC LIMIT 501",
+		},
+	}
+
+	for _, tc := range testCases {
+		var queries []string
+		src := Source{}
+		src.sourceConfig.Config.ProjectID = "project"
+		src.sourceConfig.Config.DatasetID = "dataset"
+		src.sourceConfig.Config.IncrementOrder = tc.order
+		src.sourceConfig.Config.IncrementColNames = tc.columns
+		src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+		src.bqReadClient = mockQueryClient{queries: &queries}
+
+		_, err := src.getRowIterator(context.Background(), tc.offset, "", "table1", "", false, 0)
+		if err != nil {
+			t.Fatalf("%s %v: expected no error, got %v", tc.order, tc.columns, err)
+		}
+		if len(queries) != 1 || queries[0] != tc.want {
+			t.Errorf("%s %v: expected query %q, got %q", tc.order, tc.columns, tc.want, queries)
+		}
+	}
+}
+
+func TestReadGoogleRowIncrementOrderDesc(t *testing.T) {
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.IncrementOrder = googlebigquery.IncrementOrderDesc
+	src.bqReadClient = mockTableClient{
+		schema: bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}},
+		tables: map[string][][]bigquery.Value{
+			"table1": {{int64(3)}, {int64(2)}, {int64(1)}},
+		},
+	}
+	src.records = make(chan sdk.Record, 10)
+	fetchPos(&src, sdk.Position{})
+
+	err := src.ReadGoogleRow(context.Background(), "table1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// the last row read is the smallest, the next page continues below it
+	if src.getPosition("table1") != "INT64 1" {
+		t.Errorf("expected offset of the smallest row, got %v", src.getPosition("table1"))
+	}
+}
+
+func TestReadGoogleRowOrderBy(t *testing.T) {
+	var queries []string
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.IncrementColNames = []string{"updated_at"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.OrderBy = []string{"id"}
+	schema := bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}, {Name: "updated_at", Type: bigquery.IntegerFieldType}}
+	src.bqReadClient = mockTableClient{
+		schema:  schema,
+		queries: &queries,
+		tables: map[string][][]bigquery.Value{
+			"table1": {{int64(1), int64(30)}, {int64(2), int64(10)}, {int64(3), int64(20)}},
+		},
+	}
+	src.records = make(chan sdk.Record, 10)
+	fetchPos(&src, sdk.Position{})
+
+	if err := src.ReadGoogleRow(context.Background(), "table1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(src.records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(src.records))
+	}
+	// the offset is the greatest incrementing value, not the one of the last row read
+	if src.getPosition("table1") != "INT64 30" {
+		t.Errorf("expected offset of the greatest updated_at, got %v", src.getPosition("table1"))
+	}
+	for len(src.records) > 0 {
+		<-src.records
+	}
+
+	// the rows equal to the offset are read again and the ones read before skipped
+	src.bqReadClient = mockTableClient{
+		schema:  schema,
+		queries: &queries,
+		tables: map[string][][]bigquery.Value{
+			"table1": {{int64(1), int64(30)}, {int64(4), int64(40)}, {int64(5), int64(35)}},
+		},
+	}
+	if err := src.ReadGoogleRow(context.Background(), "table1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var ids []interface{}
+	for len(src.records) > 0 {
+		record := <-src.records
+		ids = append(ids, record.Payload.After.(sdk.StructuredData)["id"])
+		var position Position
+		if err := json.Unmarshal(record.Position, &position); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if position.Offsets["table1"] != "INT64 30" {
+			t.Errorf("expected records to carry the offset the query started from, got %v", position.Offsets["table1"])
+		}
+	}
+	if fmt.Sprint(ids) != "[4 5]" {
+		t.Errorf("expected the rows after the offset, got %v", ids)
+	}
+	if src.getPosition("table1") != "INT64 40" {
+		t.Errorf("expected offset of the greatest updated_at, got %v", src.getPosition("table1"))
+	}
+
+	want := []string{
+		"SELECT * FROM `project.dataset.table1` ORDER BY `id`, `updated_at` LIMIT 501",
+		"SELECT * FROM `project.dataset.table1` WHERE `updated_at` >= CAST(@offset AS INT64) ORDER BY `id`, `updated_at` LIMIT 501",
+	}
+	if !reflect.DeepEqual(queries, want) {
+		t.Errorf("expected queries %q, got %q", want, queries)
+	}
+}
+
+// mockOrderByClient serves rows of (id, updated_at) ordered by id. It honors the offset of updated_at,
+// the cursor of the order columns and the LIMIT of the queries.
+type mockOrderByClient struct {
+	rows    [][]bigquery.Value
+	queries *[]string
+}
+
+func (bq mockOrderByClient) Query(ctx context.Context, s *Source, query string, params ...bigquery.QueryParameter) (it rowIterator, err error) {
+	*bq.queries = append(*bq.queries, query)
+	values := make(map[string]int64)
+	for _, param := range params {
+		value, _ := strconv.ParseInt(fmt.Sprint(param.Value), 10, 64)
+		values[param.Name] = value
+	}
+	limit := len(bq.rows)
+	if match := limitRegex.FindStringSubmatch(query); match != nil {
+		limit, _ = strconv.Atoi(match[1])
+	}
+
+	var rows [][]bigquery.Value
+	for _, row := range bq.rows {
+		id, updatedAt := row[0].(int64), row[1].(int64)
+		if offset, ok := values["offset"]; ok && updatedAt < offset {
+			continue
+		}
+		if cursor, ok := values["cursor0"]; ok && (id < cursor || (id == cursor && updatedAt <= values["cursor1"])) {
+			continue
+		}
+		if len(rows) < limit {
+			rows = append(rows, row)
+		}
+	}
+	schema := bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}, {Name: "updated_at", Type: bigquery.IntegerFieldType}}
+	return &mockRowIterator{rows: rows, schema: schema}, nil
+}
+
+func (bq mockOrderByClient) Tables(ctx context.Context, s *Source) (tableIDs []string, err error) {
+	return nil, nil
+}
+
+func (bq mockOrderByClient) Close() error {
+	return nil
+}
+
+func TestReadGoogleRowOrderByPages(t *testing.T) {
+	var queries []string
+	newSource := func(pos sdk.Position) *Source {
+		src := &Source{}
+		src.sourceConfig.Config.ProjectID = "project"
+		src.sourceConfig.Config.DatasetID = "dataset"
+		src.sourceConfig.Config.TableIDs = []string{"table1"}
+		src.sourceConfig.Config.IncrementColNames = []string{"updated_at"}
+		src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+		src.sourceConfig.Config.OrderBy = []string{"id"}
+		src.sourceConfig.Config.BatchSize = 2
+		src.bqReadClient = mockOrderByClient{
+			rows:    [][]bigquery.Value{{int64(1), int64(30)}, {int64(2), int64(10)}, {int64(3), int64(20)}},
+			queries: &queries,
+		}
+		src.records = make(chan sdk.Record, 10)
+		fetchPos(src, pos)
+		return src
+	}
+
+	src := newSource(sdk.Position{})
+	if err := src.ReadGoogleRow(context.Background(), "table1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var records []sdk.Record
+	for len(src.records) > 0 {
+		records = append(records, <-src.records)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(records))
+	}
+	if src.getPosition("table1") != "INT64 30" {
+		t.Errorf("expected offset of the greatest updated_at, got %v", src.getPosition("table1"))
+	}
+	if cursor := src.getPosition(orderCursorPositionKey("table1")); len(cursor) > 0 {
+		t.Errorf("expected cursor to be removed once the rows were read to the end, got %v", cursor)
+	}
+	want := []string{
+		"SELECT * FROM `project.dataset.table1` ORDER BY `id`, `updated_at` LIMIT 3",
+		"SELECT * FROM `project.dataset.table1` WHERE (`id` > CAST(@cursor0 AS INT64) OR (`id` = CAST(@cursor0 AS INT64) AND `updated_at` > CAST(@cursor1 AS INT64))) ORDER BY `id`, `updated_at` LIMIT 3",
+	}
+	if !reflect.DeepEqual(queries, want) {
+		t.Errorf("expected queries %q, got %q", want, queries)
+	}
+
+	// a restart after the first record resumes after its row and keeps its greatest offset
+	queries = nil
+	src = newSource(records[0].Position)
+	if err := src.ReadGoogleRow(context.Background(), "table1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var ids []interface{}
+	for len(src.records) > 0 {
+		record := <-src.records
+		ids = append(ids, record.Payload.After.(sdk.StructuredData)["id"])
+	}
+	if fmt.Sprint(ids) != "[2 3]" {
+		t.Errorf("expected the rows after the first one, got %v", ids)
+	}
+	if src.getPosition("table1") != "INT64 30" {
+		t.Errorf("expected offset of the greatest updated_at read before the restart, got %v", src.getPosition("table1"))
+	}
+	if len(queries) != 1 || !strings.Contains(queries[0], "@cursor0") {
+		t.Errorf("expected a single query after the cursor, got %q", queries)
+	}
+}
+
+func TestKeysetConditionReservedWords(t *testing.T) {
+	condition, _, err := keysetCondition("offset", []string{"order", "group"}, joinOffsets([]string{"INT64 1", "INT64 2"}), false, false)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := "(`order` > CAST(@offset0 AS INT64) OR (`order` = CAST(@offset0 AS INT64) AND `group` > CAST(@offset1 AS INT64)))"
+	if condition != want {
+		t.Errorf("expected condition %q, got %q", want, condition)
+	}
+}
+
+func TestKeysetConditionOffsetMismatch(t *testing.T) {
+	_, _, err := keysetCondition("offset", []string{"updated_at", "id"}, "INT64 2", false, false)
+	if err == nil {
+		t.Errorf("expected error for offset of a single column")
+	}
+}
+
+// mockWatermarkClient serves rows of (id, updated_at) ordered by updated_at, which isn't unique.
+// Rows equal to the offset are returned when the query compares with >=.
+type mockWatermarkClient struct {
+	rows *[][]bigquery.Value
+}
+
+func (bq mockWatermarkClient) Query(ctx context.Context, s *Source, query string, params ...bigquery.QueryParameter) (it rowIterator, err error) {
+	limit, _ := strconv.Atoi(limitRegex.FindStringSubmatch(query)[1])
+	after := offsetParam(params)
+	inclusive := strings.Contains(query, "`updated_at` >= ")
+
+	var rows [][]bigquery.Value
+	for _, row := range *bq.rows {
+		updatedAt := int(row[1].(int64))
+		if len(params) > 0 && (updatedAt < after || (updatedAt == after && !inclusive)) {
+			continue
+		}
+		if len(rows) < limit {
+			rows = append(rows, row)
+		}
+	}
+	schema := bigquery.Schema{
+		{Name: "id", Type: bigquery.StringFieldType},
+		{Name: "updated_at", Type: bigquery.IntegerFieldType},
+	}
+	return &mockRowIterator{rows: rows, schema: schema}, nil
+}
+
+func (bq mockWatermarkClient) Tables(ctx context.Context, s *Source) (tableIDs []string, err error) {
+	return nil, nil
+}
+
+func (bq mockWatermarkClient) Close() error {
+	return nil
+}
+
+func TestReadGoogleRowWatermarkBoundary(t *testing.T) {
+	rows := [][]bigquery.Value{
+		{"a", int64(1)},
+		{"b", int64(2)},
+		{"c", int64(2)},
+		{"d", int64(2)},
+		{"e", int64(3)},
+	}
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.IncrementColNames = []string{"updated_at"}
+	// the rows with updated_at 2 are split across pages
+	src.sourceConfig.Config.BatchSize = 2
+	src.bqReadClient = mockWatermarkClient{rows: &rows}
+	src.records = make(chan sdk.Record, 20)
+	fetchPos(&src, sdk.Position{})
+
+	read := func() []string {
+		src.tomb = &tomb.Tomb{}
+		err := runCDCIteratorInTomb(&src)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		var ids []string
+		for len(src.records) > 0 {
+			record := <-src.records
+			ids = append(ids, record.Payload.After.(sdk.StructuredData)["id"].(string))
+		}
+		return ids
+	}
+
+	if ids := read(); !reflect.DeepEqual(ids, []string{"a", "b", "c", "d", "e"}) {
+		t.Errorf("expected every row once, got %v", ids)
+	}
+
+	// a row added with the updated_at of the offset is read, the rows read before aren't duplicated
+	rows = append(rows, []bigquery.Value{"f", int64(3)})
+	if ids := read(); !reflect.DeepEqual(ids, []string{"f"}) {
+		t.Errorf("expected only the row equal to the watermark, got %v", ids)
+	}
+	if ids := read(); len(ids) != 0 {
+		t.Errorf("expected no rows read again, got %v", ids)
+	}
+}
+
+func TestWatermarkBoundary(t *testing.T) {
+	var boundary watermarkBoundary
+	boundary.add("INT64 2", []byte("a"))
+	boundary.add("INT64 2", []byte("b"))
+	if !boundary.seen("INT64 2", []byte("a")) || boundary.seen("INT64 2", []byte("c")) {
+		t.Errorf("expected only the added keys to be seen")
+	}
+	if boundary.skip("INT64 2") != 2 || boundary.skip("INT64 1") != 0 {
+		t.Errorf("expected 2 rows skipped at the offset, got %d", boundary.skip("INT64 2"))
+	}
+
+	// the keys are dropped once the offset grows
+	boundary.add("INT64 3", []byte("c"))
+	if boundary.seen("INT64 3", []byte("a")) || boundary.skip("INT64 3") != 1 {
+		t.Errorf("expected keys of the previous offset to be dropped")
+	}
+}
+
+func TestInclusiveOffset(t *testing.T) {
+	src := Source{}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	if src.inclusiveOffset("table1") {
+		t.Errorf("expected offset on the primary key to be exclusive")
+	}
+	src.sourceConfig.Config.IncrementColNames = []string{"updated_at", "id"}
+	if src.inclusiveOffset("table1") {
+		t.Errorf("expected offset holding the primary key to be exclusive")
+	}
+	src.sourceConfig.Config.IncrementColNames = []string{"updated_at"}
+	if !src.inclusiveOffset("table1") {
+		t.Errorf("expected offset on updated_at to be inclusive")
+	}
+}
+
+// mockOffsetClient serves the rows 1 to rows of each table after the offset of the query. Queries
+// ordered descending return the last row.
+type mockOffsetClient struct {
+	rows    map[string]int
+	queries *[]string
+}
+
+func (bq mockOffsetClient) Query(ctx context.Context, s *Source, query string, params ...bigquery.QueryParameter) (it rowIterator, err error) {
+	if bq.queries != nil {
+		*bq.queries = append(*bq.queries, query)
+	}
+	for tableID, count := range bq.rows {
+		if strings.Contains(query, "."+tableID+"`") {
+			var rows [][]bigquery.Value
+			if strings.HasSuffix(query, " DESC LIMIT 1") {
+				if count > 0 {
+					rows = append(rows, []bigquery.Value{int64(count)})
+				}
+				return &mockRowIterator{rows: rows, schema: bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}}}, nil
+			}
+			for _, param := range params {
+				// rows after the end position aren't served
+				if end, _ := strconv.Atoi(fmt.Sprint(param.Value)); param.Name == "end" && end < count {
+					count = end
+				}
+			}
+			for id := offsetParam(params) + 1; id <= count; id++ {
+				rows = append(rows, []bigquery.Value{int64(id)})
+			}
+			return &mockRowIterator{rows: rows, schema: bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}}}, nil
+		}
+	}
+	return nil, fmt.Errorf("table not found in query %s", query)
+}
+
+func (bq mockOffsetClient) Tables(ctx context.Context, s *Source) (tableIDs []string, err error) {
+	return nil, nil
+}
+
+func (bq mockOffsetClient) Close() error {
+	return nil
+}
+
+func TestRunCDCIteratorRestartMultipleTables(t *testing.T) {
+	newSource := func(pos sdk.Position) *Source {
+		src := &Source{}
+		src.sourceConfig.Config.ProjectID = "project"
+		src.sourceConfig.Config.DatasetID = "dataset"
+		src.sourceConfig.Config.TableIDs = []string{"table1", "table2"}
+		src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+		// tables are read one after the other so the position of the restart is known
+		src.sourceConfig.Config.MaxConcurrentReads = 1
+		src.bqReadClient = mockOffsetClient{rows: map[string]int{"table1": 5, "table2": 3}}
+		src.records = make(chan sdk.Record, 10)
+		src.tomb = &tomb.Tomb{}
+		fetchPos(src, pos)
+		return src
+	}
+
+	src := newSource(sdk.Position{})
+	err := runCDCIteratorInTomb(src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(src.records) != 8 {
+		t.Fatalf("expected 8 records, got %v", len(src.records))
+	}
+
+	// stop after the second row of table2 was read
+	var restart sdk.Position
+	for i := 0; i < 7; i++ {
+		record := <-src.records
+		var position Position
+		err = json.Unmarshal(record.Position, &position)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if position.Table != record.Metadata[MetadataTable] {
+			t.Errorf("expected position of table %v, got %v", record.Metadata[MetadataTable], position.Table)
+		}
+		restart = record.Position
+	}
+
+	src = newSource(restart)
+	err = runCDCIteratorInTomb(src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// table1 was read completely, table2 resumes after its own offset
+	if len(src.records) != 1 {
+		t.Fatalf("expected 1 record, got %v", len(src.records))
+	}
+	record := <-src.records
+	if record.Metadata[MetadataTable] != "table2" || record.Payload.After.(sdk.StructuredData)["id"] != int64(3) {
+		t.Errorf("expected row 3 of table2, got %v of %v", record.Payload.After, record.Metadata[MetadataTable])
+	}
+	want := map[string]string{"table1": "INT64 5", "table2": "INT64 3"}
+	if !reflect.DeepEqual(src.position.positions, want) {
+		t.Errorf("expected positions %v, got %v", want, src.position.positions)
+	}
+}
+
+func TestRunCDCIteratorRestartAfterEmptySnapshot(t *testing.T) {
+	rows := map[string]int{"table1": 0, "table2": 2}
+	newSource := func(pos sdk.Position) *Source {
+		src := &Source{}
+		src.sourceConfig.Config.ProjectID = "project"
+		src.sourceConfig.Config.DatasetID = "dataset"
+		src.sourceConfig.Config.TableIDs = []string{"table1", "table2"}
+		src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+		src.sourceConfig.Config.MaxConcurrentReads = 1
+		src.bqReadClient = mockOffsetClient{rows: rows}
+		src.records = make(chan sdk.Record, 10)
+		src.tomb = &tomb.Tomb{}
+		fetchPos(src, pos)
+		return src
+	}
+
+	// table1 is empty, its snapshot is done without any record
+	src := newSource(sdk.Position{})
+	err := runCDCIteratorInTomb(src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// the next poll returns nothing
+	src.tomb = &tomb.Tomb{}
+	err = runCDCIteratorInTomb(src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(src.records) != 2 {
+		t.Fatalf("expected 2 records, got %v", len(src.records))
+	}
+	var restart sdk.Position
+	for len(src.records) > 0 {
+		restart = (<-src.records).Position
+	}
+	var position Position
+	err = json.Unmarshal(restart, &position)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(position.SnapshotsDone, []string{"table1"}) {
+		t.Errorf("expected snapshot of table1 done in position, got %v", position.SnapshotsDone)
+	}
+
+	// a row added to table1 after the restart is a change, not part of the snapshot
+	rows["table1"] = 1
+	src = newSource(restart)
+	err = runCDCIteratorInTomb(src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(src.records) != 1 {
+		t.Fatalf("expected 1 record, got %v", len(src.records))
+	}
+	record := <-src.records
+	if record.Metadata[MetadataTable] != "table1" || record.Operation != sdk.OperationCreate {
+		t.Errorf("expected create record of table1, got %v record of %v", record.Operation, record.Metadata[MetadataTable])
+	}
+}
+
+func TestReadGoogleRowResumeSnapshot(t *testing.T) {
+	testCases := []struct {
+		name      string
+		position  string
+		query     string
+		operation sdk.Operation
+	}{
+		{
+			name:      "snapshot done",
+			position:  `{"version":1,"mode":"snapshot","offsets":{"table1":"INT64 2"},"snapshotsDone":["table1"]}`,
+			query:     "SELECT * FROM `project.dataset.table1` WHERE `id` > CAST(@offset AS INT64) ORDER BY `id` LIMIT 501",
+			operation: sdk.OperationCreate,
+		},
+		{
+			name:      "snapshot resumed",
+			position:  `{"version":1,"mode":"snapshot","offsets":{"table1":"INT64 2"}}`,
+			query:     "SELECT * FROM `project.dataset.table1` WHERE `id` > CAST(@offset AS INT64) ORDER BY `id` LIMIT 501",
+			operation: sdk.OperationSnapshot,
+		},
+		{
+			name:      "legacy polling position",
+			position:  `{"version":1,"mode":"cdc","offsets":{"table1":"INT64 2"}}`,
+			query:     "SELECT * FROM `project.dataset.table1` WHERE `id` > CAST(@offset AS INT64) ORDER BY `id` LIMIT 501",
+			operation: sdk.OperationCreate,
+		},
+		{
+			name:      "empty table snapshot done",
+			position:  `{"version":1,"mode":"cdc","offsets":{"table2":"INT64 1"},"snapshotsDone":["table1","table2"]}`,
+			query:     "SELECT * FROM `project.dataset.table1` ORDER BY `id` LIMIT 501",
+			operation: sdk.OperationCreate,
+		},
+	}
+
+	for _, tc := range testCases {
+		var queries []string
+		src := Source{}
+		src.sourceConfig.Config.ProjectID = "project"
+		src.sourceConfig.Config.DatasetID = "dataset"
+		src.sourceConfig.Config.TableIDs = []string{"table1"}
+		src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+		// the storage API is only used for snapshots read from the first row
+		src.sourceConfig.Config.ReadMode = googlebigquery.ReadModeStorage
+		src.bqReadClient = mockOffsetClient{rows: map[string]int{"table1": 3}, queries: &queries}
+		src.records = make(chan sdk.Record, 10)
+		fetchPos(&src, sdk.Position(tc.position))
+
+		err := src.ReadGoogleRow(context.Background(), "table1")
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", tc.name, err)
+		}
+		if len(queries) == 0 || queries[0] != tc.query {
+			t.Errorf("%s: expected query %q, got %q", tc.name, tc.query, queries)
+		}
+		if len(src.records) == 0 {
+			t.Fatalf("%s: expected records", tc.name)
+		}
+		if record := <-src.records; record.Operation != tc.operation {
+			t.Errorf("%s: expected %v record, got %v", tc.name, tc.operation, record.Operation)
+		}
+	}
+}
+
+func TestReadGoogleRowUpdatedRows(t *testing.T) {
+	schema := bigquery.Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType},
+		{Name: "updated_at", Type: bigquery.IntegerFieldType},
+	}
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.IncrementColNames = []string{"updated_at"}
+	src.sourceConfig.Config.KeyCacheSize = 10
+	src.bqReadClient = mockTableClient{
+		schema: schema,
+		tables: map[string][][]bigquery.Value{
+			"table1": {{int64(1), int64(100)}, {int64(2), int64(101)}},
+		},
+	}
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	err := runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for len(src.records) > 0 {
+		<-src.records
+	}
+
+	// row 1 is updated which bumps its updated_at, row 3 is new
+	src.bqReadClient = mockTableClient{
+		schema: schema,
+		tables: map[string][][]bigquery.Value{
+			"table1": {{int64(3), int64(102)}, {int64(1), int64(103)}},
+		},
+	}
+	src.tomb = &tomb.Tomb{}
+	err = runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(src.records) != 2 {
+		t.Fatalf("expected 2 records, got %v", len(src.records))
+	}
+	want := []sdk.Operation{sdk.OperationCreate, sdk.OperationUpdate}
+	for i := range want {
+		record := <-src.records
+		if record.Operation != want[i] {
+			t.Errorf("expected operation %v for row %v, got %v", want[i], record.Payload.After, record.Operation)
+		}
+	}
+}
+
+func TestKeyCacheEvictsOldestKeys(t *testing.T) {
+	var cache keyCache
+	for _, key := range []string{"1", "2", "3"} {
+		if cache.seen("table1", []byte(key), 2) {
+			t.Errorf("expected key %v to be new", key)
+		}
+	}
+	// key 1 was evicted to make room for key 3
+	if cache.seen("table1", []byte("1"), 2) {
+		t.Errorf("expected evicted key to be new")
+	}
+	if !cache.seen("table1", []byte("3"), 2) {
+		t.Errorf("expected key 3 to be seen")
+	}
+	if cache.seen("table2", []byte("3"), 2) {
+		t.Errorf("expected keys to be tracked per table")
+	}
+	if cache.seen("table1", []byte("3"), 0) {
+		t.Errorf("expected disabled cache to never report seen keys")
+	}
+}
+
+// mockChangesClient serves the rows of table1 to snapshot queries and the changes to queries of
+// the change functions. Functions with an error in errs fail.
+type mockChangesClient struct {
+	rows    [][]bigquery.Value
+	changes [][]bigquery.Value
+	errs    map[string]error
+	queries *[]string
+	params  *[]bigquery.QueryParameter
+}
+
+func (bq mockChangesClient) Query(ctx context.Context, s *Source, query string, params ...bigquery.QueryParameter) (it rowIterator, err error) {
+	*bq.queries = append(*bq.queries, query)
+	*bq.params = append(*bq.params, params...)
+	schema := bigquery.Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType},
+		{Name: "name", Type: bigquery.StringFieldType},
+	}
+	for _, function := range []string{changesFunction, appendsFunction} {
+		if strings.Contains(query, " "+function+"(TABLE ") {
+			if err := bq.errs[function]; err != nil {
+				return nil, err
+			}
+			schema = append(schema,
+				&bigquery.FieldSchema{Name: changeTypeColumn, Type: bigquery.StringFieldType},
+				&bigquery.FieldSchema{Name: changeTimestampColumn, Type: bigquery.TimestampFieldType})
+			return &mockRowIterator{rows: bq.changes, schema: schema}, nil
+		}
+	}
+	return &mockRowIterator{rows: bq.rows, schema: schema}, nil
+}
+
+func (bq mockChangesClient) Tables(ctx context.Context, s *Source) (tableIDs []string, err error) {
+	return nil, nil
+}
+
+func (bq mockChangesClient) Close() error {
+	return nil
+}
+
+func newChangeHistorySource(client mockChangesClient, pos sdk.Position) *Source {
+	src := &Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.CDCMode = googlebigquery.CDCModeChangeHistory
+	src.bqReadClient = client
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(src, pos)
+	return src
+}
+
+func TestReadGoogleRowChangeHistory(t *testing.T) {
+	var queries []string
+	var params []bigquery.QueryParameter
+	changedAt := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	src := newChangeHistorySource(mockChangesClient{
+		changes: [][]bigquery.Value{
+			{int64(3), "new", "INSERT", changedAt},
+			{int64(1), "renamed", "UPDATE", changedAt},
+			{int64(2), nil, "DELETE", changedAt},
+		},
+		queries: &queries,
+		params:  &params,
+	}, sdk.Position(`{"version":1,"mode":"cdc","offsets":{"table1":"INT64 2","table1#changes":"TIMESTAMP 2022-01-02 00:00:00+00:00"}}`))
+
+	err := runCDCIteratorInTomb(src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := "SELECT * FROM CHANGES(TABLE `project.dataset.table1`, CAST(@start AS TIMESTAMP), CAST(@end AS TIMESTAMP)) " +
+		"WHERE _CHANGE_TIMESTAMP > CAST(@start AS TIMESTAMP) ORDER BY _CHANGE_TIMESTAMP"
+	if len(queries) != 1 || queries[0] != want {
+		t.Errorf("expected query %v, got %v", want, queries)
+	}
+	if params[0].Name != "start" || params[0].Value != "2022-01-02 00:00:00+00:00" {
+		t.Errorf("expected start parameter, got %v", params[0])
+	}
+
+	if len(src.records) != 3 {
+		t.Fatalf("expected 3 records, got %v", len(src.records))
+	}
+	wantOperations := []sdk.Operation{sdk.OperationCreate, sdk.OperationUpdate, sdk.OperationDelete}
+	for i := range wantOperations {
+		record := <-src.records
+		if record.Operation != wantOperations[i] {
+			t.Errorf("expected operation %v, got %v", wantOperations[i], record.Operation)
+		}
+		if record.Operation == sdk.OperationDelete {
+			if key := string(record.Key.Bytes()); key != "2" {
+				t.Errorf("expected key 2 of deleted row, got %v", key)
+			}
+			if record.Payload.After != nil {
+				t.Errorf("expected delete without payload, got %v", record.Payload.After)
+			}
+		} else if _, ok := record.Payload.After.(sdk.StructuredData)[changeTypeColumn]; ok {
+			t.Errorf("expected payload without change columns, got %v", record.Payload.After)
+		}
+	}
+
+	// the watermark moves to the end of the changes read
+	if watermark := src.position.positions["table1#changes"]; watermark <= "TIMESTAMP 2022-01-02 00:00:00+00:00" {
+		t.Errorf("expected watermark to advance, got %v", watermark)
+	}
+}
+
+func TestReadGoogleRowChangeHistorySnapshot(t *testing.T) {
+	var queries []string
+	var params []bigquery.QueryParameter
+	src := newChangeHistorySource(mockChangesClient{
+		rows:    [][]bigquery.Value{{int64(1), "one"}, {int64(2), "two"}},
+		queries: &queries,
+		params:  &params,
+	}, sdk.Position{})
+
+	before := time.Now().Truncate(time.Microsecond)
+	err := runCDCIteratorInTomb(src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(src.records) != 2 {
+		t.Fatalf("expected 2 records, got %v", len(src.records))
+	}
+	for len(src.records) > 0 {
+		if record := <-src.records; record.Operation != sdk.OperationSnapshot {
+			t.Errorf("expected snapshot operation, got %v", record.Operation)
+		}
+	}
+
+	// changes are read from the start of the snapshot, which is too recent for CHANGES yet
+	for _, query := range queries {
+		if strings.Contains(query, changesFunction) {
+			t.Errorf("expected no changes query, got %v", query)
+		}
+	}
+	_, watermark := parseOffset(src.position.positions["table1#changes"])
+	started, err := time.Parse(timestampOffsetLayout, watermark)
+	if err != nil || started.Before(before) {
+		t.Errorf("expected watermark at the start of the snapshot, got %v (%v)", watermark, err)
+	}
+	if _, ok := src.position.positions["table1#snapshot"]; ok {
+		t.Errorf("expected snapshot start to be removed, got %v", src.position.positions)
+	}
+}
+
+func TestReadGoogleRowChangeHistoryFallback(t *testing.T) {
+	var queries []string
+	var params []bigquery.QueryParameter
+	unsupported := &googleapi.Error{Code: 400, Message: "change history is not enabled"}
+	src := newChangeHistorySource(mockChangesClient{
+		rows: [][]bigquery.Value{{int64(3), "three"}},
+		errs: map[string]error{
+			changesFunction: unsupported,
+			appendsFunction: unsupported,
+		},
+		queries: &queries,
+		params:  &params,
+	}, sdk.Position(`{"version":1,"mode":"cdc","offsets":{"table1":"INT64 2","table1#changes":"TIMESTAMP 2022-01-02 00:00:00+00:00"}}`))
+
+	err := runCDCIteratorInTomb(src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// CHANGES, APPENDS and then the polling query are run
+	if len(queries) != 3 || !strings.Contains(queries[1], appendsFunction) ||
+		queries[2] != "SELECT * FROM `project.dataset.table1` WHERE `id` > CAST(@offset AS INT64) ORDER BY `id` LIMIT 501" {
+		t.Errorf("expected fallback to APPENDS and polling, got %v", queries)
+	}
+	if src.changeHistory(context.Background(), "table1") {
+		t.Errorf("expected table to fall back to polling")
+	}
+	if len(src.records) != 1 {
+		t.Fatalf("expected 1 record, got %v", len(src.records))
+	}
+	if record := <-src.records; record.Operation != sdk.OperationCreate {
+		t.Errorf("expected create operation, got %v", record.Operation)
+	}
+}
+
+func TestReadGoogleRowDetectDeletes(t *testing.T) {
+	var queries []string
+	schema := bigquery.Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType},
+		{Name: "name", Type: bigquery.StringFieldType},
+	}
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.DetectDeletes = true
+	src.bqReadClient = mockTableClient{
+		schema: schema,
+		tables: map[string][][]bigquery.Value{
+			"table1": {{int64(1), "one"}, {int64(2), "two"}, {int64(3), "three"}},
+		},
+		queries: &queries,
+	}
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	err := runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := "SELECT `id` FROM `project.dataset.table1`"
+	if queries[len(queries)-1] != want {
+		t.Errorf("expected primary key scan %v, got %v", want, queries[len(queries)-1])
+	}
+	for len(src.records) > 0 {
+		if record := <-src.records; record.Operation == sdk.OperationDelete {
+			t.Errorf("expected no delete on the first scan, got %v", record)
+		}
+	}
+
+	// row 2 is deleted
+	src.bqReadClient = mockTableClient{
+		schema: schema,
+		tables: map[string][][]bigquery.Value{
+			"table1": {{int64(1), "one"}, {int64(3), "three"}},
+		},
+		queries: &queries,
+	}
+	src.tomb = &tomb.Tomb{}
+	err = runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var deletes []sdk.Record
+	for len(src.records) > 0 {
+		if record := <-src.records; record.Operation == sdk.OperationDelete {
+			deletes = append(deletes, record)
+		}
+	}
+	if len(deletes) != 1 {
+		t.Fatalf("expected 1 delete record, got %v", len(deletes))
+	}
+	if key := string(deletes[0].Key.Bytes()); key != "2" {
+		t.Errorf("expected key 2 of deleted row, got %v", key)
+	}
+	if deletes[0].Metadata[MetadataTable] != "table1" {
+		t.Errorf("expected table metadata, got %v", deletes[0].Metadata)
+	}
+}
+
+func TestKeySetScanInterval(t *testing.T) {
+	var keys keySet
+	if !keys.due("table1", time.Hour) {
+		t.Errorf("expected table never scanned to be due")
+	}
+	keys.replace("table1", map[string]struct{}{"1": {}})
+	if keys.due("table1", time.Hour) {
+		t.Errorf("expected table scanned just now not to be due")
+	}
+
+	// keys added after the scan are reported missing once they disappear
+	keys.add("table1", []byte("2"))
+	missing := keys.replace("table1", map[string]struct{}{"1": {}})
+	if !reflect.DeepEqual(missing, []string{"2"}) {
+		t.Errorf("expected key 2 to be missing, got %v", missing)
+	}
+}
+
+func TestPollBackoff(t *testing.T) {
+	backoff := newPollBackoff(time.Minute, 5*time.Minute)
+
+	// ticks polled after each empty poll grow till the cap
+	for _, want := range []int{1, 2, 4, 5, 5} {
+		ticks := 1
+		for !backoff.tick() {
+			ticks++
+		}
+		if ticks != want {
+			t.Errorf("expected poll after %v ticks, got %v", want, ticks)
+		}
+		backoff.polled(false)
+	}
+
+	backoff.polled(true)
+	if backoff.interval != time.Minute {
+		t.Errorf("expected interval reset to %v, got %v", time.Minute, backoff.interval)
+	}
+	if !backoff.tick() {
+		t.Errorf("expected poll on the next tick once rows were read")
+	}
+
+	// without cap the interval stays fixed
+	backoff = newPollBackoff(time.Minute, 0)
+	backoff.polled(false)
+	if backoff.interval != time.Minute {
+		t.Errorf("expected fixed interval without cap, got %v", backoff.interval)
+	}
+}
+
+func TestReadGoogleRowCDCMode(t *testing.T) {
+	var queries []string
+	rows := map[string]int{"table1": 5, "table2": 0}
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.TableIDs = []string{"table1", "table2"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.Mode = googlebigquery.ModeCDC
+	// the queries are collected by the mock, tables are read one after the other
+	src.sourceConfig.Config.MaxConcurrentReads = 1
+	src.bqReadClient = mockOffsetClient{rows: rows, queries: &queries}
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	// rows inserted before the start are skipped
+	err := runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(src.records) != 0 {
+		t.Fatalf("expected no records, got %v", len(src.records))
+	}
+	want := "SELECT `id` FROM `project.dataset.table1` ORDER BY `id` DESC LIMIT 1"
+	found := false
+	for _, query := range queries {
+		found = found || query == want
+	}
+	if !found {
+		t.Errorf("expected watermark query %q, got %q", want, queries)
+	}
+
+	// rows inserted after the start are read, also from the table which was empty
+	rows["table1"] = 7
+	rows["table2"] = 1
+	src.tomb = &tomb.Tomb{}
+	err = runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(src.records) != 3 {
+		t.Fatalf("expected 3 records, got %v", len(src.records))
+	}
+	ids := make(map[string][]int64)
+	for len(src.records) > 0 {
+		record := <-src.records
+		if record.Operation != sdk.OperationCreate {
+			t.Errorf("expected create operation, got %v", record.Operation)
+		}
+		table := record.Metadata[MetadataTable]
+		ids[table] = append(ids[table], record.Payload.After.(sdk.StructuredData)["id"].(int64))
+	}
+	wantIDs := map[string][]int64{"table1": {6, 7}, "table2": {1}}
+	if !reflect.DeepEqual(ids, wantIDs) {
+		t.Errorf("expected rows %v, got %v", wantIDs, ids)
+	}
+}
+
+// mockFlakyClient fails the first queries with err before passing them to the wrapped client
+type mockFlakyClient struct {
+	bqClient
+	failures int
+	err      error
+	calls    *int
+}
+
+func (bq mockFlakyClient) Query(ctx context.Context, s *Source, query string, params ...bigquery.QueryParameter) (it rowIterator, err error) {
+	*bq.calls++
+	if *bq.calls <= bq.failures {
+		return nil, bq.err
+	}
+	return bq.bqClient.Query(ctx, s, query, params...)
+}
+
+func TestReadGoogleRowRetriesTransientErrors(t *testing.T) {
+	tables := mockTableClient{
+		schema: bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}},
+		tables: map[string][][]bigquery.Value{"table1": {{int64(1)}, {int64(2)}}},
+	}
+	unavailable := &googleapi.Error{Code: http.StatusServiceUnavailable, Message: "backend unavailable"}
+	rateLimited := &googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "rateLimitExceeded"}}}
+	denied := &googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "accessDenied"}}}
+
+	tests := []struct {
+		name      string
+		err       error
+		failures  int
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "succeeds after two transient errors", err: unavailable, failures: 2, wantCalls: 3},
+		{name: "retries rate limit errors", err: rateLimited, failures: 1, wantCalls: 2},
+		{name: "gives up after max retries", err: unavailable, failures: 10, wantCalls: 4, wantErr: true},
+		{name: "doesn't retry permanent errors", err: denied, failures: 1, wantCalls: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			src := Source{}
+			src.sourceConfig.Config.TableIDs = []string{"table1"}
+			src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+			src.sourceConfig.Config.MaxRetries = 3
+			src.sourceConfig.Config.RetryDelay = time.Millisecond
+			src.bqReadClient = mockFlakyClient{bqClient: tables, failures: tt.failures, err: tt.err, calls: &calls}
+			src.records = make(chan sdk.Record, 10)
+			fetchPos(&src, sdk.Position{})
+
+			err := src.ReadGoogleRow(context.Background(), "table1")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			// the snapshot is read by the query succeeding after the failures
+			if calls != tt.wantCalls {
+				t.Errorf("expected %v queries, got %v", tt.wantCalls, calls)
+			}
+			if !tt.wantErr && len(src.records) != 2 {
+				t.Errorf("expected 2 records, got %v", len(src.records))
+			}
+		})
+	}
+}
+
+func TestQueryRetryHonorsContext(t *testing.T) {
+	calls := 0
+	src := Source{}
+	src.sourceConfig.Config.MaxRetries = 3
+	src.sourceConfig.Config.RetryDelay = time.Hour
+	src.bqReadClient = mockFlakyClient{failures: 10, err: &googleapi.Error{Code: http.StatusServiceUnavailable}, calls: &calls}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := src.query(ctx, "SELECT 1")
+	if err != context.Canceled {
+		t.Errorf("expected context canceled, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected a single query, got %v", calls)
+	}
+}
+
+// mockDeadClient fails every query with a connection error and records if it was closed
+type mockDeadClient struct {
+	mockQueryClient
+	closed *bool
+}
+
+func (bq mockDeadClient) Query(ctx context.Context, s *Source, query string, params ...bigquery.QueryParameter) (it rowIterator, err error) {
+	*bq.queries = append(*bq.queries, query)
+	return nil, &url.Error{Op: "Post", URL: "https://bigquery.googleapis.com", Err: errors.New("connection reset by peer")}
+}
+
+func (bq mockDeadClient) Close() error {
+	*bq.closed = true
+	return nil
+}
+
+func TestReadGoogleRowRecreatesDeadClient(t *testing.T) {
+	var queries []string
+	closed := false
+	connects := 0
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.MaxRetries = 5
+	src.sourceConfig.Config.RetryDelay = time.Millisecond
+	src.bqReadClient = mockDeadClient{mockQueryClient: mockQueryClient{queries: &queries}, closed: &closed}
+	src.connect = func() (bqClient, error) {
+		connects++
+		return mockTableClient{
+			schema: bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}},
+			tables: map[string][][]bigquery.Value{"table1": {{int64(1)}, {int64(2)}}},
+		}, nil
+	}
+	src.records = make(chan sdk.Record, 10)
+	fetchPos(&src, sdk.Position{})
+
+	err := src.ReadGoogleRow(context.Background(), "table1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(queries) != maxConnectionFailures {
+		t.Errorf("expected %v queries on the dead client, got %v", maxConnectionFailures, len(queries))
+	}
+	if connects != 1 || !closed {
+		t.Errorf("expected the dead client to be closed and replaced once, got %v connects, closed %v", connects, closed)
+	}
+	if len(src.records) != 2 {
+		t.Errorf("expected 2 records, got %v", len(src.records))
+	}
+	if src.connectionFailures != 0 {
+		t.Errorf("expected the connection failures to be reset, got %v", src.connectionFailures)
+	}
+}
+
+func TestReconnectOncePerGeneration(t *testing.T) {
+	connects := 0
+	src := Source{}
+	src.bqReadClient = mockTableClient{}
+	src.connect = func() (bqClient, error) {
+		connects++
+		return mockTableClient{}, nil
+	}
+
+	// tables failing on the same dead client only replace it once
+	_, generation := src.readClient()
+	for i := 0; i < 3; i++ {
+		if err := src.reconnect(context.Background(), generation); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	if connects != 1 {
+		t.Errorf("expected a single reconnect, got %v", connects)
+	}
+
+	src.clientClosed = true
+	_, generation = src.readClient()
+	if err := src.reconnect(context.Background(), generation); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if connects != 1 {
+		t.Errorf("expected no reconnect once the client is closed, got %v", connects)
+	}
+}
+
+func TestQueryMaxBytesBilledError(t *testing.T) {
+	calls := 0
+	exceeded := &bigquery.Error{Reason: "bytesBilledLimitExceeded", Message: "Query exceeded limit for bytes billed"}
+	src := Source{}
+	src.sourceConfig.Config.MaxRetries = 3
+	src.sourceConfig.Config.RetryDelay = time.Millisecond
+	src.bqReadClient = mockFlakyClient{failures: 10, err: exceeded, calls: &calls}
+
+	_, err := src.query(context.Background(), "SELECT 1")
+	if !errors.Is(err, ErrMaxBytesBilled) || !errors.Is(err, exceeded) {
+		t.Errorf("expected max bytes billed error wrapping %v, got %v", exceeded, err)
+	}
+	if calls != 1 {
+		t.Errorf("expected a single query, got %v", calls)
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: &googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "quotaExceeded"}}}, want: true},
+		{err: &googleapi.Error{Code: http.StatusTooManyRequests}, want: true},
+		{err: fmt.Errorf("job failed: %w", &bigquery.Error{Reason: "rateLimitExceeded"}), want: true},
+		{err: &googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "accessDenied"}}}, want: false},
+		{err: fmt.Errorf("mock error"), want: false},
+	}
+	for _, tt := range tests {
+		err := classifyError(tt.err)
+		if errors.Is(err, ErrRateLimited) != tt.want {
+			t.Errorf("expected rate limited %v for %v, got %v", tt.want, tt.err, err)
+		}
+		if !errors.Is(err, tt.err) {
+			t.Errorf("expected %v to wrap %v", err, tt.err)
+		}
+	}
+}
+
+func TestPollBackoffThrottled(t *testing.T) {
+	backoff := newPollBackoff(time.Second, 5*time.Second)
+
+	// throttled polls pause beyond the cap of empty polls
+	for _, want := range []time.Duration{minThrottledInterval, 2 * minThrottledInterval, 4 * minThrottledInterval} {
+		backoff.throttled()
+		if backoff.interval != want {
+			t.Errorf("expected interval %v, got %v", want, backoff.interval)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		backoff.throttled()
+	}
+	if backoff.interval != maxThrottledInterval {
+		t.Errorf("expected interval capped at %v, got %v", maxThrottledInterval, backoff.interval)
+	}
+
+	backoff.polled(true)
+	if backoff.interval != time.Second {
+		t.Errorf("expected interval reset to %v, got %v", time.Second, backoff.interval)
+	}
+}
+
+func TestRunIteratorBacksOffWhenRateLimited(t *testing.T) {
+	calls := 0
+	quotaErr := &googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "quotaExceeded"}}}
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.MaxConcurrentReads = 1
+	src.bqReadClient = mockFlakyClient{failures: 1000, err: quotaErr, calls: &calls}
+	src.records = make(chan sdk.Record, 10)
+	src.ticker = time.NewTicker(time.Millisecond)
+	src.backoff = newPollBackoff(time.Millisecond, time.Millisecond)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	startIterator(&src)
+	time.Sleep(100 * time.Millisecond)
+
+	// the iterator keeps running and pauses polling instead of querying every tick
+	select {
+	case <-src.tomb.Dying():
+		t.Fatalf("expected iterator to keep running, got %v", src.tomb.Err())
+	default:
+	}
+	src.tomb.Kill(nil)
+	_ = src.tomb.Wait()
+	src.ticker.Stop()
+
+	// quota errors aren't retried, the first poll is the only query
+	if calls != 1 {
+		t.Errorf("expected a single query while throttled, got %v", calls)
+	}
+	if src.backoff.interval != minThrottledInterval {
+		t.Errorf("expected polling paused for %v, got %v", minThrottledInterval, src.backoff.interval)
+	}
+}
+
+func TestReadGoogleRowTableNotFound(t *testing.T) {
+	notFoundErr := &googleapi.Error{Code: http.StatusNotFound, Message: "Not found: Table project:dataset.missing"}
+
+	// configured tables which don't exist fail the sync
+	calls := 0
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"missing"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.bqReadClient = mockFlakyClient{failures: 1, err: notFoundErr, calls: &calls}
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	err := runCDCIteratorInTomb(&src)
+	if !errors.Is(err, ErrTableNotFound) {
+		t.Fatalf("expected table not found error, got %v", err)
+	}
+	if !errors.Is(err, notFoundErr) || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("expected error to name the table and wrap the BigQuery error, got %v", err)
+	}
+
+	// tables discovered in the dataset which disappeared are skipped
+	calls = 0
+	src.sourceConfig.Config.TableIDs = nil
+	src.bqReadClient = mockFlakyClient{bqClient: mockTableClient{tables: map[string][][]bigquery.Value{"missing": nil}}, failures: 1, err: notFoundErr, calls: &calls}
+	src.tomb = &tomb.Tomb{}
+	err = runCDCIteratorInTomb(&src)
+	if err != nil {
+		t.Errorf("expected discovered table to be skipped, got %v", err)
+	}
+}
+
+// mockMetadataClient returns the metadata of the tables it holds and not found for others
+type mockMetadataClient struct {
+	tables map[string]bool
+	schema bigquery.Schema
+	err    error
+}
+
+func (bq mockMetadataClient) TableMetadata(ctx context.Context, s *Source, tableID string) (*bigquery.TableMetadata, error) {
+	if bq.err != nil {
+		return nil, bq.err
+	}
+	if !bq.tables[tableID] {
+		return nil, &googleapi.Error{Code: http.StatusNotFound, Message: "Not found: Table " + tableID}
+	}
+	return &bigquery.TableMetadata{Name: tableID, Schema: bq.schema}, nil
+}
+
+func TestValidateTables(t *testing.T) {
+	client := mockMetadataClient{tables: map[string]bool{"table1": true}}
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.TableIDs = []string{"table1", "tabel2", "table3"}
+
+	err := src.validateTables(context.Background(), client)
+	if err == nil {
+		t.Fatalf("expected error for missing tables")
+	}
+	if !errors.Is(err, ErrTableNotFound) {
+		t.Errorf("expected ErrTableNotFound, got %v", err)
+	}
+	want := "table not found: tables tabel2, table3 not found in dataset project.dataset, check tableID or set skipTableValidation to skip this check"
+	if err.Error() != want {
+		t.Errorf("expected error %q, got %q", want, err.Error())
+	}
+
+	src.sourceConfig.Config.SkipTableValidation = true
+	if err := src.validateTables(context.Background(), client); err != nil {
+		t.Errorf("expected validation to be skipped, got %v", err)
+	}
+
+	src.sourceConfig.Config.SkipTableValidation = false
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	if err := src.validateTables(context.Background(), client); err != nil {
+		t.Errorf("expected no error for existing table, got %v", err)
+	}
+
+	client.err = &googleapi.Error{Code: http.StatusForbidden, Message: "Access Denied"}
+	if err := src.validateTables(context.Background(), client); err == nil || !errors.Is(err, client.err) {
+		t.Errorf("expected access denied error, got %v", err)
+	}
+}
+
+func TestValidateTablesColumns(t *testing.T) {
+	client := mockMetadataClient{
+		tables: map[string]bool{"table1": true},
+		schema: bigquery.Schema{
+			{Name: "id", Type: bigquery.IntegerFieldType},
+			{Name: "updated_at", Type: bigquery.TimestampFieldType},
+			{Name: "address", Type: bigquery.RecordFieldType},
+			{Name: "tags", Type: bigquery.StringFieldType, Repeated: true},
+			{Name: "payload", Type: bigquery.JSONFieldType},
+			{Name: "location", Type: bigquery.GeographyFieldType},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		increment  []string
+		primaryKey []string
+		wantErr    string
+		missing    bool
+	}{
+		{name: "valid columns", increment: []string{"updated_at", "id"}, primaryKey: []string{"id"}},
+		{name: "ordered by primary key", primaryKey: []string{"id"}},
+		{name: "missing incrementing column", increment: []string{"updatedAt"}, primaryKey: []string{"id"},
+			wantErr: "column missing: incrementing column updatedAt not found in table table1", missing: true},
+		{name: "missing primary key column", increment: []string{"id"}, primaryKey: []string{"ID"},
+			wantErr: "column missing: primary key column ID not found in table table1", missing: true},
+		{name: "record column", increment: []string{"address"}, primaryKey: []string{"id"},
+			wantErr: "incrementing column address of table table1 has type RECORD, which rows can't be ordered by"},
+		{name: "json column", increment: []string{"payload"}, primaryKey: []string{"id"},
+			wantErr: "incrementing column payload of table table1 has type JSON, which rows can't be ordered by"},
+		{name: "geography column", increment: []string{"location"}, primaryKey: []string{"id"},
+			wantErr: "incrementing column location of table table1 has type GEOGRAPHY, which rows can't be ordered by"},
+		{name: "repeated column", increment: []string{"tags"}, primaryKey: []string{"id"},
+			wantErr: "incrementing column tags of table table1 has type repeated STRING, which rows can't be ordered by"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := Source{}
+			src.sourceConfig.Config.TableIDs = []string{"table1"}
+			src.sourceConfig.Config.IncrementColNames = tt.increment
+			src.sourceConfig.Config.PrimaryKeyColNames = tt.primaryKey
+
+			err := src.validateTables(context.Background(), client)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("expected error %q, got %v", tt.wantErr, err)
+			}
+			if errors.Is(err, ErrColumnMissing) != tt.missing {
+				t.Errorf("expected errors.Is(err, ErrColumnMissing) to be %v, got %v", tt.missing, err)
+			}
+		})
+	}
+}
+
+// mockBilledClient reports the bytes processed by every query like a finished BigQuery job
+type mockBilledClient struct {
+	bqClient
+	bytes int64
+}
+
+func (bq mockBilledClient) Query(ctx context.Context, s *Source, query string, params ...bigquery.QueryParameter) (it rowIterator, err error) {
+	s.addBytesScanned(bq.bytes)
+	return bq.bqClient.Query(ctx, s, query, params...)
+}
+
+func TestReadGoogleRowCountsRowsAndBytes(t *testing.T) {
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.bqReadClient = mockBilledClient{
+		bqClient: mockTableClient{
+			schema: bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}},
+			tables: map[string][][]bigquery.Value{"table1": {{int64(1)}, {int64(2)}, {int64(3)}}},
+		},
+		bytes: 1024,
+	}
+	src.records = make(chan sdk.Record, 10)
+	fetchPos(&src, sdk.Position{})
+
+	stats := src.startPoll()
+	if err := src.ReadGoogleRow(context.Background(), "table1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	src.logPoll(context.Background(), stats)
+
+	if src.RowsRead() != 3 {
+		t.Errorf("expected 3 rows read, got %v", src.RowsRead())
+	}
+	if src.BytesScanned() != 1024 {
+		t.Errorf("expected 1024 bytes scanned, got %v", src.BytesScanned())
+	}
+
+	// counters add up across polls
+	if err := src.ReadGoogleRow(context.Background(), "table1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if src.BytesScanned() != 2048 {
+		t.Errorf("expected 2048 bytes scanned, got %v", src.BytesScanned())
+	}
+}
+
+func TestReadGoogleRowLag(t *testing.T) {
+	start := time.Date(2022, 1, 2, 15, 0, 0, 0, time.UTC)
+	now := start.Add(time.Hour)
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1", "table2", "table3"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.TableIncrementColNames = map[string][]string{
+		"table1": {"updated_at"},
+		"table2": {"updated_at"},
+		"table3": {"id"},
+	}
+	src.sourceConfig.Config.MaxConcurrentReads = 1
+	src.bqReadClient = mockTableClient{
+		schema: bigquery.Schema{
+			{Name: "id", Type: bigquery.IntegerFieldType},
+			{Name: "updated_at", Type: bigquery.TimestampFieldType},
+		},
+		tables: map[string][][]bigquery.Value{
+			"table1": {{int64(1), start.Add(-time.Minute)}, {int64(2), start}},
+			"table2": {{int64(1), start.Add(30 * time.Minute)}},
+			"table3": {{int64(1), start.Add(-24 * time.Hour)}},
+		},
+	}
+	src.now = func() time.Time { return now }
+	src.records = make(chan sdk.Record, 10)
+	fetchPos(&src, sdk.Position{})
+
+	if _, ok := src.Lag(); ok {
+		t.Errorf("expected no lag before the first poll")
+	}
+
+	src.tomb = &tomb.Tomb{}
+	stats := src.startPoll()
+	if err := runCDCIteratorInTomb(&src); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	src.logPoll(context.Background(), stats)
+
+	// table1 is the furthest behind, table3 is incremented by id and not measured
+	lag, ok := src.Lag()
+	if !ok || lag != time.Hour {
+		t.Errorf("expected lag of 1h, got %v", lag)
+	}
+
+	// the lag grows while no newer rows are read
+	now = now.Add(10 * time.Minute)
+	src.logPoll(context.Background(), src.startPoll())
+	if lag, _ := src.Lag(); lag != 70*time.Minute {
+		t.Errorf("expected lag of 1h10m, got %v", lag)
+	}
+}
+
+func TestFormatParams(t *testing.T) {
+	_, params, err := keysetCondition("offset", []string{"updated_at", "id"}, joinOffsets([]string{"TIMESTAMP 2022-01-02 15:04:05.000000 UTC", "INT64 5"}), false, false)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := "@offset0=2022-01-02 15:04:05.000000 UTC, @offset1=5"
+	if got := formatParams(params); got != want {
+		t.Errorf("expected params %q, got %q", want, got)
+	}
+	if got := formatParams(nil); got != "" {
+		t.Errorf("expected no params, got %q", got)
+	}
+}
+
+// mockEstimateClient dry runs queries, estimating the bytes of the table referenced in the query
+type mockEstimateClient struct {
+	mockQueryClient
+	bytes map[string]int64
+}
+
+func (bq mockEstimateClient) Estimate(ctx context.Context, s *Source, query string, params ...bigquery.QueryParameter) (int64, error) {
+	for tableID, bytes := range bq.bytes {
+		if strings.Contains(query, "."+tableID+"`") {
+			return bytes, nil
+		}
+	}
+	return 0, fmt.Errorf("table not found in query %s", query)
+}
+
+func TestRunDryRun(t *testing.T) {
+	var queries []string
+	var logs bytes.Buffer
+	src := Source{}
+	src.sourceConfig.Config.TableIDs = []string{"table1", "table2"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.DryRun = true
+	src.bqReadClient = mockEstimateClient{
+		mockQueryClient: mockQueryClient{queries: &queries},
+		bytes:           map[string]int64{"table1": 1024, "table2": 2048},
+	}
+	ctx := zerolog.New(&logs).WithContext(context.Background())
+	src.records = make(chan sdk.Record, 10)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	src.tomb.Go(func() error {
+		return src.runDryRun(ctx)
+	})
+	<-src.tomb.Dead()
+
+	if err := src.tomb.Err(); err != nil {
+		t.Errorf("expected dry run to stop without error, got %v", err)
+	}
+	_, err := src.Read(context.Background())
+	if !errors.Is(err, sdk.ErrBackoffRetry) {
+		t.Errorf("expected backoff retry, got %v", err)
+	}
+	if len(src.records) != 0 || len(queries) != 0 {
+		t.Errorf("expected no records and no queries run, got %v records and queries %v", len(src.records), queries)
+	}
+	for _, estimate := range []string{`"tableID":"table1","bytesProcessed":1024`, `"tableID":"table2","bytesProcessed":2048`, `"bytesProcessed":3072`} {
+		if !strings.Contains(logs.String(), estimate) {
+			t.Errorf("expected estimate %s to be logged, got %s", estimate, logs.String())
+		}
+	}
+}
+
+func TestSeedStartPosition(t *testing.T) {
+	schema := bigquery.Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType},
+		{Name: "created_at", Type: bigquery.TimestampFieldType},
+	}
+	client := mockMetadataClient{tables: map[string]bool{"table1": true, "table2": true}, schema: schema}
+	var queries []string
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.TableIDs = []string{"table1", "table2"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.StartPosition = "1"
+	src.bqReadClient = mockOffsetClient{rows: map[string]int{"table1": 3, "table2": 3}, queries: &queries}
+	src.records = make(chan sdk.Record, 10)
+	// the saved position of table2 is kept
+	fetchPos(&src, sdk.Position(`{"version":1,"mode":"snapshot","offsets":{"table2":"INT64 2"},"snapshotsDone":["table2"]}`))
+
+	if err := src.seedStartPosition(context.Background(), client); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := src.getPosition("table1"); got != "INT64 1" {
+		t.Errorf("expected table1 to start after %q, got %q", "INT64 1", got)
+	}
+	if got := src.getPosition("table2"); got != "INT64 2" {
+		t.Errorf("expected table2 to keep its position %q, got %q", "INT64 2", got)
+	}
+
+	if err := src.ReadGoogleRow(context.Background(), "table1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := "SELECT * FROM `project.dataset.table1` WHERE `id` > CAST(@offset AS INT64) ORDER BY `id` LIMIT 501"
+	if len(queries) == 0 || queries[0] != want {
+		t.Errorf("expected query %q, got %q", want, queries)
+	}
+	if len(src.records) != 2 {
+		t.Fatalf("expected the 2 records after the start position, got %d", len(src.records))
+	}
+	if record := <-src.records; record.Operation != sdk.OperationCreate {
+		t.Errorf("expected create record, got %v", record.Operation)
+	}
+
+	// the value has to match the type of the incrementing column
+	src.setPosition("table1", "")
+	delete(src.position.snapshotsDone, "table1")
+	src.sourceConfig.Config.StartPosition = "abc"
+	if err := src.seedStartPosition(context.Background(), client); err == nil {
+		t.Errorf("expected error for start position not matching INTEGER column")
+	}
+
+	src.sourceConfig.Config.IncrementColNames = []string{"created_at"}
+	src.sourceConfig.Config.StartPosition = "2023-01-01T00:00:00Z"
+	if err := src.seedStartPosition(context.Background(), client); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := src.getPosition("table1"); !strings.HasPrefix(got, "TIMESTAMP 2023-01-01") {
+		t.Errorf("expected timestamp position, got %q", got)
+	}
+}
+
+// mockBoundedClient is a mockOffsetClient providing the schema of its tables
+type mockBoundedClient struct {
+	mockOffsetClient
+}
+
+func (bq mockBoundedClient) TableMetadata(ctx context.Context, s *Source, tableID string) (*bigquery.TableMetadata, error) {
+	return &bigquery.TableMetadata{Name: tableID, Schema: bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}}}, nil
+}
+
+func TestRunIteratorEndPosition(t *testing.T) {
+	var queries []string
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.StartPosition = "2"
+	src.sourceConfig.Config.EndPosition = "6"
+	client := mockBoundedClient{mockOffsetClient{rows: map[string]int{"table1": 10}, queries: &queries}}
+	src.bqReadClient = client
+	src.records = make(chan sdk.Record, 20)
+	src.ticker = time.NewTicker(5 * time.Millisecond)
+	defer src.ticker.Stop()
+	src.backoff = newPollBackoff(5*time.Millisecond, 0)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	if err := src.seedStartPosition(context.Background(), client); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	startIterator(&src)
+
+	var ids []interface{}
+	for len(ids) < 4 {
+		select {
+		case record := <-src.records:
+			ids = append(ids, record.Payload.After.(sdk.StructuredData)["id"])
+		case <-time.After(time.Second):
+			t.Fatalf("expected 4 records, got %v", ids)
+		}
+	}
+	// a few polling periods pass without querying the table again
+	time.Sleep(30 * time.Millisecond)
+	src.tomb.Kill(nil)
+	if err := src.tomb.Wait(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if fmt.Sprint(ids) != "[3 4 5 6]" {
+		t.Errorf("expected the rows between the start and end position, got %v", ids)
+	}
+	if len(src.records) != 0 {
+		t.Errorf("expected no rows after the end position, got %d", len(src.records))
+	}
+	want := "SELECT * FROM `project.dataset.table1` WHERE `id` > CAST(@offset AS INT64) AND `id` <= CAST(@end AS INT64) ORDER BY `id` LIMIT 501"
+	if len(queries) != 1 || queries[0] != want {
+		t.Errorf("expected the single query %q, got %q", want, queries)
+	}
+	if !src.endReached("table1") {
+		t.Errorf("expected table1 to be read up to the end position")
+	}
+}
+
+func TestSeedStartPositionAfterEnd(t *testing.T) {
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.StartPosition = "10"
+	src.sourceConfig.Config.EndPosition = "6"
+	client := mockBoundedClient{mockOffsetClient{rows: map[string]int{"table1": 10}}}
+	src.bqReadClient = client
+	fetchPos(&src, sdk.Position{})
+
+	if err := src.seedStartPosition(context.Background(), client); err == nil {
+		t.Errorf("expected error for start position after the end position")
+	}
+
+	src.sourceConfig.Config.StartPosition = ""
+	src.sourceConfig.Config.EndPosition = "six"
+	if err := src.seedStartPosition(context.Background(), client); err == nil {
+		t.Errorf("expected error for end position not matching INTEGER column")
+	}
+}
+
+// mockJob is a query job running till its context is done, unless finished is set
+type mockJob struct {
+	finished bool
+	canceled *bool
+}
+
+func (j mockJob) ID() string {
+	return "job_123"
+}
+
+func (j mockJob) Wait(ctx context.Context) (*bigquery.JobStatus, error) {
+	if j.finished {
+		return &bigquery.JobStatus{State: bigquery.Done}, nil
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (j mockJob) Cancel(ctx context.Context) error {
+	*j.canceled = true
+	return nil
+}
+
+func TestWaitJobTimeout(t *testing.T) {
+	src := Source{}
+	src.sourceConfig.Config.JobTimeout = 20 * time.Millisecond
+	ctx := context.Background()
+
+	canceled := false
+	jobCtx, cancel := src.jobContext(ctx)
+	defer cancel()
+	_, err := src.waitJob(ctx, jobCtx, mockJob{canceled: &canceled})
+	if !errors.Is(err, ErrJobTimeout) {
+		t.Fatalf("expected job timeout error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "job_123") {
+		t.Errorf("expected the error to name the job, got %v", err)
+	}
+	if !canceled {
+		t.Errorf("expected the timed out job to be canceled")
+	}
+
+	// jobs finishing in time aren't canceled
+	canceled = false
+	jobCtx, cancel = src.jobContext(ctx)
+	defer cancel()
+	status, err := src.waitJob(ctx, jobCtx, mockJob{finished: true, canceled: &canceled})
+	if err != nil || status.State != bigquery.Done {
+		t.Errorf("expected finished job, got %v, %v", status, err)
+	}
+	if canceled {
+		t.Errorf("expected the finished job not to be canceled")
+	}
+
+	// stopping the source isn't a timeout
+	stopped, stop := context.WithCancel(ctx)
+	jobCtx, cancel = src.jobContext(stopped)
+	defer cancel()
+	stop()
+	_, err = src.waitJob(stopped, jobCtx, mockJob{canceled: &canceled})
+	if !errors.Is(err, context.Canceled) || errors.Is(err, ErrJobTimeout) {
+		t.Errorf("expected context canceled error, got %v", err)
+	}
+}
+
+// mockMaterializingClient serves the rows of mockOffsetClient and records the temporary tables
+type mockMaterializingClient struct {
+	mockOffsetClient
+	created *[]string
+	dropped *[]string
+}
+
+func (bq mockMaterializingClient) Materialize(ctx context.Context, s *Source, dst string, query string, params ...bigquery.QueryParameter) (rowIterator, error) {
+	*bq.created = append(*bq.created, dst)
+	return bq.Query(ctx, s, query, params...)
+}
+
+func (bq mockMaterializingClient) DropTable(ctx context.Context, s *Source, tableID string) error {
+	*bq.dropped = append(*bq.dropped, tableID)
+	return nil
+}
+
+func (bq mockMaterializingClient) Tables(ctx context.Context, s *Source) (tableIDs []string, err error) {
+	return []string{"table1", "conduit_snapshot_table1_1a2b3c4d"}, nil
+}
+
+func TestReadGoogleRowMaterializedSnapshot(t *testing.T) {
+	var queries, created, dropped []string
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.MaterializeSnapshot = true
+	src.materializeSuffix = "_1a2b3c4d"
+	src.bqReadClient = mockMaterializingClient{
+		mockOffsetClient: mockOffsetClient{rows: map[string]int{"table1": 3}, queries: &queries},
+		created:          &created,
+		dropped:          &dropped,
+	}
+	src.records = make(chan sdk.Record, 10)
+	fetchPos(&src, sdk.Position{})
+
+	if err := src.ReadGoogleRow(context.Background(), "table1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := []string{"conduit_snapshot_table1_1a2b3c4d"}
+	if !reflect.DeepEqual(created, want) {
+		t.Errorf("expected temporary table %v to be created, got %v", want, created)
+	}
+	// the whole snapshot is written with a single query
+	if len(queries) != 1 || queries[0] != "SELECT * FROM `project.dataset.table1` ORDER BY `id`" {
+		t.Errorf("expected a single query without limit, got %q", queries)
+	}
+	if len(src.records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(src.records))
+	}
+	if record := <-src.records; record.Operation != sdk.OperationSnapshot {
+		t.Errorf("expected snapshot record, got %v", record.Operation)
+	}
+	if !reflect.DeepEqual(dropped, want) {
+		t.Errorf("expected temporary table %v to be dropped once read, got %v", want, dropped)
+	}
+	if !src.snapshotDone("table1") {
+		t.Errorf("expected snapshot of table1 to be done")
+	}
+
+	// the changes are queried page by page
+	if err := src.ReadGoogleRow(context.Background(), "table1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(created) != 1 || len(queries) != 2 || !strings.HasSuffix(queries[1], " LIMIT 501") {
+		t.Errorf("expected changes to be queried without temporary table, got %q", queries)
+	}
+}
+
+func TestTeardownDropsMaterializedTables(t *testing.T) {
+	var created, dropped []string
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.bqReadClient = mockMaterializingClient{created: &created, dropped: &dropped}
+
+	// temporary tables aren't synced as tables of the dataset
+	tables, err := src.getTables(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(tables, []string{"table1"}) {
+		t.Errorf("expected temporary table to be skipped, got %v", tables)
+	}
+
+	// the snapshot was stopped before it was read till the end
+	src.materialized.Store("conduit_snapshot_table1_1a2b3c4d", true)
+	if err := src.Teardown(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(dropped, []string{"conduit_snapshot_table1_1a2b3c4d"}) {
+		t.Errorf("expected temporary table to be dropped on teardown, got %v", dropped)
+	}
+}
+
+// mockConnectionChecker fails the test query or the dataset access with the errors
+type mockConnectionChecker struct {
+	queryErr   error
+	datasetErr error
+	datasets   *[]string
+}
+
+func (bq mockConnectionChecker) TestQuery(ctx context.Context, location string) error {
+	return bq.queryErr
+}
+
+func (bq mockConnectionChecker) DatasetAccess(ctx context.Context, datasetID string) error {
+	*bq.datasets = append(*bq.datasets, datasetID)
+	return bq.datasetErr
+}
+
+func TestPing(t *testing.T) {
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+
+	tests := []struct {
+		name       string
+		queryErr   error
+		datasetErr error
+		wantErr    string
+	}{
+		{name: "valid credentials"},
+		{name: "invalid credentials", queryErr: &googleapi.Error{Code: http.StatusUnauthorized, Message: "Invalid Credentials"},
+			wantErr: "error while running a test query in project project: credentials rejected, check serviceAccountJSON"},
+		{name: "no access to dataset", datasetErr: &googleapi.Error{Code: http.StatusForbidden, Message: "Access Denied"},
+			wantErr: "error while accessing dataset project.dataset: permission denied"},
+		{name: "missing dataset", datasetErr: &googleapi.Error{Code: http.StatusNotFound, Message: "Not found: Dataset"},
+			wantErr: "error while accessing dataset project.dataset: not found, check projectID, datasetID and datasetLocation"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var datasets []string
+			checker := mockConnectionChecker{queryErr: tt.queryErr, datasetErr: tt.datasetErr, datasets: &datasets}
+			err := src.ping(context.Background(), checker)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				if !reflect.DeepEqual(datasets, []string{"dataset"}) {
+					t.Errorf("expected access to dataset to be checked, got %v", datasets)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+			}
+			var apiErr *googleapi.Error
+			if !errors.As(err, &apiErr) {
+				t.Errorf("expected the API error to be wrapped, got %v", err)
+			}
+		})
+	}
+
+	if err := (&Source{}).Ping(context.Background()); err == nil {
+		t.Errorf("expected error for source which isn't configured")
+	}
+}
+
+// mockLocationClient returns the location of the dataset or fails fetching its metadata
+type mockLocationClient struct {
+	location string
+	err      error
+}
+
+func (bq mockLocationClient) DatasetLocation(ctx context.Context, s *Source, datasetID string) (string, error) {
+	return bq.location, bq.err
+}
+
+func TestResolveLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		location string
+		client   mockLocationClient
+		want     string
+		wantErr  error
+	}{
+		{name: "detected", client: mockLocationClient{location: "EU"}, want: "EU"},
+		{name: "matching", location: "eu", client: mockLocationClient{location: "EU"}, want: "eu"},
+		{name: "mismatched", location: "US", client: mockLocationClient{location: "EU"}, wantErr: ErrLocationMismatch},
+		{name: "configured without metadata", location: "US", client: mockLocationClient{err: errors.New("access denied")}, want: "US"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := Source{}
+			src.sourceConfig.Config.ProjectID = "project"
+			src.sourceConfig.Config.DatasetID = "dataset"
+			src.sourceConfig.Config.Location = tt.location
+
+			err := src.resolveLocation(context.Background(), tt.client)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				want := "dataset location mismatch: dataset project.dataset is in EU, but datasetLocation is US"
+				if err.Error() != want {
+					t.Errorf("expected error %q, got %q", want, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if src.sourceConfig.Config.Location != tt.want {
+				t.Errorf("expected location %q, got %q", tt.want, src.sourceConfig.Config.Location)
+			}
+		})
+	}
+
+	src := Source{}
+	src.sourceConfig.Config.DatasetID = "dataset"
+	if err := src.resolveLocation(context.Background(), mockLocationClient{err: errors.New("access denied")}); err == nil {
+		t.Errorf("expected error when the location can't be detected")
+	}
+}
+
+func TestRunIteratorHeartbeat(t *testing.T) {
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.HeartbeatInterval = time.Minute
+	src.bqReadClient = mockTableClient{
+		schema: bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}},
+		tables: map[string][][]bigquery.Value{"table1": nil},
+	}
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	src.now = func() time.Time { return now }
+	src.lastEmitted = now.Add(-2 * time.Minute).UnixNano()
+	src.records = make(chan sdk.Record, 10)
+	src.ticker = time.NewTicker(time.Hour)
+	defer src.ticker.Stop()
+	src.backoff = newPollBackoff(time.Hour, 0)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+	src.setPosition("table1", "INT64 5")
+	src.markSnapshotDone("table1")
+
+	startIterator(&src)
+	var record sdk.Record
+	select {
+	case record = <-src.records:
+	case <-time.After(time.Second):
+		t.Fatalf("expected a heartbeat on the empty poll")
+	}
+	src.tomb.Kill(nil)
+	if err := src.tomb.Wait(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if record.Metadata[MetadataHeartbeat] != "true" || record.Key != nil || record.Payload.After != nil {
+		t.Errorf("expected heartbeat record without key and payload, got %v", record)
+	}
+	var position Position
+	if err := json.Unmarshal(record.Position, &position); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if position.Offsets["table1"] != "INT64 5" {
+		t.Errorf("expected heartbeat to carry the current offset, got %v", position.Offsets)
+	}
+	if len(src.records) != 0 {
+		t.Errorf("expected a single heartbeat, got %d more records", len(src.records))
+	}
+
+	// no heartbeat is emitted before the interval passed since the last record
+	src.heartbeat(context.Background())
+	if len(src.records) != 0 {
+		t.Errorf("expected no heartbeat within the interval, got %d records", len(src.records))
+	}
+}
+
+func TestRunIteratorParentContextCanceled(t *testing.T) {
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.TableIDs = []string{"table1"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.bqReadClient = mockOffsetClient{rows: map[string]int{"table1": 10}}
+	// the reading goroutine blocks on the full channel
+	src.records = make(chan sdk.Record, 1)
+	src.ticker = time.NewTicker(time.Hour)
+	defer src.ticker.Stop()
+	src.backoff = newPollBackoff(time.Hour, 0)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	parent, cancel := context.WithCancel(context.Background())
+	ctx := src.tomb.Context(parent)
+	src.tomb.Go(func() error {
+		return src.runIterator(ctx)
+	})
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-src.tomb.Dead():
+	case <-time.After(time.Second):
+		t.Fatalf("expected the iterator to stop once the parent context is canceled")
+	}
+	if err := src.tomb.Err(); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context canceled, got %v", err)
+	}
+	if len(src.records) != 1 {
+		t.Errorf("expected only the buffered record, got %d", len(src.records))
+	}
+}
+
+func TestRunIteratorMaxRows(t *testing.T) {
+	src := Source{}
+	src.sourceConfig.Config.ProjectID = "project"
+	src.sourceConfig.Config.DatasetID = "dataset"
+	src.sourceConfig.Config.TableIDs = []string{"table1", "table2"}
+	src.sourceConfig.Config.PrimaryKeyColNames = []string{"id"}
+	src.sourceConfig.Config.MaxRows = 5
+	src.bqReadClient = mockOffsetClient{rows: map[string]int{"table1": 10, "table2": 10}}
+	src.records = make(chan sdk.Record, 30)
+	src.ticker = time.NewTicker(5 * time.Millisecond)
+	defer src.ticker.Stop()
+	src.backoff = newPollBackoff(5*time.Millisecond, 0)
+	src.tomb = &tomb.Tomb{}
+	fetchPos(&src, sdk.Position{})
+
+	startIterator(&src)
+	// a few polling periods pass without reading any further
+	time.Sleep(50 * time.Millisecond)
+	src.tomb.Kill(nil)
+	if err := src.tomb.Wait(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(src.records) != 5 {
+		t.Errorf("expected 5 records over both tables, got %d", len(src.records))
+	}
+	if !src.maxRowsReached() {
+		t.Errorf("expected max rows to be reached")
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googlesource
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	googlebigquery "github.com/neha-Gupta1/conduit-connector-bigquery"
+)
+
+// seedStartPosition sets the offset of the tables without position to the configured start position,
+// so they are read incrementally after it instead of being snapshot. The start position is parsed
+// with the type of the incrementing column of every table. The end position is validated as well,
+// so an invalid value fails the start instead of the first query.
+func (s *Source) seedStartPosition(ctx context.Context, client tableMetadataClient) error {
+	config := s.sourceConfig.Config
+	if len(config.StartPosition) == 0 && len(config.EndPosition) == 0 {
+		return nil
+	}
+
+	tables, err := s.getTables(ctx)
+	if err != nil {
+		return fmt.Errorf("error while getting tables: %w", err)
+	}
+	for _, tableID := range tables {
+		field, err := s.incrementField(ctx, client, tableID)
+		if err != nil {
+			return err
+		}
+		var start, end bigquery.Value
+		if len(config.StartPosition) > 0 {
+			if start, err = parsePosition(field, config.StartPosition); err != nil {
+				return fmt.Errorf("invalid %s for column %s of table %s: %w", googlebigquery.ConfigStartPosition, field.Name, tableID, err)
+			}
+		}
+		if len(config.EndPosition) > 0 {
+			if end, err = parsePosition(field, config.EndPosition); err != nil {
+				return fmt.Errorf("invalid %s for column %s of table %s: %w", googlebigquery.ConfigEndPosition, field.Name, tableID, err)
+			}
+		}
+		if start != nil && end != nil && !positionBefore(start, end) {
+			return fmt.Errorf("%s %s isn't before %s %s", googlebigquery.ConfigStartPosition, config.StartPosition,
+				googlebigquery.ConfigEndPosition, config.EndPosition)
+		}
+
+		if start == nil || len(s.getPosition(tableID)) > 0 || s.snapshotDone(tableID) {
+			continue
+		}
+		offset, err := s.positionOffset(ctx, field, start)
+		if err != nil {
+			return fmt.Errorf("invalid %s for column %s of table %s: %w", googlebigquery.ConfigStartPosition, field.Name, tableID, err)
+		}
+		s.setPosition(tableID, offset)
+		// the rows before the start position were loaded elsewhere, the rows after it are changes
+		s.markSnapshotDone(tableID)
+	}
+	return nil
+}
+
+// incrementField returns the schema of the incrementing column of the table
+func (s *Source) incrementField(ctx context.Context, client tableMetadataClient, tableID string) (*bigquery.FieldSchema, error) {
+	md, err := client.TableMetadata(ctx, s, tableID)
+	if err != nil {
+		return nil, fmt.Errorf("error while fetching metadata of table %s: %w", tableID, err)
+	}
+	columnNames := s.incrementColNames(tableID)
+	if len(columnNames) == 0 {
+		return nil, fmt.Errorf("no incrementing or primary key column to order table %s by", tableID)
+	}
+	for _, field := range md.Schema {
+		if field.Name == columnNames[0] {
+			return field, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: incrementing column %s not found in table %s", ErrColumnMissing, columnNames[0], tableID)
+}
+
+// parsePosition parses the configured position as value of the column
+func parsePosition(field *bigquery.FieldSchema, value string) (bigquery.Value, error) {
+	switch field.Type {
+	case bigquery.IntegerFieldType:
+		return strconv.ParseInt(value, 10, 64)
+	case bigquery.FloatFieldType:
+		return strconv.ParseFloat(value, 64)
+	case bigquery.NumericFieldType, bigquery.BigNumericFieldType:
+		rat, ok := new(big.Rat).SetString(value)
+		if !ok {
+			return nil, fmt.Errorf("%q is not a number", value)
+		}
+		return rat, nil
+	case bigquery.StringFieldType:
+		return value, nil
+	case bigquery.TimestampFieldType:
+		return parseTimestamp(value)
+	case bigquery.DateFieldType:
+		return civil.ParseDate(value)
+	case bigquery.DateTimeFieldType:
+		return civil.ParseDateTime(value)
+	case bigquery.TimeFieldType:
+		return civil.ParseTime(value)
+	default:
+		return nil, fmt.Errorf("columns of type %s can't be read from or up to a position", field.Type)
+	}
+}
+
+// positionBefore reports if the position a is smaller than b. Both are parsed for the same column.
+func positionBefore(a, b bigquery.Value) bool {
+	switch a := a.(type) {
+	case int64:
+		return a < b.(int64)
+	case float64:
+		return a < b.(float64)
+	case *big.Rat:
+		return a.Cmp(b.(*big.Rat)) < 0
+	case string:
+		return a < b.(string)
+	case bool:
+		return !a && b.(bool)
+	case time.Time:
+		return a.Before(b.(time.Time))
+	case civil.Date:
+		return a.Before(b.(civil.Date))
+	case civil.DateTime:
+		return a.Before(b.(civil.DateTime))
+	case civil.Time:
+		return a.Before(b.(civil.Time))
+	}
+	return false
+}
+
+// positionOffset returns the offset of the parsed position. The value is converted like the values
+// read from the table, so the offset is formatted the same way.
+func (s *Source) positionOffset(ctx context.Context, field *bigquery.FieldSchema, value bigquery.Value) (string, error) {
+	converted, err := s.convertValue(ctx, field, value)
+	if err != nil {
+		return "", err
+	}
+	return formatOffset(field, value, converted), nil
+}
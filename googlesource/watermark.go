@@ -0,0 +1,78 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googlesource
+
+import (
+	"strings"
+)
+
+// watermarkBoundary holds the keys of the rows read with the current offset of a table. Incrementing
+// columns like updated_at aren't unique, so the rows equal to the offset are queried again to not
+// miss the ones split across pages or added later, and the rows already read are skipped.
+type watermarkBoundary struct {
+	offset string
+	keys   map[string]struct{}
+}
+
+// inclusiveOffset reports if the rows equal to the offset of the table are read again. Offsets
+// holding all the primary key columns are unique, and without primary key the rows read can't be
+// told apart.
+func (s *Source) inclusiveOffset(tableID string) bool {
+	keyColumns := s.sourceConfig.Config.PrimaryKeyColNames
+	if len(keyColumns) == 0 {
+		return false
+	}
+	incrementColumns := " " + strings.Join(s.incrementColNames(tableID), " ") + " "
+	for _, column := range keyColumns {
+		if !strings.Contains(incrementColumns, " "+column+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// watermarkBoundary returns the boundary of the rows stored under the position key. Every position
+// key is read by a single goroutine at a time.
+func (s *Source) watermarkBoundary(positionKey string) *watermarkBoundary {
+	boundary, _ := s.boundaries.LoadOrStore(positionKey, &watermarkBoundary{})
+	return boundary.(*watermarkBoundary)
+}
+
+// skip returns the number of rows equal to the offset which were already read
+func (b *watermarkBoundary) skip(offset string) int {
+	if b.offset != offset {
+		return 0
+	}
+	return len(b.keys)
+}
+
+// seen reports if the row with the offset and key was already read
+func (b *watermarkBoundary) seen(offset string, key []byte) bool {
+	if b.offset != offset {
+		return false
+	}
+	_, ok := b.keys[string(key)]
+	return ok
+}
+
+// add remembers the row with the offset and key. The keys of the previous offset are dropped once
+// the offset grows.
+func (b *watermarkBoundary) add(offset string, key []byte) {
+	if b.offset != offset || b.keys == nil {
+		b.offset = offset
+		b.keys = make(map[string]struct{})
+	}
+	b.keys[string(key)] = struct{}{}
+}
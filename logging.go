@@ -0,0 +1,42 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googlebigquery
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+// logLevels are the accepted log levels keyed by their config value
+var logLevels = map[string]zerolog.Level{
+	"trace": zerolog.TraceLevel,
+	"debug": zerolog.DebugLevel,
+	"info":  zerolog.InfoLevel,
+	"warn":  zerolog.WarnLevel,
+	"error": zerolog.ErrorLevel,
+}
+
+// WithLogLevel returns a context whose logger drops messages below the level, so the verbose logs
+// of the connector can be silenced. The context is returned unchanged when no level is configured,
+// the level set in Conduit applies then.
+func WithLogLevel(ctx context.Context, level string) context.Context {
+	lvl, ok := logLevels[level]
+	if !ok {
+		return ctx
+	}
+	logger := zerolog.Ctx(ctx).Level(lvl)
+	return logger.WithContext(ctx)
+}
@@ -0,0 +1,48 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googlebigquery
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestWithLogLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf).Level(zerolog.TraceLevel)
+	ctx := logger.WithContext(context.Background())
+
+	// without level the level of the context logger applies
+	zerolog.Ctx(WithLogLevel(ctx, "")).Trace().Msg("verbose")
+	if !strings.Contains(buf.String(), "verbose") {
+		t.Errorf("expected trace message to be logged, got %q", buf.String())
+	}
+
+	buf.Reset()
+	ctx = WithLogLevel(ctx, "info")
+	zerolog.Ctx(ctx).Trace().Msg("verbose")
+	zerolog.Ctx(ctx).Debug().Msg("details")
+	zerolog.Ctx(ctx).Info().Msg("important")
+	if strings.Contains(buf.String(), "verbose") || strings.Contains(buf.String(), "details") {
+		t.Errorf("expected messages below info to be dropped, got %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), "important") {
+		t.Errorf("expected info message to be logged, got %q", buf.String())
+	}
+}
@@ -22,55 +22,384 @@ import (
 func Specification() sdk.Specification {
 	return sdk.Specification{
 		Name:        "bigquery",
-		Summary:     "A BigQuery source plugin for Conduit, written in Go.",
-		Description: "A plugin to fetch data from google BigQuery",
+		Summary:     "A BigQuery source and destination plugin for Conduit, written in Go.",
+		Description: "A plugin to fetch data from google BigQuery and to write data into it",
 		Version:     "v0.1.0",
 		Author:      "Neha Gupta",
-		SourceParams: map[string]sdk.Parameter{
-			ConfigServiceAccount: {
-				Default:     "",
-				Required:    true,
-				Description: "service account key file with data pulling access. ref: https://cloud.google.com/docs/authentication/getting-started", // We can also take it as value if required
-			},
-			ConfigProjectID: {
-				Default:     "",
-				Required:    true,
-				Description: "Google project ID.",
-			},
-			ConfigDatasetID: {
-				Default:     "",
-				Required:    true,
-				Description: "Google Bigqueries dataset ID.",
-			},
-			ConfigLocation: {
-				Default:     "",
-				Required:    true,
-				Description: "Google Bigqueries dataset location.",
-			},
-			ConfigTableID: {
-				Default:     "",
-				Required:    true,
-				Description: "Google Bigqueries table ID.",
-			},
-			ConfigPollingTime: {
-				Default:     "5",
-				Required:    false,
-				Description: "polling period for the CDC mode, formatted as a time.Duration string.",
-			},
-			ConfigIncrementalColName: {
-				Default:  "",
-				Required: false,
-				Description: `Column name which provides visibility about newer rows. For eg, updated_at column which stores when the row was last updated\n
-				primary key with incremental value say id of type int or float.  \n eg value,
-				 updated_at`,
-			},
-			ConfigPrimaryKeyColName: {
-				Default:  "",
-				Required: false,
-				Description: `Column name which provides visibility about uniqueness. For eg, _id which stores \n
-				primary key with incremental value say id of type int or float.  \n eg value,
-				 id`,
-			},
+	}
+}
+
+// SourceParameters returns the parameters of the source connector.
+func SourceParameters() map[string]sdk.Parameter {
+	return map[string]sdk.Parameter{
+		ConfigServiceAccount: {
+			Default:     "",
+			Required:    false,
+			Description: "service account key with data pulling access, as JSON or path to the key file. Values starting with { are read as JSON. Application Default Credentials are used when blank. ref: https://cloud.google.com/docs/authentication/getting-started",
+		},
+		ConfigServiceAccountJSON: {
+			Default:     "",
+			Required:    false,
+			Description: "service account key provided inline as JSON. Takes precedence over serviceAccount when both are set.",
+		},
+		ConfigServiceAccountBase64: {
+			Default:     "",
+			Required:    false,
+			Description: "service account key JSON encoded as base64. Credentials are used in the order serviceAccountJSON, serviceAccountBase64, serviceAccount.",
+		},
+		ConfigImpersonateServiceAccount: {
+			Default:     "",
+			Required:    false,
+			Description: "email of the service account to impersonate. The configured credentials are used as base credentials and need the token creator role on it.",
+		},
+		ConfigImpersonateDelegates: {
+			Default:     "",
+			Required:    false,
+			Description: "comma separated service account emails forming the delegation chain used for impersonation.",
+		},
+		ConfigScopes: {
+			Default:     "https://www.googleapis.com/auth/bigquery",
+			Required:    false,
+			Description: "comma separated OAuth scopes requested for the BigQuery client. Defaults to the full BigQuery scope as the read-only one can't run the query jobs the tables are read with.",
+		},
+		ConfigEndpoint: {
+			Default:     "",
+			Required:    false,
+			Description: "BigQuery API endpoint used instead of the default one, eg. http://localhost:9050 for the BigQuery emulator. Meant for testing and private deployments.",
+		},
+		ConfigInsecure: {
+			Default:     "false",
+			Required:    false,
+			Description: "send requests to the endpoint without credentials, eg. to the BigQuery emulator. Requires endpoint.",
+		},
+		ConfigProjectID: {
+			Default:     "",
+			Required:    true,
+			Description: "Google project ID.",
+		},
+		ConfigDatasetID: {
+			Default:     "",
+			Required:    true,
+			Description: "Google Bigqueries dataset ID.",
+		},
+		ConfigLocation: {
+			Default:     "",
+			Required:    false,
+			Description: "Google Bigqueries dataset location. Detected from the metadata of the dataset when blank, and checked against it otherwise.",
+		},
+		ConfigTableID: {
+			Default:     "",
+			Required:    false,
+			Description: "Comma separated Google Bigqueries table IDs. All tables of the dataset are synced when blank.",
+		},
+		ConfigTableIncludeRegex: {
+			Default:     "",
+			Required:    false,
+			Description: "Regex which discovered tables need to match to be synced. Only used when tableID is blank.",
+		},
+		ConfigTableExcludeRegex: {
+			Default:     "",
+			Required:    false,
+			Description: "Regex for discovered tables which should not be synced. Only used when tableID is blank.",
+		},
+		ConfigSkipTableValidation: {
+			Default:     "false",
+			Required:    false,
+			Description: "skip checking that the tables listed in tableID exist when the connector starts, eg. for tables which are created later.",
+		},
+		ConfigQueryLabels: {
+			Default:     "",
+			Required:    false,
+			Description: "comma separated key=value labels set on every query job, eg. team=data,pipeline=orders, so the billing export can group the cost of the sync. Keys and values follow the BigQuery label rules.",
+		},
+		ConfigMaxBytesBilled: {
+			Default:     "",
+			Required:    false,
+			Description: "maximum number of bytes billed for every query. Queries which would bill more fail instead of running. No limit when blank.",
+		},
+		ConfigDryRun: {
+			Default:     "false",
+			Required:    false,
+			Description: "only log an estimate of the bytes processed by the queries of every table, without reading any rows. The connector stops with a dry run error once the tables are estimated.",
+		},
+		ConfigUseQueryCache: {
+			Default:     "true",
+			Required:    false,
+			Description: "let queries return cached results of identical earlier queries, which aren't billed. Set to false to always read the rows from the tables, eg. for polling.",
+		},
+		ConfigQueryPriority: {
+			Default:     "interactive",
+			Required:    false,
+			Description: "priority the query jobs run with. interactive runs them right away, batch queues them till idle slots are available, which suits large backfills that aren't latency sensitive.",
+		},
+		ConfigLogLevel: {
+			Default:     "",
+			Required:    false,
+			Description: "minimum level of the messages logged by the connector, one of trace, debug, info, warn or error. The level configured in Conduit applies when blank.",
+		},
+		ConfigPollingTime: {
+			Default:     "5m",
+			Required:    false,
+			Description: "polling period for the CDC mode, formatted as a time.Duration string.",
+		},
+		ConfigAllowFastPolling: {
+			Default:     "false",
+			Required:    false,
+			Description: "allow a pollingTime below 1s. Polling that often runs many queries, which are billed and count against the BigQuery quotas.",
+		},
+		ConfigMaxPollingTime: {
+			Default:     "",
+			Required:    false,
+			Description: "cap of the polling period, formatted as a time.Duration string. The period doubles after every poll without new rows and is reset once rows are read. The period stays fixed when empty.",
+		},
+		ConfigHeartbeatInterval: {
+			Default:     "",
+			Required:    false,
+			Description: "time without new rows after which a heartbeat record carrying the current position is emitted, formatted as a time.Duration string. Heartbeat records have the metadata bigquery.heartbeat set to true and no key nor payload. No heartbeats when empty.",
+		},
+		ConfigMaxConcurrentReads: {
+			Default:     "4",
+			Required:    false,
+			Description: "maximum number of tables queried at the same time. Remaining tables wait till a table is done.",
+		},
+		ConfigBytesEncoding: {
+			Default:     "base64",
+			Required:    false,
+			Description: "encoding used for BYTES columns in the payload. Either base64 or hex.",
+		},
+		ConfigJSONAsString: {
+			Default:     "false",
+			Required:    false,
+			Description: "keep JSON columns as raw strings instead of parsing them into structured values.",
+		},
+		ConfigTimestampFormat: {
+			Default:     "2006-01-02 15:04:05.999999 MST",
+			Required:    false,
+			Description: "Go time layout used for TIMESTAMP columns. Use rfc3339 for RFC 3339 strings or unix for epoch milliseconds.",
+		},
+		ConfigTimestampLocation: {
+			Default:     "UTC",
+			Required:    false,
+			Description: "IANA time zone, eg. America/New_York, TIMESTAMP columns are formatted in.",
+		},
+		ConfigFilter: {
+			Default:     "",
+			Required:    false,
+			Description: "BigQuery SQL condition rows need to match to be synced, eg. region = 'us'. Passed to BigQuery as is.",
+		},
+		ConfigSamplePercent: {
+			Default:     "",
+			Required:    false,
+			Description: "percentage of the data blocks of every table read with TABLESAMPLE SYSTEM, eg. 10 to sync about a tenth of the rows for testing. The sample is approximate and drawn again by every query. All rows are read when empty.",
+		},
+		ConfigQuery: {
+			Default:     "",
+			Required:    false,
+			Description: "custom BigQuery SQL query synced instead of the tables. It needs to return the incrementing and primary key columns. Can't be used with filter.",
+		},
+		ConfigPartitions: {
+			Default:     "",
+			Required:    false,
+			Description: "comma separated IDs of the time partitions to read, formatted as YYYY, YYYYMM, YYYYMMDD or YYYYMMDDHH, eg. 20240101,20240102. Only the selected partitions are scanned. All partitions are read when blank.",
+		},
+		ConfigPartitionField: {
+			Default:     "_PARTITIONTIME",
+			Required:    false,
+			Description: "column the tables are partitioned by. The _PARTITIONTIME pseudo column selects the partitions of ingestion time partitioned tables.",
 		},
+		ConfigPartitionLookback: {
+			Default:     "",
+			Required:    false,
+			Description: "time window of partitions read from tables requiring a partition filter, formatted as a time.Duration string, eg. 168h. Only used when partitions is blank and the offset doesn't filter the partitions.",
+		},
+		ConfigIncludePseudoColumns: {
+			Default:     "false",
+			Required:    false,
+			Description: "set to true to add the _PARTITIONTIME and _PARTITIONDATE pseudo columns of ingestion time partitioned tables to the payload as partition_time and partition_date. Other tables are read without them.",
+		},
+		ConfigColumns: {
+			Default:     "",
+			Required:    false,
+			Description: "comma separated columns to select. The incrementing and primary key columns are always selected. All columns are selected when blank.",
+		},
+		ConfigExcludeColumns: {
+			Default:     "",
+			Required:    false,
+			Description: "comma separated columns dropped from the records. Nested fields are given as path, eg. user.email. The incrementing and primary key columns can't be excluded.",
+		},
+		ConfigBatchSize: {
+			Default:     "500",
+			Required:    false,
+			Description: "number of rows fetched by each query.",
+		},
+		ConfigMaxRows: {
+			Default:     "",
+			Required:    false,
+			Description: "total number of records emitted over all tables before reading stops, eg. for a bounded test. Unlike batchSize, which is the number of rows per query, it caps the whole sync. No limit when empty.",
+		},
+		ConfigBufferSize: {
+			Default:     "100",
+			Required:    false,
+			Description: "number of records buffered before the tables are read any further.",
+		},
+		ConfigKeyCacheSize: {
+			Default:     "10000",
+			Required:    false,
+			Description: "number of record keys remembered to emit rows which are read again, eg. after their incrementing column was bumped by an update, as update records. 0 disables it.",
+		},
+		ConfigMode: {
+			Default:     "snapshot",
+			Required:    false,
+			Description: "snapshot reads the existing rows of the tables before their changes. cdc skips the snapshot and only reads the rows added after the connector started, eg. for tables backfilled elsewhere.",
+		},
+		ConfigCDCMode: {
+			Default:     "polling",
+			Required:    false,
+			Description: "how changes after the snapshot are read. polling queries the rows with a bigger incrementing column, changeHistory reads inserts, updates and deletes with the CHANGES function of tables with change history enabled.",
+		},
+		ConfigDetectDeletes: {
+			Default:     "false",
+			Required:    false,
+			Description: "periodically compare the primary keys of the tables with the keys found by the previous scan and emit delete records for the keys which disappeared. The keys of all the tables are kept in memory.",
+		},
+		ConfigDetectDeletesInterval: {
+			Default:     "1h",
+			Required:    false,
+			Description: "time between two scans of the primary keys of a table when detecting deletes, formatted as a time.Duration string.",
+		},
+		ConfigMaxRetries: {
+			Default:     "3",
+			Required:    false,
+			Description: "number of times a query failing with a transient error, eg. rateLimitExceeded or backendError, is retried. Queries failing to reach BigQuery are retried too and the client is recreated after 3 of them failed in a row. 0 disables retries.",
+		},
+		ConfigRetryDelay: {
+			Default:     "1s",
+			Required:    false,
+			Description: "delay before the first retry of a query, formatted as a time.Duration string. The delay doubles with every retry and is randomized by up to half.",
+		},
+		ConfigJobTimeout: {
+			Default:     "",
+			Required:    false,
+			Description: "how long a query job may run before it is canceled, formatted as a time.Duration string. The read of the table fails with a timeout error then. No timeout when empty.",
+		},
+		ConfigReadMode: {
+			Default:     "query",
+			Required:    false,
+			Description: "how snapshots are read. query pages through the table with query jobs, storage runs a single query job and downloads its result using the BigQuery Storage Read API. The table isn't read directly, the query is billed the same in both modes, only downloading the result is faster.",
+		},
+		ConfigReadStreams: {
+			Default:     "1",
+			Required:    false,
+			Description: "number of parallel streams the snapshot of a table is split across. Requires readMode storage. Every stream is a query scanning the whole table, so N streams are billed N times the bytes of a single snapshot query. Streams count against maxConcurrentReads. Records are only ordered within a stream.",
+		},
+		ConfigMaterializeSnapshot: {
+			Default:     "false",
+			Required:    false,
+			Description: "write the rows of a snapshot to a temporary table of the dataset with a single query and read them from it, instead of querying the table page by page. Requires permission to create tables in the dataset.",
+		},
+		ConfigIncrementalColName: {
+			Default:  "",
+			Required: false,
+			Description: `Column name which provides visibility about newer rows. For eg, updated_at column which stores when the row was last updated\n
+			primary key with incremental value say id of type int or float. Column can be provided per table as table:column.  \n eg value,
+			 updated_at or table1:updated_at,table2:id. Composite columns are wrapped in parentheses, eg. table1:(updated_at,id).
+			 Tables without column are paginated by the primary key.`,
+		},
+		ConfigIncrementOrder: {
+			Default:     "asc",
+			Required:    false,
+			Description: "asc reads the rows from the smallest incrementing column value on. desc reads them newest first, eg. to backfill recent rows first; rows added once the table was read to the end aren't read then.",
+		},
+		ConfigOrderBy: {
+			Default:     "",
+			Required:    false,
+			Description: "comma separated columns the rows are ordered by, eg. id for a stable order while updated_at is the incrementing column. The incrementing columns are still used as offset, so the rows after the offset are read by a single query instead of pages.",
+		},
+		ConfigStartPosition: {
+			Default:     "",
+			Required:    false,
+			Description: "Value of the incrementing column the tables without saved position are read after, eg. 2023-01-01T00:00:00Z for a table backfilled up to then. The rows after it are read as creates and the snapshot is skipped.",
+		},
+		ConfigEndPosition: {
+			Default:     "",
+			Required:    false,
+			Description: "Value of the incrementing column the tables are read up to, eg. to replay a fixed window with startPosition. Tables are no longer polled once read up to it.",
+		},
+		ConfigKeyColumns: {
+			Default:     "",
+			Required:    false,
+			Description: "comma separated columns the record key is built from, eg. customer_id to partition the records by customer downstream. The primary key still tells rows apart. The primary key is used as record key when empty.",
+		},
+		ConfigKeyFormat: {
+			Default:     "json",
+			Required:    false,
+			Description: "encoding of the record key. json encodes the value of a single key column, and an object of the columns of a composite key. gob is the Go specific encoding of earlier versions.",
+		},
+		ConfigNullHandling: {
+			Default:     "null",
+			Required:    false,
+			Description: "how NULL column values are written to the payload. null keeps the column with a null value, omit leaves the column out. NULL key columns are encoded as null in the key either way.",
+		},
+		ConfigOnConversionError: {
+			Default:     "fail",
+			Required:    false,
+			Description: "what happens to rows whose values or key can't be converted to a record. fail stops the sync, skip logs and skips the row, dlq emits the row as record whose metadata bigquery.conversionError holds the error.",
+		},
+		ConfigPrimaryKeyColName: {
+			Default:  "",
+			Required: false,
+			Description: `Column name which provides visibility about uniqueness. For eg, _id which stores \n
+			primary key with incremental value say id of type int or float. Composite keys are given as comma separated columns.  \n eg value,
+			 id or order_id,line_no`,
+		},
+	}
+}
+
+// DestinationParameters returns the parameters of the destination connector. Credentials, project,
+// dataset and location are configured the same way as for the source.
+func DestinationParameters() map[string]sdk.Parameter {
+	source := SourceParameters()
+	params := make(map[string]sdk.Parameter)
+	for _, key := range []string{
+		ConfigServiceAccount,
+		ConfigServiceAccountJSON,
+		ConfigServiceAccountBase64,
+		ConfigImpersonateServiceAccount,
+		ConfigImpersonateDelegates,
+		ConfigScopes,
+		ConfigEndpoint,
+		ConfigInsecure,
+		ConfigProjectID,
+		ConfigDatasetID,
+		ConfigLocation,
+		ConfigLogLevel,
+	} {
+		params[key] = source[key]
+	}
+	params[ConfigLocation] = sdk.Parameter{
+		Default:     "",
+		Required:    true,
+		Description: "Google Bigqueries dataset location.",
+	}
+	params[ConfigTableID] = sdk.Parameter{
+		Default:     "",
+		Required:    true,
+		Description: "table the records are written to. The table needs to exist and its columns to match the fields of the record payloads.",
+	}
+	params[ConfigWriteMode] = sdk.Parameter{
+		Default:     WriteModeInsert,
+		Required:    false,
+		Description: "how records are written. `insert` appends the records with streaming inserts. `upsert` merges the records into the table by `primaryKeyColName` with a MERGE query per batch, so existing rows are updated and deletes remove the matching row.",
+	}
+	params[ConfigAutoCreate] = sdk.Parameter{
+		Default:     "false",
+		Required:    false,
+		Description: "create the table when it doesn't exist. The schema is inferred from the payload of the first record and later records need to fit it.",
+	}
+	params[ConfigPrimaryKeyColName] = sdk.Parameter{
+		Default:     "",
+		Required:    false,
+		Description: "comma separated primary key columns the records are merged by. Required when `writeMode` is `upsert`.",
 	}
+	return params
 }